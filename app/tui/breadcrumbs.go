@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/search"
+)
+
+// Bytes of each note shown as its snippet when listing a folder.
+const folderSnippetSize = 300
+
+// previewView renders the preview below the breadcrumbs of the previewed
+// note.
+func (m Model) previewView() string {
+	crumbs := lipgloss.NewStyle().MaxWidth(m.width / 2).Render(m.breadcrumbs())
+	return lipgloss.JoinVertical(lipgloss.Left, crumbs, m.preview.View())
+}
+
+// breadcrumbs renders the folders from the notes root down to the
+// previewed note, e.g. "notes › projects › plan.md". The folder listed with
+// ctrl+n and ctrl+p is highlighted.
+func (m Model) breadcrumbs() string {
+	rel, err := filepath.Rel(m.rootPath, m.previewPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return theme.Status.Render(filepath.ToSlash(m.previewPath))
+	}
+
+	dir := m.rootPath
+	crumbs := []string{m.crumb(filepath.Base(m.rootPath), dir)}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		crumbs = append(crumbs, m.crumb(part, dir))
+	}
+	crumbs = append(crumbs, theme.Status.Copy().UnsetPaddingLeft().Bold(true).Render(parts[len(parts)-1]))
+	separator := theme.Status.Copy().UnsetPaddingLeft().Render(" › ")
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(crumbs, separator))
+}
+
+// crumb renders the breadcrumb of the folder dir, highlighted if listed.
+func (m Model) crumb(name, dir string) string {
+	if dir == m.listedDir {
+		return theme.matchStyle(0).Render(name)
+	}
+	return theme.Status.Copy().UnsetPaddingLeft().Render(name)
+}
+
+// siblingsFolder returns the folder of the previewed note, or else of the
+// selected one, "" when there is neither.
+func (m Model) siblingsFolder() string {
+	switch {
+	case m.preview != nil && m.previewPath != "":
+		return filepath.Dir(m.previewPath)
+	case m.list.SelectedItem() != nil:
+		return filepath.Dir(m.list.SelectedItem().(Note).path)
+	}
+	return ""
+}
+
+// listFolder lists the notes in dir, not those in its subfolders, in place
+// of the results.
+func (m *Model) listFolder(dir string) tea.Cmd {
+	m.listedDir = dir
+	rel, err := filepath.Rel(m.rootPath, dir)
+	if err != nil {
+		rel = dir
+	} else if rel == "." {
+		rel = filepath.Base(m.rootPath)
+	}
+	m.status = tr("notes in %s (ctrl+p goes up)", filepath.ToSlash(rel))
+
+	m.queryId++
+	queryId, root, extensions := m.queryId, m.rootPath, m.extensions
+	return func() tea.Msg {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return ResultMsg{results: search.SearchResult{Err: err}, queryId: queryId}
+		}
+		hits := []search.DocumentMatch{}
+		for _, f := range files {
+			path := filepath.Join(dir, f.Name())
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") || !notes.IsNote(root, path, extensions) {
+				continue
+			}
+			hits = append(hits, fileHit(path))
+		}
+		sort.Slice(hits, func(i, j int) bool { return strings.ToLower(hits[i].Path) < strings.ToLower(hits[j].Path) })
+		return ResultMsg{results: search.SearchResult{Hits: hits}, queryId: queryId}
+	}
+}
+
+// fileHit lists the note at path with its start as the snippet, or none
+// when it's private.
+func fileHit(path string) search.DocumentMatch {
+	if notes.IsPrivateFile(path) {
+		return search.DocumentMatch{Path: path, Private: true}
+	}
+	return search.DocumentMatch{Path: path, Content: snippetOf(path)}
+}
+
+// snippetOf returns the start of the note at path.
+func snippetOf(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, folderSnippetSize)
+	n, _ := f.Read(head)
+	return strings.ToValidUTF8(string(head[:n]), "")
+}
+
+// listParent lists the folder above the one listed, or above the folder of
+// the note when none is, up to the notes root.
+func (m *Model) listParent() tea.Cmd {
+	dir := m.listedDir
+	if dir == "" {
+		dir = m.siblingsFolder()
+		if dir == "" {
+			return nil
+		}
+	}
+	if rel, err := filepath.Rel(m.rootPath, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		m.status = tr("already at the notes root")
+		return nil
+	}
+	return m.listFolder(filepath.Dir(dir))
+}
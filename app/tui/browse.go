@@ -0,0 +1,221 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// treeEntry is a folder or a note in the browse tree.
+type treeEntry struct {
+	path  string
+	depth int
+	dir   bool
+}
+
+// browseState shows the notes root as a tree of folders, read as they are
+// expanded.
+type browseState struct {
+	entries  []treeEntry     // rows of the tree, expanded folders followed by their children
+	expanded map[string]bool // folders whose children are shown
+	cursor   int             // selected row
+	offset   int             // first row shown
+}
+
+// Keys the browse tree leaves to the preview, as in the results.
+var browsePreviewKeys = []string{
+	"ctrl+k", "ctrl+j", "alt+l", "alt+w", "alt+g", "alt+k", "alt+x",
+	"alt+left", "alt+right", "alt+up", "alt+down", "alt+enter",
+}
+
+// newBrowseState starts browsing at the notes root.
+func (m Model) newBrowseState() (*browseState, error) {
+	entries, err := m.readTreeDir(m.rootPath, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &browseState{entries: entries, expanded: map[string]bool{}}, nil
+}
+
+// readTreeDir lists the folders, notes and attachments in dir, folders
+// first. Hidden files are left out.
+func (m Model) readTreeDir(dir string, depth int) ([]treeEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []treeEntry{}
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name())
+		if strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if !f.IsDir() && !notes.IsAttachment(path) && !notes.IsNote(m.rootPath, path, m.extensions) {
+			continue
+		}
+		entries = append(entries, treeEntry{path: path, depth: depth, dir: f.IsDir()})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].dir != entries[j].dir {
+			return entries[i].dir
+		}
+		return strings.ToLower(entries[i].path) < strings.ToLower(entries[j].path)
+	})
+	return entries, nil
+}
+
+// toggleFolder expands the folder at row i, reading its children, or
+// collapses it.
+func (m *Model) toggleFolder(i int) {
+	b := m.browse
+	entry := b.entries[i]
+	if b.expanded[entry.path] {
+		end := i + 1
+		for end < len(b.entries) && b.entries[end].depth > entry.depth {
+			delete(b.expanded, b.entries[end].path)
+			end++
+		}
+		b.entries = append(b.entries[:i+1], b.entries[end:]...)
+		delete(b.expanded, entry.path)
+		return
+	}
+
+	children, err := m.readTreeDir(entry.path, entry.depth+1)
+	if err != nil {
+		m.status = tr("can't read %s: %s", filepath.Base(entry.path), err)
+		return
+	}
+	b.entries = append(b.entries[:i+1], append(children, b.entries[i+1:]...)...)
+	b.expanded[entry.path] = true
+}
+
+// treeHeight is the number of rows the browse tree shows.
+func (m Model) treeHeight() int {
+	return lo.Max([]int{m.height - 2, 1})
+}
+
+// updateBrowse handles key presses while browsing.
+// Keys: up/down or tab/shift+tab - move, right/left - expand/collapse a
+// folder, enter - expand a folder or preview a note, ctrl+o - open the note,
+// esc - close the preview, then the tree. Typing starts a search.
+func (m Model) updateBrowse(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.browse
+	if len(b.entries) == 0 && key.String() != "esc" && key.String() != "alt+h" {
+		return m, nil
+	}
+
+	switch key.String() {
+	case "esc":
+		if m.preview != nil {
+			m.preview = nil
+			m.setListSize()
+			return m, nil
+		}
+		m.browse = nil
+		m.status = ""
+		return m, nil
+	case "alt+h", "ctrl+c":
+		m.browse = nil
+		m.status = ""
+		return m, nil
+	case "up", "shift+tab":
+		b.cursor = lo.Max([]int{b.cursor - 1, 0})
+	case "down", "tab":
+		b.cursor = lo.Min([]int{b.cursor + 1, len(b.entries) - 1})
+	case "pgup":
+		b.cursor = lo.Max([]int{b.cursor - m.treeHeight(), 0})
+	case "pgdown":
+		b.cursor = lo.Min([]int{b.cursor + m.treeHeight(), len(b.entries) - 1})
+	case "home":
+		b.cursor = 0
+	case "end":
+		b.cursor = len(b.entries) - 1
+	case "right":
+		if entry := b.entries[b.cursor]; entry.dir && !b.expanded[entry.path] {
+			m.toggleFolder(b.cursor)
+		}
+	case "left":
+		entry := b.entries[b.cursor]
+		if entry.dir && b.expanded[entry.path] {
+			m.toggleFolder(b.cursor)
+			break
+		}
+		// Up to the parent folder.
+		for i := b.cursor - 1; i >= 0; i-- {
+			if b.entries[i].depth < entry.depth {
+				b.cursor = i
+				break
+			}
+		}
+	case "enter":
+		entry := b.entries[b.cursor]
+		if entry.dir {
+			m.toggleFolder(b.cursor)
+			break
+		}
+		cmd := m.openPreview(entry.path)
+		m.setListSize()
+		return m, cmd
+	case "ctrl+o":
+		if entry := b.entries[b.cursor]; !entry.dir && !notes.IsAttachment(entry.path) {
+			return m, m.edit(entry.path)
+		}
+	}
+
+	// Keep the cursor in view.
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+m.treeHeight() {
+		b.offset = b.cursor - m.treeHeight() + 1
+	}
+	return m, nil
+}
+
+// viewBrowse renders the rows of the tree in view, beside the preview if
+// one is open.
+func (m Model) viewBrowse() string {
+	b := m.browse
+	width := m.width
+	if m.preview != nil {
+		width = m.width / 2
+	}
+
+	lines := []string{}
+	if len(b.entries) == 0 {
+		lines = append(lines, "  "+tr("no notes in %s", m.rootPath))
+	}
+	end := lo.Min([]int{b.offset + m.treeHeight(), len(b.entries)})
+	for i := b.offset; i < end; i++ {
+		entry := b.entries[i]
+		icon := "  "
+		if entry.dir && b.expanded[entry.path] {
+			icon = "▾ "
+		} else if entry.dir {
+			icon = "▸ "
+		}
+		name := filepath.Base(entry.path)
+		if entry.dir {
+			name += "/"
+		}
+		line := strings.Repeat("  ", entry.depth) + icon + name
+		if i == b.cursor {
+			line = theme.matchStyle(0).Render("› " + line)
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+
+	tree := lipgloss.NewStyle().Width(width).Height(m.treeHeight()).MaxWidth(width).
+		Render(strings.Join(lines, "\n"))
+	if m.preview == nil {
+		return tree
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, tree, m.previewView())
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// builderOp is a comparison offered by the query builder.
+type builderOp struct {
+	label string
+	build func(field, value string) string // the query clause
+}
+
+// builderOps build bleve clauses over the indexed frontmatter fields.
+// Dates are compared through the Dates fields, see bleve_indexer.Note.
+var builderOps = []builderOp{
+	{"is", func(field, value string) string { return "+Properties." + field + ":" + quoteValue(value) }},
+	{"is not", func(field, value string) string { return "-Properties." + field + ":" + quoteValue(value) }},
+	{"is set", func(field, _ string) string { return "+Properties." + field + ":*" }},
+	{"after", func(field, value string) string { return "+Dates." + field + `:>"` + value + `"` }},
+	{"before", func(field, value string) string { return "+Dates." + field + `:<"` + value + `"` }},
+}
+
+// quoteValue quotes values of several words as a phrase.
+func quoteValue(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + strings.ReplaceAll(value, `"`, "") + `"`
+	}
+	return value
+}
+
+// builderState is the form building a field query, e.g. status is draft,
+// for those who don't know the query syntax.
+type builderState struct {
+	field textinput.Model
+	value textinput.Model
+	op    int // index in builderOps
+	focus int // 0 field, 1 operator, 2 value
+}
+
+// newBuilderState starts an empty form.
+func newBuilderState() *builderState {
+	field := textinput.New()
+	field.Prompt = tr("Field:") + " "
+	field.Placeholder = "status"
+	field.Focus()
+
+	value := textinput.New()
+	value.Prompt = tr("Value:") + " "
+	value.Placeholder = "draft, 2024-01-31"
+
+	return &builderState{field: field, value: value}
+}
+
+// clause returns the query clause of the form, "" while incomplete.
+func (b *builderState) clause() string {
+	field := strings.ToLower(strings.TrimSpace(b.field.Value()))
+	value := strings.TrimSpace(b.value.Value())
+	op := builderOps[b.op]
+	if field == "" || strings.ContainsAny(field, " :") || (value == "" && op.label != "is set") {
+		return ""
+	}
+	return op.build(field, value)
+}
+
+// updateBuilder handles key presses while the query builder is open.
+// Keys: tab/shift+tab - next/previous input, left/right - change the
+// operator, enter - add the clause to the query, esc - cancel.
+func (m Model) updateBuilder(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.builder
+
+	switch key.String() {
+	case "esc", "ctrl+c":
+		m.builder = nil
+		return m, nil
+	case "enter":
+		clause := b.clause()
+		if clause == "" {
+			return m, nil
+		}
+		m.builder = nil
+		query := strings.TrimSpace(m.textInput.Value())
+		if query != "" {
+			query += " "
+		}
+		// The trailing space keeps the clause from being taken as a prefix.
+		m.textInput.SetValue(query + clause + " ")
+		m.textInput.CursorEnd()
+		return m, m.search(m.textInput.Value())
+	case "tab", "shift+tab":
+		if key.String() == "tab" {
+			b.focus = (b.focus + 1) % 3
+		} else {
+			b.focus = (b.focus + 2) % 3
+		}
+		b.field.Blur()
+		b.value.Blur()
+		switch b.focus {
+		case 0:
+			return m, b.field.Focus()
+		case 2:
+			return m, b.value.Focus()
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	switch b.focus {
+	case 0:
+		b.field, cmd = b.field.Update(key)
+	case 1:
+		switch key.String() {
+		case "left", "h":
+			b.op = (b.op + len(builderOps) - 1) % len(builderOps)
+		case "right", "l", " ":
+			b.op = (b.op + 1) % len(builderOps)
+		}
+	case 2:
+		b.value, cmd = b.value.Update(key)
+	}
+	return m, cmd
+}
+
+// viewBuilder renders the form and the clause it builds.
+func (m Model) viewBuilder() string {
+	b := m.builder
+
+	op := tr("Operator:") + " ‹ " + tr(builderOps[b.op].label) + " ›"
+	if b.focus == 1 {
+		op = theme.matchStyle(0).Render(op)
+	}
+
+	clause := b.clause()
+	if clause == "" {
+		clause = tr("(incomplete)")
+	}
+
+	lines := []string{
+		b.field.View(),
+		op,
+		b.value.View(),
+		"",
+		"→ " + clause,
+		"",
+		theme.Status.Copy().UnsetPaddingLeft().Render(
+			tr("tab next · ←/→ operator · enter add to the query · esc cancel")),
+	}
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+)
+
+// calendarState is the month grid of the daily notes.
+type calendarState struct {
+	day   time.Time    // selected day
+	notes map[int]bool // days of the shown month that have a daily note
+}
+
+// newCalendarState shows the month of day.
+func (m Model) newCalendarState(day time.Time) *calendarState {
+	c := &calendarState{day: day}
+	m.scanMonth(c)
+	return c
+}
+
+// scanMonth looks up which days of the shown month have a daily note.
+func (m Model) scanMonth(c *calendarState) {
+	c.notes = map[int]bool{}
+	first := time.Date(c.day.Year(), c.day.Month(), 1, 0, 0, 0, 0, time.Local)
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		if _, err := os.Stat(m.dailyNote(d)); err == nil {
+			c.notes[d.Day()] = true
+		}
+	}
+}
+
+// updateCalendar handles key presses while the calendar is open.
+// Keys: arrows or hjkl - move by day and week, [ ] - previous and next
+// month, t - today, enter - open or create the daily note, esc - close.
+func (m Model) updateCalendar(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.calendar
+	day := c.day
+
+	switch key.String() {
+	case "esc", "ctrl+c", "alt+j":
+		m.calendar = nil
+		return m, nil
+	case "left", "h":
+		day = day.AddDate(0, 0, -1)
+	case "right", "l":
+		day = day.AddDate(0, 0, 1)
+	case "up", "k":
+		day = day.AddDate(0, 0, -7)
+	case "down", "j":
+		day = day.AddDate(0, 0, 7)
+	case "[", "pgup":
+		day = day.AddDate(0, -1, 0)
+	case "]", "pgdown":
+		day = day.AddDate(0, 1, 0)
+	case "t":
+		day = time.Now()
+	case "enter":
+		m.calendar = nil
+		return m.openDailyNote(c.day)
+	}
+
+	otherMonth := day.Month() != c.day.Month() || day.Year() != c.day.Year()
+	c.day = day
+	if otherMonth {
+		m.scanMonth(c)
+	}
+	return m, nil
+}
+
+// openDailyNote opens the daily note of day in the editor, creating it
+// with the date as heading if it doesn't exist yet.
+func (m Model) openDailyNote(day time.Time) (tea.Model, tea.Cmd) {
+	path := m.dailyNote(day)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte("# "+day.Format("2006-01-02")+"\n"), 0644)
+		}
+		if err != nil {
+			m.status = tr("can't create note: %s", err)
+			return m, nil
+		}
+		m.status = tr("created %s", filepath.ToSlash(path))
+	}
+
+	return m, m.edit(path)
+}
+
+// viewCalendar renders the month grid in place of the results. Days with
+// a daily note are highlighted, today is underlined.
+func (m Model) viewCalendar() string {
+	c := m.calendar
+	first := time.Date(c.day.Year(), c.day.Month(), 1, 0, 0, 0, 0, time.Local)
+	today := time.Now()
+
+	title := fmt.Sprintf("%s %d", tr(first.Month().String()), first.Year())
+	lines := []string{
+		theme.Status.Copy().UnsetPaddingLeft().Bold(true).Render(title),
+		tr("Mo Tu We Th Fr Sa Su"),
+	}
+
+	// Weeks start on Monday.
+	cells := make([]string, (int(first.Weekday())+6)%7)
+	for i := range cells {
+		cells[i] = "  "
+	}
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		style := lipgloss.NewStyle()
+		if c.notes[d.Day()] {
+			style = theme.matchStyle(0).Copy()
+		}
+		if d.YearDay() == today.YearDay() && d.Year() == today.Year() {
+			style = style.Underline(true)
+		}
+		if d.Day() == c.day.Day() {
+			style = style.Reverse(true)
+		}
+		cells = append(cells, style.Render(fmt.Sprintf("%2d", d.Day())))
+	}
+	for len(cells) > 0 {
+		week := cells[:lo.Min([]int{7, len(cells)})]
+		lines = append(lines, strings.Join(week, " "))
+		cells = cells[len(week):]
+	}
+
+	lines = append(lines, "", theme.Status.Copy().UnsetPaddingLeft().Render(
+		tr("enter open or create · arrows move · [ ] month · t today · esc close")))
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// fileType describes the file from its extension, or its content when the
+// extension is unknown, e.g. "image/png".
+func fileType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "?"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+// metadataCard describes a file without a text preview, such as an image
+// or a pdf: its type, size, modification and creation time and the notes linking to it.
+func (m *Model) metadataCard(path string) string {
+	label := theme.Status.Copy().UnsetPaddingLeft()
+	lines := []string{theme.matchStyle(0).Render(filepath.Base(path)), ""}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return strings.Join(append(lines, tr("Error: %s", err)), "\n")
+	}
+	lines = append(lines,
+		label.Render(tr("Type:"))+" "+fileType(path),
+		label.Render(tr("Size:"))+" "+formatBytes(info.Size()),
+		label.Render(tr("Modified:"))+" "+info.ModTime().Format("2006-01-02 15:04"),
+	)
+	if created, ok := notes.Birthtime(path); ok {
+		lines = append(lines, label.Render(tr("Created:"))+" "+created.Format("2006-01-02 15:04"))
+	}
+
+	if notes.IsAttachment(path) {
+		// The notes linking to the attachment came with the hit.
+		var referencedBy []string
+		if item, ok := lo.Find(m.results, func(item list.Item) bool { return item.(Note).path == path }); ok {
+			referencedBy = item.(Note).referencedBy
+		}
+		lines = append(lines, "", label.Render(tr("Referenced by:")))
+		if len(referencedBy) == 0 {
+			lines = append(lines, "  "+tr("no note references this attachment"))
+		}
+		for _, ref := range referencedBy {
+			lines = append(lines, "  "+filepath.ToSlash(ref))
+		}
+		if len(referencedBy) > 0 {
+			lines = append(lines, "", label.Render(tr("ctrl+o opens the first note linking to it")))
+		}
+	}
+	return lipgloss.NewStyle().PaddingLeft(1).Render(strings.Join(lines, "\n"))
+}
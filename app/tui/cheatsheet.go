@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/samber/lo"
+)
+
+// cheatSheetMsg carries the query syntax of the backend.
+type cheatSheetMsg struct {
+	backend []search.SyntaxEntry
+}
+
+// showCheatSheet fetches the query syntax of the backend, which may be a
+// daemon, and shows it once it arrives.
+func (m Model) showCheatSheet() tea.Cmd {
+	indexer := m.indexer
+	return func() tea.Msg {
+		return cheatSheetMsg{indexer.Syntax()}
+	}
+}
+
+// viewCheatSheet renders the cheat sheet in place of the results.
+func (m Model) viewCheatSheet() string {
+	section := func(title string, entries []search.SyntaxEntry) []string {
+		width := lo.Max(lo.Map(entries, func(e search.SyntaxEntry, _ int) int { return lipgloss.Width(e.Example) }))
+		lines := []string{theme.Status.Copy().UnsetPaddingLeft().Bold(true).Render(title)}
+		for _, e := range entries {
+			example := e.Example + strings.Repeat(" ", width-lipgloss.Width(e.Example))
+			lines = append(lines, "  "+theme.matchStyle(0).Render(example)+"  "+tr(e.Help))
+		}
+		return append(lines, "")
+	}
+
+	lines := section(tr("notes_search operators"), search.Operators)
+	lines = append(lines, section(tr("backend query syntax"), m.cheatSheet)...)
+	if len(m.macros) > 0 {
+		macros := lo.MapToSlice(m.macros, func(name, query string) search.SyntaxEntry {
+			return search.SyntaxEntry{Example: "@" + name, Help: query}
+		})
+		sort.Slice(macros, func(i, j int) bool { return macros[i].Example < macros[j].Example })
+		lines = append(lines, section(tr("macros"), macros)...)
+	}
+	lines = append(lines, theme.Status.Copy().UnsetPaddingLeft().Render(tr("press any key to close")))
+
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
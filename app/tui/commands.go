@@ -0,0 +1,586 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/atotto/clipboard"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/search/bleve_indexer"
+	"github.com/noelzubin/notes_search/search/remote"
+	"github.com/noelzubin/notes_search/stats"
+	"github.com/noelzubin/notes_search/trash"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// Address the daemon listens on when none is given to `serve`.
+const defaultServeAddr = "localhost:7331"
+
+// command is run from the command line instead of starting the TUI,
+// e.g. `notes_search empty-trash`.
+type command struct {
+	args string // arguments taken by the command
+	help string // short description
+	run  func(config *utils.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"serve": {
+		args: "[addr]",
+		help: "serve the index to --connect clients (default " + defaultServeAddr + ")",
+		run:  runServe,
+	},
+	"replace": {
+		args: "[--regex] [--query q] <pattern> <replacement>",
+		help: "replace text across the results of a query",
+		run:  runReplace,
+	},
+	"verify": {
+		args: "[--repair]",
+		help: "check the index against the notes on disk",
+		run:  runVerify,
+	},
+	"clip": {
+		help: "create a note in the inbox from the clipboard",
+		run:  runClip,
+	},
+	"index": {
+		args: "[--dry-run]",
+		help: "index the notes, or list what would change",
+		run:  runIndex,
+	},
+	"tag": {
+		args: "rename [--dry-run] <old> <new> | list [query]",
+		help: "rename a tag across the notes, merging it into new if that exists, or count the tags",
+		run:  runTag,
+	},
+	"links": {
+		args: "orphans | broken",
+		help: "list the notes without links from or to other notes, or the links to missing notes",
+		run:  runLinks,
+	},
+	"stats": {
+		args: "[--usage]",
+		help: "show a histogram of the recent search latencies and a heatmap of the notes created or modified each day, or the usage metrics",
+		run:  runStats,
+	},
+	"snapshot": {
+		args: "create [file] | restore <file>",
+		help: "save the index to a tarball, or restore it from one",
+		run:  runSnapshot,
+	},
+	"empty-trash": {
+		help: "permanently remove deleted notes",
+		run:  runEmptyTrash,
+	},
+}
+
+// usage prints the flags and the available commands.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: notes_search [flags] [command]\n\nflags:\n")
+	flag.PrintDefaults()
+
+	fmt.Fprintf(out, "\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\t%s\n", name, commands[name].args, commands[name].help)
+	}
+	w.Flush()
+}
+
+// runServe indexes the notes and serves them over HTTP.
+func runServe(config *utils.Config, args []string) error {
+	addr := defaultServeAddr
+	if len(args) > 0 {
+		addr = args[0]
+	}
+	// Fail before indexing, which can take a while.
+	if err := config.Server.Validate(); err != nil {
+		return err
+	}
+
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+
+	// Index once up front so clients don't start on a stale index.
+	indexer.IndexNotes(context.Background())
+	return remote.Serve(addr, indexer, config)
+}
+
+// runClip creates a note in the inbox from the clipboard and indexes it.
+func runClip(config *utils.Config, args []string) error {
+	content, err := clipboard.ReadAll()
+	if err != nil {
+		return err
+	}
+	path, err := notes.Create(config.InboxDir(), notes.NewNoteExtension(config.NoteExtensions()), content)
+	if err != nil {
+		return err
+	}
+
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+	indexer.IndexNotes(context.Background())
+	fmt.Println(path)
+	return nil
+}
+
+// runIndex indexes the notes of every vault. With --dry-run it lists the
+// notes that would be added, updated or deleted instead, and why.
+func runIndex(config *utils.Config, args []string) error {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the changes, leave the index alone")
+	flags.Parse(args)
+
+	if !*dryRun {
+		indexer, err := newIndexer(config)
+		if err != nil {
+			return err
+		}
+		indexer.IndexNotes(context.Background())
+
+		// The other roots are indexed even if some fail.
+		failed := 0
+		for _, status := range indexer.IndexStatus() {
+			if status.Err != "" {
+				fmt.Fprintf(os.Stderr, "skipped %s: %s\n", status.Root, status.Err)
+				failed++
+			}
+			if status.Skipped > 0 {
+				fmt.Fprintf(os.Stderr, "%d files skipped due to permissions in %s, see the debug log\n", status.Skipped, status.Root)
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of the notes roots couldn't be indexed", failed)
+		}
+		return nil
+	}
+
+	if connectAddr != "" {
+		return errors.New("--dry-run checks the local index, run it on the daemon's host")
+	}
+
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, vault := range config.VaultConfigs() {
+		changes, err := bleve_indexer.PlanIndex(vault)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", change.Action, change.Reason, change.Path)
+			counts[change.Action]++
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("%d to add, %d to update, %d to delete\n", counts["add"], counts["update"], counts["delete"])
+	return nil
+}
+
+// runStats prints the latencies of the recent searches in the TUI and a
+// heatmap of the days the notes were created or modified on over the last
+// year, or with --usage the usage metrics.
+func runStats(config *utils.Config, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	usage := flags.Bool("usage", false, "report the usage metrics")
+	flags.Parse(args)
+
+	if *usage {
+		return printUsage(config)
+	}
+
+	latencies, err := stats.NewLatencies().All()
+	if err != nil {
+		return err
+	}
+	fmt.Print(stats.Histogram(latencies, 40))
+
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+	end := time.Now()
+	activity, err := indexer.Activity(context.Background(), stats.HeatmapStart(end, stats.HeatmapWeeks))
+	if err != nil {
+		return err
+	}
+	fmt.Print("\n" + stats.Heatmap(activity, end, stats.HeatmapWeeks))
+	return nil
+}
+
+// printUsage prints the recorded usage metrics and the size of the indexes.
+func printUsage(config *utils.Config) error {
+	if !config.UsageMetrics {
+		fmt.Println("usage metrics are off, set usage_metrics: true in the config to record them")
+		return nil
+	}
+	report, err := stats.NewUsage().Report()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if !report.Since.IsZero() {
+		fmt.Fprintf(w, "since\t%s\n", report.Since.Format("2006-01-02"))
+	}
+	fmt.Fprintf(w, "queries\t%d\n", report.Queries)
+	if report.Queries > 0 {
+		fmt.Fprintf(w, "average query\t%s\n", stats.Round(report.QueryTime/time.Duration(report.Queries)))
+	}
+	fmt.Fprintf(w, "reindexes\t%d\n", report.Reindexes)
+	if report.Reindexes > 0 {
+		fmt.Fprintf(w, "average reindex\t%s\n", stats.Round(report.IndexTime/time.Duration(report.Reindexes)))
+		fmt.Fprintf(w, "last reindex\t%s\n", stats.Round(report.LastIndex))
+	}
+	fmt.Fprintf(w, "indexed notes\t%d\n", report.IndexedNotes)
+	if report.SkippedFiles > 0 {
+		fmt.Fprintf(w, "skipped (permissions)\t%d\n", report.SkippedFiles)
+	}
+	for _, vault := range config.VaultConfigs() {
+		fmt.Fprintf(w, "index size %s\t%s\n", vault.VaultName(), formatBytes(bleve_indexer.IndexSize(vault)))
+	}
+	return w.Flush()
+}
+
+// formatBytes formats a size with a binary unit, e.g. 12.3 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runSnapshot saves the indexes of every vault to a tarball, or restores
+// them from one.
+func runSnapshot(config *utils.Config, args []string) error {
+	if connectAddr != "" {
+		return errors.New("snapshots are of the local index, run it on the daemon's host")
+	}
+	if len(args) == 0 {
+		return errors.New("usage: notes_search snapshot create [file] | restore <file>")
+	}
+
+	switch args[0] {
+	case "create":
+		file := "notes_search-" + time.Now().Format("2006-01-02") + ".tar.gz"
+		if len(args) > 1 {
+			file = args[1]
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		if err := bleve_indexer.CreateSnapshot(config.VaultConfigs(), f); err != nil {
+			f.Close()
+			os.Remove(file)
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Println(file)
+		return nil
+	case "restore":
+		if len(args) < 2 {
+			return errors.New("usage: notes_search snapshot restore <file>")
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		restored, err := bleve_indexer.RestoreSnapshot(config.VaultConfigs(), f)
+		for _, root := range restored {
+			fmt.Printf("restored the index of %s\n", root)
+		}
+		if err != nil {
+			return err
+		}
+		if len(restored) == 0 {
+			return errors.New("the snapshot has no index of the configured vaults")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown snapshot command %q, use create or restore", args[0])
+}
+
+// runEmptyTrash removes everything in the trash.
+func runEmptyTrash(config *utils.Config, args []string) error {
+	n, err := trash.New().Empty()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("removed %d notes from the trash\n", n)
+	return nil
+}
+
+// runVerify reports the drift between the local indexes and the notes on
+// disk, and fixes it with --repair.
+func runVerify(config *utils.Config, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "fix the drift by updating the index")
+	flags.Parse(args)
+
+	if connectAddr != "" {
+		return errors.New("verify checks the local index, run it on the daemon's host")
+	}
+
+	drift := bleve_indexer.Drift{}
+	for _, vault := range config.VaultConfigs() {
+		indexer, err := bleve_indexer.NewBleveIndexer(vault)
+		if err != nil {
+			return err
+		}
+		d, err := indexer.Verify(*repair)
+		indexer.CloseIndex()
+		if err != nil {
+			return err
+		}
+		drift.Missing = append(drift.Missing, d.Missing...)
+		drift.Stale = append(drift.Stale, d.Stale...)
+		drift.Changed = append(drift.Changed, d.Changed...)
+	}
+
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"missing", drift.Missing},
+		{"stale", drift.Stale},
+		{"changed", drift.Changed},
+	} {
+		for _, path := range group.paths {
+			fmt.Printf("%-8s %s\n", group.label, path)
+		}
+	}
+
+	switch {
+	case drift.Empty():
+		fmt.Println("index is up to date")
+	case *repair:
+		fmt.Printf("repaired %d missing, %d stale and %d changed notes\n", len(drift.Missing), len(drift.Stale), len(drift.Changed))
+	default:
+		return fmt.Errorf("index drifted: %d missing, %d stale, %d changed (run with --repair to fix)", len(drift.Missing), len(drift.Stale), len(drift.Changed))
+	}
+	return nil
+}
+
+// runTag renames a tag in the frontmatter and the inline #tags of every
+// note of every vault, then reindexes.
+func runTag(config *utils.Config, args []string) error {
+	usage := errors.New("usage: notes_search tag rename [--dry-run] <old> <new> | tag list [query]")
+	if len(args) > 0 && args[0] == "list" {
+		return runTagList(config, strings.Join(args[1:], " "))
+	}
+	if len(args) == 0 || args[0] != "rename" {
+		return usage
+	}
+	flags := flag.NewFlagSet("tag rename", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only show the changes")
+	flags.Parse(args[1:])
+	if flags.NArg() != 2 {
+		return usage
+	}
+
+	paths := []string{}
+	for _, vault := range config.VaultConfigs() {
+		paths = append(paths, notes.Paths(vault)...)
+	}
+	paths = lo.Filter(lo.Uniq(paths), func(path string, _ int) bool { return !notes.IsAttachment(path) })
+
+	renames, err := notes.PlanTagRename(paths, flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	for _, rename := range renames {
+		fmt.Println(rename.Path)
+		if rename.FrontmatterChanged() {
+			fmt.Printf("  tags: [%s] -> [%s]\n", strings.Join(rename.Before, ", "), strings.Join(rename.After, ", "))
+		}
+		for _, o := range rename.Inline {
+			fmt.Printf("%4d - %s\n%4d + %s\n", o.Line, o.Before, o.Line, o.After)
+		}
+	}
+
+	if *dryRun || len(renames) == 0 {
+		fmt.Printf("%d notes to change\n", len(renames))
+		return nil
+	}
+
+	for _, rename := range renames {
+		if err := rename.Apply(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("renamed the tag in %d notes\n", len(renames))
+
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+	indexer.IndexNotes(context.Background())
+	return nil
+}
+
+// runTagList prints the tags of the notes matching the query with the
+// number of notes having each, most used first.
+func runTagList(config *utils.Config, query string) error {
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+	tags, err := indexer.Tags(context.Background(), utils.ExpandMacros(query, config.Macros))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%s\t%d\n", tag.Tag, tag.Count)
+	}
+	return w.Flush()
+}
+
+// runLinks prints the orphan notes, or the broken links with the note
+// they are in.
+func runLinks(config *utils.Config, args []string) error {
+	if len(args) != 1 || (args[0] != "orphans" && args[0] != "broken") {
+		return errors.New("usage: notes_search links orphans | broken")
+	}
+	graph := linkGraph(config.VaultConfigs())
+	if args[0] == "orphans" {
+		for _, path := range graph.Orphans() {
+			fmt.Println(path)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, link := range graph.Broken {
+		fmt.Fprintf(w, "%s\t%s\n", link.Source, link.Target)
+	}
+	return w.Flush()
+}
+
+// wordsQuery returns a query for the notes with the words of the pattern,
+// leaving out anything the backends would read as query syntax. Notes it
+// finds without the pattern itself just have nothing to replace.
+func wordsQuery(pattern string) string {
+	words := strings.FieldsFunc(pattern, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+	return strings.Join(words, " ")
+}
+
+// allHits returns the paths of every note matching the query, page by
+// page.
+func allHits(ctx context.Context, indexer search.NotesIndexer, query string) ([]string, error) {
+	paths := []string{}
+	for from := 0; ; from += search.DefaultSize {
+		result := indexer.SearchPage(ctx, query, from, search.DefaultSize)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		for _, hit := range result.Hits {
+			paths = append(paths, hit.Path)
+		}
+		if len(result.Hits) < search.DefaultSize {
+			return paths, nil
+		}
+	}
+}
+
+// runReplace replaces pattern in the notes matching the query,
+// asking for confirmation before writing each note.
+func runReplace(config *utils.Config, args []string) error {
+	flags := flag.NewFlagSet("replace", flag.ExitOnError)
+	regex := flags.Bool("regex", false, "treat the pattern as a regular expression")
+	query := flags.String("query", "", "notes to replace in (default: the notes with the words of the pattern)")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		return errors.New("usage: notes_search replace [--regex] [--query q] <pattern> <replacement>")
+	}
+	pattern, replacement := flags.Arg(0), flags.Arg(1)
+	if *query == "" {
+		if *regex {
+			return errors.New("--query is required with --regex")
+		}
+		*query = wordsQuery(pattern)
+		if *query == "" {
+			return errors.New("--query is required when the pattern has no words")
+		}
+	}
+
+	indexer, err := newIndexer(config)
+	if err != nil {
+		return err
+	}
+
+	paths, err := allHits(context.Background(), indexer, utils.ExpandMacros(*query, config.Macros))
+	if err != nil {
+		return err
+	}
+	plans, err := notes.PlanReplace(paths, pattern, replacement, *regex)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d notes match the query, %d contain the pattern\n", len(paths), len(plans))
+
+	in := bufio.NewReader(os.Stdin)
+	replaced := 0
+	for _, plan := range plans {
+		fmt.Println(plan.Path)
+		for _, o := range plan.Occurrences {
+			fmt.Printf("%4d - %s\n%4d + %s\n", o.Line, o.Before, o.Line, o.After)
+		}
+
+		fmt.Print("replace? [y/N/q] ")
+		answer, _ := in.ReadString('\n')
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		if answer == "q" {
+			break
+		}
+		if answer != "y" {
+			continue
+		}
+
+		if err := plan.Apply(); err != nil {
+			return err
+		}
+		replaced++
+	}
+
+	fmt.Printf("replaced in %d of %d notes\n", replaced, len(plans))
+	if replaced > 0 {
+		indexer.IndexNotes(context.Background())
+	}
+	return nil
+}
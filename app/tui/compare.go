@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/samber/lo"
+)
+
+// compareState shows two notes side by side, or their differences as a
+// unified diff, in place of the results.
+type compareState struct {
+	paths   [2]string
+	lines   []diffLine // the notes aligned line by line
+	unified bool       // show the unified diff instead of the notes side by side
+	offset  int        // first line shown
+}
+
+// diffLine is a line of one note or both: removed lines are only in the
+// first note, added ones only in the second.
+type diffLine struct {
+	kind        byte // ' ' in both, '-' removed, '+' added, '@' hunk header of the unified diff
+	left, right int  // line numbers in the notes, 0 when not in it
+	text        string
+}
+
+// Lines of context around the changes of the unified diff.
+const diffContext = 3
+
+// Most pairs of lines the diff compares, past it the differing middle of
+// the notes is shown as removed then added.
+const maxDiffCells = 4_000_000
+
+// newCompareState reads the two notes and aligns them.
+func newCompareState(a, b string) (*compareState, error) {
+	texts := [2][]string{}
+	for i, path := range []string{a, b} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		texts[i] = strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+	}
+	return &compareState{paths: [2]string{a, b}, lines: diffLines(texts[0], texts[1])}, nil
+}
+
+// comparedPaths returns the two notes alt+d compares: the two marked ones,
+// or the marked one and the selected one.
+func (m *Model) comparedPaths() ([]string, bool) {
+	paths := m.targetPaths()
+	if len(paths) == 1 && m.list.SelectedItem() != nil {
+		paths = lo.Uniq(append(paths, m.list.SelectedItem().(Note).path))
+	}
+	return paths, len(paths) == 2
+}
+
+// updateCompare handles key presses while comparing notes.
+// Keys: up/down - scroll, pgup/pgdown - scroll a page, tab - switch
+// between side by side and the unified diff, esc or alt+d - close.
+func (m Model) updateCompare(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.compare
+	page := lo.Max([]int{m.height - 5, 1})
+	switch key.String() {
+	case "esc", "alt+d":
+		m.compare = nil
+		m.status = ""
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	case "tab":
+		c.unified = !c.unified
+		c.offset = 0
+	case "up", "k":
+		c.offset--
+	case "down", "j":
+		c.offset++
+	case "pgup":
+		c.offset -= page
+	case "pgdown", " ":
+		c.offset += page
+	case "home":
+		c.offset = 0
+	}
+	c.offset = lo.Clamp(c.offset, 0, lo.Max([]int{len(c.rows()) - page, 0}))
+	return m, nil
+}
+
+// rows returns the lines shown in the current mode.
+func (c *compareState) rows() []diffLine {
+	if c.unified {
+		return unifiedLines(c.lines)
+	}
+	return c.lines
+}
+
+// viewCompare renders the compared notes in place of the results.
+func (m Model) viewCompare() string {
+	c := m.compare
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	height := lo.Max([]int{m.height - 5, 1})
+	rows := c.rows()
+	rows = rows[lo.Min([]int{c.offset, len(rows)}):lo.Min([]int{c.offset + height, len(rows)})]
+
+	var lines []string
+	if c.unified {
+		lines = append(lines, theme.Removed.Render("--- "+m.relPath(c.paths[0])), theme.Added.Render("+++ "+m.relPath(c.paths[1])))
+		for _, row := range rows {
+			lines = append(lines, truncate.String(renderUnified(row), uint(lo.Max([]int{m.width - 2, 1}))))
+		}
+	} else {
+		width := lo.Max([]int{(m.width - 5) / 2, 1})
+		lines = append(lines, pad(theme.Removed.Render(m.relPath(c.paths[0])), width)+" │ "+theme.Added.Render(m.relPath(c.paths[1])), "")
+		for _, row := range rows {
+			lines = append(lines, sideBySide(row, width))
+		}
+	}
+	if len(c.lines) > 0 && lo.EveryBy(c.lines, func(l diffLine) bool { return l.kind == ' ' }) {
+		lines = append(lines, faint.Render(tr("the notes are identical")))
+	}
+	lines = append(lines, "", faint.Render(tr("tab side by side/diff · ↑↓ pgup pgdown scroll · esc close")))
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// renderUnified renders a line of the unified diff, a hunk header when
+// it's a gap.
+func renderUnified(row diffLine) string {
+	switch row.kind {
+	case '-':
+		return theme.Removed.Render("-" + row.text)
+	case '+':
+		return theme.Added.Render("+" + row.text)
+	case '@':
+		return theme.Status.Copy().UnsetPaddingLeft().Render(row.text)
+	}
+	return " " + row.text
+}
+
+// sideBySide renders a line of the aligned notes in two columns of width,
+// leaving the side that lacks it empty.
+func sideBySide(row diffLine, width int) string {
+	cell := func(text string, style lipgloss.Style) string {
+		return pad(style.Render(truncate.String(expandTabs(text), uint(width))), width)
+	}
+	plain := lipgloss.NewStyle()
+	switch row.kind {
+	case '-':
+		return cell(row.text, theme.Removed) + " │"
+	case '+':
+		return pad("", width) + " │ " + cell(row.text, theme.Added)
+	}
+	return cell(row.text, plain) + " │ " + cell(row.text, plain)
+}
+
+// pad fills s with spaces up to width columns.
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", lo.Max([]int{width - lipgloss.Width(s), 0}))
+}
+
+// expandTabs replaces tabs by spaces so the columns stay aligned.
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// unifiedLines keeps the changed lines with diffContext lines around them,
+// each group of changes headed like in a unified diff.
+func unifiedLines(lines []diffLine) []diffLine {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.kind == ' ' {
+			continue
+		}
+		for j := lo.Max([]int{i - diffContext, 0}); j <= lo.Min([]int{i + diffContext, len(lines) - 1}); j++ {
+			keep[j] = true
+		}
+	}
+
+	out := []diffLine{}
+	for i := 0; i < len(lines); i++ {
+		if !keep[i] {
+			continue
+		}
+		if i == 0 || !keep[i-1] {
+			out = append(out, diffLine{kind: '@', text: hunkHeader(lines, i)})
+		}
+		out = append(out, lines[i])
+	}
+	return out
+}
+
+// hunkHeader returns the @@ -l +r @@ header of the hunk starting at i.
+func hunkHeader(lines []diffLine, i int) string {
+	left, right := 0, 0
+	for _, line := range lines[i:] {
+		if left == 0 && line.left > 0 {
+			left = line.left
+		}
+		if right == 0 && line.right > 0 {
+			right = line.right
+		}
+		if left > 0 && right > 0 {
+			break
+		}
+	}
+	return fmt.Sprintf("@@ -%d +%d @@", left, right)
+}
+
+// diffLines aligns the lines of a and b on their longest common
+// subsequence.
+func diffLines(a, b []string) []diffLine {
+	// The common start and end are aligned as is, the rest is compared.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := []diffLine{}
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, diffLine{kind: ' ', left: i + 1, right: i + 1, text: a[i]})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, diffLine{kind: ' ', left: len(a) - i + 1, right: len(b) - i + 1, text: a[len(a)-i]})
+	}
+	return lines
+}
+
+// diffMiddle aligns a and b, which start after the first offset lines of
+// the notes.
+func diffMiddle(a, b []string, offset int) []diffLine {
+	lines := []diffLine{}
+	removed := func(i int) { lines = append(lines, diffLine{kind: '-', left: offset + i + 1, text: a[i]}) }
+	added := func(j int) { lines = append(lines, diffLine{kind: '+', right: offset + j + 1, text: b[j]}) }
+
+	if len(a)*len(b) > maxDiffCells {
+		for i := range a {
+			removed(i)
+		}
+		for j := range b {
+			added(j)
+		}
+		return lines
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = lo.Max([]int{common[i+1][j], common[i][j+1]})
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{kind: ' ', left: offset + i + 1, right: offset + j + 1, text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			removed(i)
+			i++
+		default:
+			added(j)
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		removed(i)
+	}
+	for ; j < len(b); j++ {
+		added(j)
+	}
+	return lines
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/stats"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// Recently modified notes listed on the dashboard.
+const dashboardRecent = 8
+
+// dashboardState is the start screen: the recent and pinned notes, the
+// open tasks and the state of the index.
+type dashboardState struct {
+	loaded    bool
+	recent    []string             // paths of the recently modified notes
+	pinned    []string             // paths of the pinned notes that exist
+	openTasks int                  // unchecked task items across the notes, -1 when not counted
+	indexed   uint64               // notes in the index
+	statuses  []search.IndexStatus // last reindex of each root
+	cursor    int                  // selected note, pinned ones first
+}
+
+// This is emitted when the dashboard is loaded
+type dashboardMsg struct {
+	dashboard dashboardState
+}
+
+// paths lists the notes of the dashboard in the order they're shown.
+func (d *dashboardState) paths() []string {
+	return append(append([]string{}, d.pinned...), d.recent...)
+}
+
+// loadDashboard gathers what the dashboard shows and reports back with
+// dashboardMsg.
+func (m Model) loadDashboard() tea.Cmd {
+	indexer, pinned, openTasks, ctx := m.indexer, m.pinned, m.openTasks, m.ctx
+	return func() tea.Msg {
+		d := dashboardState{loaded: true, openTasks: -1, statuses: indexer.IndexStatus()}
+		d.indexed, _ = indexer.DocCount()
+		hits := indexer.Search(ctx, "").Hits
+		d.recent = lo.Map(hits[:lo.Min([]int{dashboardRecent, len(hits)})], func(hit search.DocumentMatch, _ int) string {
+			return hit.Path
+		})
+		d.pinned = lo.Map(pinnedHits(pinned), func(hit search.DocumentMatch, _ int) string { return hit.Path })
+		d.recent = lo.Without(d.recent, d.pinned...)
+		if openTasks != nil {
+			d.openTasks = openTasks()
+		}
+		return dashboardMsg{d}
+	}
+}
+
+// dashboardCmd reloads the dashboard if it's shown.
+func (m Model) dashboardCmd() tea.Cmd {
+	if m.dashboard == nil {
+		return nil
+	}
+	return m.loadDashboard()
+}
+
+// countOpenTasks counts the unchecked task items of the notes of every
+// vault.
+func countOpenTasks(config *utils.Config) int {
+	count := 0
+	for _, vault := range config.VaultConfigs() {
+		for _, path := range notes.Paths(vault) {
+			if notes.IsAttachment(path) {
+				continue
+			}
+			if body, err := os.ReadFile(path); err == nil {
+				count += notes.OpenTasks(string(body))
+			}
+		}
+	}
+	return count
+}
+
+// updateDashboard handles key presses on the dashboard.
+// Keys: up/down or tab/shift+tab - move, enter - preview the note,
+// d - open today's daily note, c - capture a note into the inbox,
+// ctrl+r - reindex, / or esc - search. Typing starts a search as well.
+func (m Model) updateDashboard(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.dashboard
+	paths := d.paths()
+	switch key.String() {
+	case "esc", "/", "alt+y":
+		m.dashboard = nil
+	case "ctrl+c":
+		return m.quit()
+	case "ctrl+r":
+		return m, m.reindex()
+	case "up", "shift+tab":
+		d.cursor = lo.Max([]int{d.cursor - 1, 0})
+	case "down", "tab":
+		d.cursor = lo.Max([]int{lo.Min([]int{d.cursor + 1, len(paths) - 1}), 0})
+	case "enter":
+		if d.cursor < len(paths) {
+			m.dashboard = nil
+			cmd := m.openPreview(paths[d.cursor])
+			m.setListSize()
+			return m, cmd
+		}
+	case "d":
+		m.dashboard = nil
+		return m.openDailyNote(time.Now())
+	case "c":
+		m.prompt = newPrompt(tr("Capture:"), "", func(m Model, value string) (Model, tea.Cmd) {
+			if strings.TrimSpace(value) == "" {
+				return m, nil
+			}
+			m.dashboard = nil
+			dir, ext := m.inboxDir, notes.NewNoteExtension(m.extensions)
+			return m, func() tea.Msg {
+				path, err := notes.Create(dir, ext, value)
+				return CreatedMsg{path, err}
+			}
+		})
+		return m, textinput.Blink
+	}
+	return m, nil
+}
+
+// viewDashboard renders the dashboard in place of the results.
+func (m Model) viewDashboard() string {
+	d := m.dashboard
+	heading := theme.Status.Copy().UnsetPaddingLeft().Bold(true)
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	if !d.loaded {
+		return lipgloss.NewStyle().PaddingLeft(2).Render(faint.Render(tr("loading…")))
+	}
+
+	lines := []string{}
+	row := 0
+	section := func(title string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		lines = append(lines, heading.Render(title))
+		for _, path := range paths {
+			line := "  " + m.relPath(path)
+			if row == d.cursor {
+				line = theme.matchStyle(0).Render("› " + m.relPath(path))
+			}
+			lines = append(lines, line)
+			row++
+		}
+		lines = append(lines, "")
+	}
+	section(tr("Pinned"), d.pinned)
+	section(tr("Recent"), d.recent)
+
+	facts := []string{tr("%d notes indexed", d.indexed)}
+	if d.openTasks >= 0 {
+		facts = append(facts, tr("%d open tasks", d.openTasks))
+	}
+	if took := lo.SumBy(d.statuses, func(s search.IndexStatus) time.Duration { return s.Took }); took > 0 {
+		facts = append(facts, tr("reindexed in %s", stats.Round(took)))
+	}
+	if skipped := lo.SumBy(d.statuses, func(s search.IndexStatus) int { return s.Skipped }); skipped > 0 {
+		facts = append(facts, tr("%d files skipped due to permissions", skipped))
+	}
+	if unindexed := lo.SumBy(d.statuses, func(s search.IndexStatus) int { return s.Errors }); unindexed > 0 {
+		facts = append(facts, tr("%d notes failed to index", unindexed))
+	}
+	if failed := lo.CountBy(d.statuses, func(s search.IndexStatus) bool { return s.Err != "" }); failed > 0 {
+		facts = append(facts, theme.Error.Render(indexSummary(d.statuses)))
+	}
+	lines = append(lines, strings.Join(facts, " · "), "", faint.Render(
+		tr("/ search · enter preview · d daily note · c capture · ctrl+r reindex")))
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// relPath returns path relative to the notes root, as is outside of it.
+func (m Model) relPath(path string) string {
+	rel, err := filepath.Rel(m.rootPath, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
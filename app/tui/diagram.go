@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time a diagram command may take before the fence is shown as is.
+const diagramTimeout = 10 * time.Second
+
+// diagramFence matches fenced code blocks, capturing their language and
+// source, e.g. "```mermaid\ngraph TD; A-->B\n```".
+var diagramFence = regexp.MustCompile("(?ms)^```[ \\t]*([\\w-]+)[^\\n]*\\n(.*?)\\n?^```[ \\t]*$")
+
+// Number of rendered diagrams kept in memory.
+const diagramCacheSize = 64
+
+// Rendered diagrams by language and source, so reopening a note doesn't run
+// the commands again. Failures are kept too. Past diagramCacheSize the
+// oldest diagram is dropped.
+var diagramCache = struct {
+	sync.Mutex
+	rendered map[string]string
+	order    []string // keys of rendered, oldest first
+}{rendered: map[string]string{}}
+
+// renderDiagrams replaces the fences in content whose language has a
+// command in commands, e.g. {mermaid: "mermaid-ascii -f {in}"}, by its
+// output in a plain fence. Fences the command fails on are left alone.
+func renderDiagrams(content string, commands map[string]string) string {
+	if len(commands) == 0 {
+		return content
+	}
+	return diagramFence.ReplaceAllStringFunc(content, func(fence string) string {
+		match := diagramFence.FindStringSubmatch(fence)
+		command, ok := commands[strings.ToLower(match[1])]
+		if !ok {
+			return fence
+		}
+
+		key := match[1] + "\x00" + match[2]
+		diagramCache.Lock()
+		rendered, ok := diagramCache.rendered[key]
+		diagramCache.Unlock()
+		if ok {
+			return rendered
+		}
+
+		rendered = fence
+		if out, err := runDiagram(command, match[2]); err != nil {
+			slog.Warn("rendering a diagram failed", "language", match[1], "err", err)
+		} else {
+			rendered = "```\n" + out + "\n```"
+		}
+		diagramCache.Lock()
+		if _, ok := diagramCache.rendered[key]; !ok {
+			diagramCache.order = append(diagramCache.order, key)
+		}
+		diagramCache.rendered[key] = rendered
+		if len(diagramCache.order) > diagramCacheSize {
+			delete(diagramCache.rendered, diagramCache.order[0])
+			diagramCache.order = diagramCache.order[1:]
+		}
+		diagramCache.Unlock()
+		return rendered
+	})
+}
+
+// runDiagram runs the diagram command on source and returns what it
+// printed. The source is written to a file for {in}, piped in otherwise.
+func runDiagram(command, source string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("empty diagram command")
+	}
+
+	var stdin *strings.Reader
+	if strings.Contains(command, "{in}") {
+		f, err := os.CreateTemp("", "diagram-")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(source)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		for i, arg := range args {
+			args[i] = strings.ReplaceAll(arg, "{in}", f.Name())
+		}
+	} else {
+		stdin = strings.NewReader(source)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagramTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
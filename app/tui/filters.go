@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+)
+
+// parseFilters turns the answer of the filter prompt into query filters:
+// ext:md, path:work/ and tag:todo, or their shorthands .md, work/ and
+// #todo, and the ~ of fuzzy search.
+func parseFilters(value string) ([]string, error) {
+	filters := []string{}
+	for _, field := range strings.Fields(value) {
+		lower := strings.ToLower(field)
+		switch {
+		case strings.HasPrefix(lower, "ext:"), strings.HasPrefix(lower, "path:"), strings.HasPrefix(lower, "tag:"):
+			if strings.HasSuffix(field, ":") {
+				return nil, errors.New(tr("%s needs a value", field))
+			}
+		case field == "~":
+		case strings.HasPrefix(field, ".") && len(field) > 1:
+			field = "ext:" + field[1:]
+		case strings.HasSuffix(field, "/"):
+			field = "path:" + field
+		case strings.HasPrefix(field, "#") && len(field) > 1:
+			field = "tag:" + field[1:]
+		default:
+			return nil, errors.New(tr("%s is not a filter, use ext:md, path:work/ or tag:todo", field))
+		}
+		filters = append(filters, field)
+	}
+	return lo.Uniq(filters), nil
+}
+
+// setFilters replaces the sticky filters by the ones typed at the prompt
+// and searches again. An empty answer clears them.
+func (m Model) setFilters(value string) (Model, tea.Cmd) {
+	filters, err := parseFilters(value)
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.filters = filters
+	m.status = lo.Ternary(len(filters) == 0, tr("filters cleared"), tr("every query is filtered (ctrl+l to change)"))
+	m.setListSize()
+	return m, m.search(m.textInput.Value())
+}
+
+// toggleFuzzy adds the lone ~ of fuzzy search to the sticky filters, or
+// takes it out, and searches again.
+func (m Model) toggleFuzzy() (Model, tea.Cmd) {
+	fuzzy := !lo.Contains(m.filters, "~")
+	m.filters = lo.Without(m.filters, "~")
+	if fuzzy {
+		m.filters = append(m.filters, "~")
+	}
+	m.status = lo.Ternary(fuzzy, tr("fuzzy search, words a few typos away match too (alt+~ to turn it off)"), tr("fuzzy search off"))
+	m.setListSize()
+	return m, m.search(m.textInput.Value())
+}
+
+// withFilters adds the sticky filters to the query. They go first so a
+// trailing space of the query still marks its last word as complete.
+func (m Model) withFilters(query string) string {
+	if len(m.filters) == 0 {
+		return query
+	}
+	return strings.Join(m.filters, " ") + " " + query
+}
+
+// viewFilters renders the sticky filters as chips above the results.
+func (m Model) viewFilters() string {
+	chip := theme.Prompt.Copy().UnsetMargins().MarginRight(1)
+	chips := lo.Map(m.filters, func(filter string, _ int) string { return chip.Render(filter) })
+	return lipgloss.NewStyle().PaddingLeft(2).Render(lipgloss.JoinHorizontal(lipgloss.Top, chips...))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// Most hops the graph view follows.
+const maxGraphHops = 5
+
+// graphState shows the notes linking to a note and the notes it links to,
+// a few hops away, in place of the results. Moving to another note and
+// centering the graph on it walks the links.
+type graphState struct {
+	graph  *notes.LinkGraph // nil until built
+	center string           // note the graph is centered on
+	back   []string         // previous centers, the last one first to go back to
+	hops   int              // links followed from the center
+	links  bool             // the cursor is on the links, not the backlinks
+	cursor [2]int           // selected row of the backlinks and of the links
+	offset [2]int           // first row shown of each
+}
+
+// graphRow is a note of the graph, hops links away from the center.
+type graphRow struct {
+	path string
+	hops int
+}
+
+// showGraph centers the graph view on the note at path, resolving the
+// links of the notes in the background.
+func (m *Model) showGraph(path string) tea.Cmd {
+	m.graphView = &graphState{center: path, hops: lo.Clamp(m.graphHops, 1, maxGraphHops)}
+	vaults := m.vaults
+	return func() tea.Msg {
+		return linkGraphMsg{linkGraph(vaults)}
+	}
+}
+
+// side returns 1 for the links and 0 for the backlinks, indexing cursor
+// and offset.
+func (g *graphState) side() int {
+	return lo.Ternary(g.links, 1, 0)
+}
+
+// rows returns the backlinks or the links around the center.
+func (g *graphState) rows(links bool) []graphRow {
+	if g.graph == nil {
+		return nil
+	}
+	if links {
+		return graphRows(g.graph.Links, g.center, g.hops)
+	}
+	return graphRows(g.graph.Backlinks, g.center, g.hops)
+}
+
+// recenter centers the graph on path, remembering the current center
+// unless going back.
+func (g *graphState) recenter(path string, back bool) {
+	if !back {
+		g.back = append(g.back, g.center)
+	}
+	g.center = path
+	g.cursor, g.offset = [2]int{}, [2]int{}
+}
+
+// graphRows walks edges breadth first from center up to hops away, then
+// lists the notes depth first, each under the note it was reached from.
+// Every note is listed once, at its fewest hops.
+func graphRows(edges map[string][]string, center string, hops int) []graphRow {
+	seen := map[string]bool{center: true}
+	children := map[string][]string{}
+	level := []string{center}
+	for depth := 1; depth <= hops && len(level) > 0; depth++ {
+		next := []string{}
+		for _, path := range level {
+			targets := append([]string{}, edges[path]...)
+			sort.Strings(targets)
+			for _, target := range targets {
+				if !seen[target] {
+					seen[target] = true
+					children[path] = append(children[path], target)
+					next = append(next, target)
+				}
+			}
+		}
+		level = next
+	}
+
+	rows := []graphRow{}
+	var walk func(path string, depth int)
+	walk = func(path string, depth int) {
+		for _, child := range children[path] {
+			rows = append(rows, graphRow{path: child, hops: depth})
+			walk(child, depth+1)
+		}
+	}
+	walk(center, 1)
+	return rows
+}
+
+// updateGraph handles key presses in the graph view.
+// Keys: left/right - backlinks or links, up/down - move, enter - center
+// the graph on the selected note, backspace - back to the previous note,
+// +/- - follow more or fewer hops, p - preview the selected note, esc or
+// alt+@ - close.
+func (m Model) updateGraph(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	g := m.graphView
+	side := g.side()
+	rows := g.rows(g.links)
+	switch key.String() {
+	case "esc", "alt+@":
+		m.graphView = nil
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	case "left", "h":
+		g.links = false
+	case "right", "l":
+		g.links = true
+	case "up", "k":
+		g.cursor[side]--
+	case "down", "j":
+		g.cursor[side]++
+	case "pgup":
+		g.cursor[side] -= m.graphRows()
+	case "pgdown":
+		g.cursor[side] += m.graphRows()
+	case "+", "=":
+		g.hops = lo.Min([]int{g.hops + 1, maxGraphHops})
+	case "-":
+		g.hops = lo.Max([]int{g.hops - 1, 1})
+	case "enter":
+		if g.cursor[side] < len(rows) {
+			g.recenter(rows[g.cursor[side]].path, false)
+		}
+	case "backspace":
+		if len(g.back) > 0 {
+			previous := g.back[len(g.back)-1]
+			g.back = g.back[:len(g.back)-1]
+			g.recenter(previous, true)
+		}
+	case "p", " ":
+		if g.cursor[side] < len(rows) {
+			m.graphView = nil
+			return m, m.openPreview(rows[g.cursor[side]].path)
+		}
+	}
+
+	side = g.side()
+	rows = g.rows(g.links)
+	g.cursor[side] = lo.Clamp(g.cursor[side], 0, lo.Max([]int{len(rows) - 1, 0}))
+	if g.cursor[side] < g.offset[side] {
+		g.offset[side] = g.cursor[side]
+	}
+	if g.cursor[side] >= g.offset[side]+m.graphRows() {
+		g.offset[side] = g.cursor[side] - m.graphRows() + 1
+	}
+	return m, nil
+}
+
+// graphRows returns how many notes fit in a column of the graph view.
+func (m Model) graphRows() int {
+	return lo.Max([]int{m.height - 8, 1})
+}
+
+// viewGraph renders the backlinks on the left and the links on the right
+// of the center note, indented by their hops from it.
+func (m Model) viewGraph() string {
+	g := m.graphView
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	width := lo.Max([]int{(m.width - 7) / 2, 1})
+	lines := []string{
+		faint.Copy().Bold(true).Render(tr("Links around %s, %d hops", m.relPath(g.center), g.hops)),
+		"",
+	}
+
+	if g.graph == nil {
+		lines = append(lines, faint.Render(tr("resolving the links…")))
+	} else {
+		columns := [2][]string{}
+		for side, links := range []bool{false, true} {
+			rows := g.rows(links)
+			arrow := lo.Ternary(links, "→ ", "← ")
+			columns[side] = append(columns[side], "  "+faint.Render(lo.Ternary(links, tr("links to"), tr("linked from"))))
+			if len(rows) == 0 {
+				columns[side] = append(columns[side], "  "+faint.Render(tr("none")))
+			}
+			offset := lo.Min([]int{g.offset[side], len(rows)})
+			for i, row := range rows[offset:lo.Min([]int{offset + m.graphRows(), len(rows)})] {
+				cell := strings.Repeat("  ", row.hops-1) + arrow + m.relPath(row.path)
+				cell = truncate.String(cell, uint(lo.Max([]int{width - 2, 1})))
+				if offset+i == g.cursor[side] && links == g.links {
+					cell = theme.matchStyle(0).Render("› " + cell)
+				} else {
+					cell = "  " + cell
+				}
+				columns[side] = append(columns[side], cell)
+			}
+		}
+		for i := 0; i < lo.Max([]int{len(columns[0]), len(columns[1])}); i++ {
+			left, right := "", ""
+			if i < len(columns[0]) {
+				left = columns[0][i]
+			}
+			if i < len(columns[1]) {
+				right = columns[1][i]
+			}
+			lines = append(lines, pad(left, width)+" │ "+right)
+		}
+	}
+
+	lines = append(lines, "", faint.Render(tr("←→ side · ↑↓ move · enter center on the note · backspace back · +/- hops · p preview · esc close")))
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
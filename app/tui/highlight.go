@@ -0,0 +1,102 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/noelzubin/notes_search/search"
+	"github.com/samber/lo"
+)
+
+// queryTerms returns the lowercased words of the query, without the
+// operators, in the order they were typed. They pick the highlight
+// colour of the matches.
+func queryTerms(query string) []string {
+	terms := []string{}
+	for _, field := range strings.Fields(search.ParseQuery(query).Text) {
+		if _, value, found := strings.Cut(field, ":"); found {
+			field = value
+		}
+		term := strings.ToLower(strings.TrimFunc(field, func(r rune) bool {
+			return !isWordRune(r)
+		}))
+		if term != "" && !lo.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// termIndex returns the index of the query term matching word, the
+// longest one when several do. The last term of a query matches as a
+// prefix, so every term is treated as one. -1 when none matches.
+func termIndex(word string, terms []string) int {
+	word = strings.ToLower(word)
+	best := -1
+	for i, term := range terms {
+		if strings.HasPrefix(word, term) && (best < 0 || len(term) > len(terms[best])) {
+			best = i
+		}
+	}
+	return best
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// sgr matches the escape sequences setting colours and attributes.
+var sgr = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// highlightTerms highlights the words of the syntax highlighted preview
+// that match a query term, each term with its own style.
+func highlightTerms(content string, terms []string) string {
+	if len(terms) == 0 {
+		return content
+	}
+
+	var out strings.Builder
+	active := "" // sequences in effect, restored after each highlight
+	prev := 0
+	for _, loc := range sgr.FindAllStringIndex(content, -1) {
+		out.WriteString(highlightWords(content[prev:loc[0]], terms, active))
+		seq := content[loc[0]:loc[1]]
+		if seq == "\x1b[0m" || seq == "\x1b[m" {
+			active = ""
+		} else {
+			active += seq
+		}
+		out.WriteString(seq)
+		prev = loc[1]
+	}
+	out.WriteString(highlightWords(content[prev:], terms, active))
+	return out.String()
+}
+
+// highlightWords highlights the matching words of text, which holds no
+// escape sequences, then restores the active ones.
+func highlightWords(text string, terms []string, active string) string {
+	var out strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			out.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if t := termIndex(word, terms); t >= 0 {
+			out.WriteString(theme.matchStyle(t).Render(word) + active)
+		} else {
+			out.WriteString(word)
+		}
+		i = j
+	}
+	return out.String()
+}
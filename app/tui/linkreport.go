@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// linkReportState lists the orphan notes, which no note links to and that
+// link to none, or the broken links, in place of the results.
+type linkReportState struct {
+	graph  *notes.LinkGraph // nil until built
+	broken bool             // list the broken links instead of the orphans
+	cursor int              // selected row
+	offset int              // first row shown
+}
+
+// This is emitted when the links of the notes were resolved
+type linkGraphMsg struct {
+	graph *notes.LinkGraph
+}
+
+// linkGraph resolves the links of the notes of the vaults.
+func linkGraph(vaults []*utils.Config) *notes.LinkGraph {
+	graph := &notes.LinkGraph{Links: map[string][]string{}, Backlinks: map[string][]string{}}
+	for _, vault := range vaults {
+		graph.Merge(notes.NewLinkGraph(vault.RootPath, notes.Paths(vault)))
+	}
+	return graph
+}
+
+// showLinkReport resolves the links of the notes in the background.
+func (m *Model) showLinkReport() tea.Cmd {
+	m.linkReport = &linkReportState{}
+	vaults := m.vaults
+	return func() tea.Msg {
+		return linkGraphMsg{linkGraph(vaults)}
+	}
+}
+
+// rows returns the number of orphans or broken links listed.
+func (r *linkReportState) rows() int {
+	switch {
+	case r.graph == nil:
+		return 0
+	case r.broken:
+		return len(r.graph.Broken)
+	}
+	return len(r.graph.Orphans())
+}
+
+// updateLinkReport handles key presses while the report is shown.
+// Keys: up/down - move, tab - switch between orphans and broken links,
+// enter - preview the orphan or the note with the broken link, c - create
+// the missing target, esc or alt+] - close.
+func (m Model) updateLinkReport(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.linkReport
+	switch key.String() {
+	case "esc", "alt+]":
+		m.linkReport = nil
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	case "tab", "shift+tab":
+		r.broken = !r.broken
+		r.cursor, r.offset = 0, 0
+	case "up", "k":
+		r.cursor--
+	case "down", "j":
+		r.cursor++
+	case "pgup":
+		r.cursor -= m.linkRows()
+	case "pgdown":
+		r.cursor += m.linkRows()
+	case "enter":
+		if r.cursor >= r.rows() {
+			return m, nil
+		}
+		path := ""
+		if r.broken {
+			path = r.graph.Broken[r.cursor].Source
+		} else {
+			path = r.graph.Orphans()[r.cursor]
+		}
+		m.linkReport = nil
+		return m, m.openPreview(path)
+	case "c":
+		if !r.broken || r.cursor >= r.rows() {
+			return m, nil
+		}
+		link := r.graph.Broken[r.cursor]
+		m.linkReport = nil
+		return m, func() tea.Msg {
+			title := strings.TrimSuffix(filepath.Base(link.Path), filepath.Ext(link.Path))
+			return CreatedMsg{path: link.Path, err: notes.CreateAt(link.Path, "# "+title+"\n")}
+		}
+	}
+	r.cursor = lo.Clamp(r.cursor, 0, lo.Max([]int{r.rows() - 1, 0}))
+	if r.cursor < r.offset {
+		r.offset = r.cursor
+	}
+	if r.cursor >= r.offset+m.linkRows() {
+		r.offset = r.cursor - m.linkRows() + 1
+	}
+	return m, nil
+}
+
+// linkRows returns how many rows of the report fit on the screen.
+func (m Model) linkRows() int {
+	return lo.Max([]int{m.height - 7, 1})
+}
+
+// viewLinkReport renders the orphan notes or the broken links.
+func (m Model) viewLinkReport() string {
+	r := m.linkReport
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	title, help := tr("Orphan notes"), tr("tab broken links · enter preview · ↑↓ move · esc close")
+	if r.broken {
+		title, help = tr("Broken links"), tr("tab orphan notes · enter preview the note · c create the target · ↑↓ move · esc close")
+	}
+	lines := []string{faint.Copy().Bold(true).Render(title), ""}
+
+	rows := []string{}
+	switch {
+	case r.graph == nil:
+		lines = append(lines, faint.Render(tr("resolving the links…")))
+	case r.broken:
+		width := lo.Max(lo.Map(r.graph.Broken, func(link notes.BrokenLink, _ int) int { return lipgloss.Width(m.relPath(link.Source)) }))
+		rows = lo.Map(r.graph.Broken, func(link notes.BrokenLink, _ int) string {
+			return pad(m.relPath(link.Source), width) + "  → " + link.Target
+		})
+		if len(rows) == 0 {
+			lines = append(lines, faint.Render(tr("no broken links")))
+		}
+	default:
+		rows = lo.Map(r.graph.Orphans(), func(path string, _ int) string { return m.relPath(path) })
+		if len(rows) == 0 {
+			lines = append(lines, faint.Render(tr("no orphan notes")))
+		}
+	}
+
+	shown := rows[lo.Min([]int{r.offset, len(rows)}):lo.Min([]int{r.offset + m.linkRows(), len(rows)})]
+	for i, row := range shown {
+		if r.offset+i == r.cursor {
+			lines = append(lines, theme.matchStyle(0).Render("› "+row))
+		} else {
+			lines = append(lines, "  "+row)
+		}
+	}
+	lines = append(lines, "", faint.Render(help))
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
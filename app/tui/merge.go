@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// promptMerge asks which note the marked notes are merged into, the first
+// of them by default.
+func (m Model) promptMerge() (Model, tea.Cmd) {
+	paths := lo.Filter(m.targetPaths(), func(path string, _ int) bool { return !notes.IsAttachment(path) })
+	if len(m.selected) < 2 || len(paths) < 2 {
+		m.status = tr("mark the notes to merge with ctrl+s")
+		return m, nil
+	}
+	return m.confirmPrivate(paths, func(m Model) (Model, tea.Cmd) {
+		m.prompt = newPrompt(tr("Merge %d notes into:", len(paths)), m.relPath(paths[0]), func(m Model, value string) (Model, tea.Cmd) {
+			return m.merge(paths, value)
+		})
+		return m, textinput.Blink
+	})
+}
+
+// merge appends the notes to the target, a path relative to the notes
+// root, each under a heading with its path, and moves them to the trash.
+// The target may be one of them, it's kept; a new one is a markdown note
+// unless it has an extension.
+func (m Model) merge(paths []string, target string) (Model, tea.Cmd) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		m.status = tr("merge cancelled")
+		return m, nil
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(m.rootPath, target)
+	}
+	if filepath.Ext(target) == "" {
+		target += ".md"
+	}
+	target = filepath.Clean(target)
+
+	sources := lo.Without(paths, target)
+	names := lo.Map(sources, func(path string, _ int) string { return m.relPath(path) })
+	if err := notes.Merge(target, sources, names); err != nil {
+		m.status = tr("merge failed: %s", err)
+		return m, nil
+	}
+
+	for _, path := range sources {
+		if _, err := m.trash.Delete(path); err != nil {
+			m.status = tr("merged into %s, but moving %s to the trash failed: %s", m.relPath(target), filepath.Base(path), err)
+			return m, m.reindex()
+		}
+	}
+	m.status = tr("merged %d notes into %s, moved them to the trash (ctrl+z to undo one by one)", len(sources), m.relPath(target))
+	m.selected = map[string]bool{}
+	m.refreshSelection()
+	return m, tea.Batch(m.reindex(), m.openPreview(target))
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// catalogs holds the translations of the UI messages per language.
+// Messages are looked up by their English format string, which is used
+// as is when a translation is missing.
+var catalogs = map[string]map[string]string{
+	"de": {
+		"Search:":            "Suche:",
+		"query":              "Suchbegriff",
+		"Replace:":           "Ersetzen:",
+		"text to replace":    "zu ersetzender Text",
+		"With:":              "Durch:",
+		"replacement":        "Ersatz",
+		"Tags:":              "Tags:",
+		"+add -remove":       "+hinzufügen -entfernen",
+		"dry run:":           "Probelauf:",
+		" (new frontmatter)": " (neue Frontmatter)",
+
+		"delete failed: %s":                                    "Löschen fehlgeschlagen: %s",
+		"moved %s to the trash (ctrl+z to undo)":               "%s in den Papierkorb verschoben (ctrl+z macht es rückgängig)",
+		"undo failed: %s":                                      "Rückgängig fehlgeschlagen: %s",
+		"restored %s":                                          "%s wiederhergestellt",
+		"can't edit inline: %s":                                "kann nicht direkt bearbeitet werden: %s",
+		"can't open scratchpad: %s":                            "Notizblock kann nicht geöffnet werden: %s",
+		"open a preview to export it":                          "zum Exportieren erst die Vorschau öffnen",
+		"archive failed: %s":                                   "Archivieren fehlgeschlagen: %s",
+		"archived %s (search is:archived to find it)":          "%s archiviert (mit is:archived wiederfinden)",
+		"no notes to pick from":                                "keine Notizen zur Auswahl",
+		"random note: %s":                                      "zufällige Notiz: %s",
+		"save failed: %s":                                      "Speichern fehlgeschlagen: %s",
+		"saved %s":                                             "%s gespeichert",
+		"discarded changes to %s":                              "Änderungen an %s verworfen",
+		"export failed: %s":                                    "Export fehlgeschlagen: %s",
+		"exported to %s":                                       "exportiert nach %s",
+		"replace failed: %s":                                   "Ersetzen fehlgeschlagen: %s",
+		"no occurrences of %s in the results":                  "%s kommt in den Ergebnissen nicht vor",
+		"replaced %s in %d notes":                              "%s in %d Notizen ersetzt",
+		"y replace  n skip  a replace all remaining  esc stop": "y ersetzen  n überspringen  a alle restlichen ersetzen  esc abbrechen",
+		"tag edit failed: %s":                                  "Tags bearbeiten fehlgeschlagen: %s",
+		"tags unchanged":                                       "Tags unverändert",
+		"updated tags of %d notes":                             "Tags von %d Notizen aktualisiert",
+		"editing tags of %d notes":                             "bearbeite Tags von %d Notizen",
+		"y apply  esc cancel":                                  "y anwenden  esc abbrechen",
+		"Exclude:":                                             "Ausschließen:",
+		"excluding %s":                                         "schließe %s aus",
+		"Narrow:":                                              "Eingrenzen:",
+		"narrowed to %d of %d results (ctrl+f to change)":      "auf %d von %d Ergebnissen eingegrenzt (ctrl+f ändert)",
+		"Path:":                                  "Pfad:",
+		"path filter cleared":                    "Pfadfilter aufgehoben",
+		"notes similar to %s":                    "ähnliche Notizen wie %s",
+		"(1 match)":                              "(1 Treffer)",
+		"(%d matches)":                           "(%d Treffer)",
+		"sorted by match count (alt+c for path)": "nach Trefferzahl sortiert (alt+c für Pfad)",
+		"sorted by path (alt+c for relevance)":   "nach Pfad sortiert (alt+c für Relevanz)",
+		"sorted by relevance":                    "nach Relevanz sortiert",
+		"attachment, not referenced by any note": "Anhang, von keiner Notiz verlinkt",
+		"referenced by %s":                       "verlinkt von %s",
+		"no note references this attachment":     "keine Notiz verlinkt diesen Anhang",
+		"notes_search operators":                 "notes_search-Operatoren",
+		"backend query syntax":                   "Abfragesyntax des Backends",
+		"press any key to close":                 "beliebige Taste schließt",
+		"leave out notes containing term":        "Notizen mit dem Begriff auslassen",
+		"search the archived notes instead":      "stattdessen die archivierten Notizen durchsuchen",
+		"word count and file size ranges (< <= > >= =, b kb mb gb)": "Bereiche für Wortzahl und Dateigröße (< <= > >= =, b kb mb gb)",
+		"notes detected as written in the language":                 "Notizen, die in der Sprache erkannt wurden",
+		"words at most 5 words apart, in any order":                 "Wörter höchstens 5 Wörter voneinander entfernt, in beliebiger Reihenfolge",
+		"notes with either word, both rank higher":                  "Notizen mit einem der Wörter, mit beiden weiter oben",
+		"notes with both words":                                     "Notizen mit beiden Wörtern",
+		"notes without the word":                                    "Notizen ohne das Wort",
+		"the exact phrase":                                          "die genaue Wortfolge",
+		"wildcards, the last word is a prefix while typing":         "Platzhalter, das letzte Wort gilt beim Tippen als Präfix",
+		"fuzzy, up to 1 edit away":                                  "unscharf, bis zu 1 Änderung entfernt",
+		"regular expression":                                        "regulärer Ausdruck",
+		"boost a word":                                              "ein Wort höher gewichten",
+		"search a single field":                                     "ein einzelnes Feld durchsuchen",
+		"numeric and date ranges on a field":                        "Zahlen- und Datumsbereiche eines Felds",
+		"a frontmatter tag, matched whole":                          "ein Tag des Frontmatters, als Ganzes",
+		"can't create note: %s":                                     "Notiz kann nicht erstellt werden: %s",
+		"created %s":                                                "%s erstellt",
+		"Append to %s (empty pastes the clipboard):":                "An %s anhängen (leer fügt die Zwischenablage ein):",
+		"append failed: %s":                                         "Anhängen fehlgeschlagen: %s",
+		"appended to %s":                                            "an %s angehängt",
+		"Mo Tu We Th Fr Sa Su":                                      "Mo Di Mi Do Fr Sa So",
+		"enter open or create · arrows move · [ ] month · t today · esc close": "Enter öffnen oder anlegen · Pfeile bewegen · [ ] Monat · t heute · Esc schließen",
+		"January":      "Januar",
+		"February":     "Februar",
+		"March":        "März",
+		"April":        "April",
+		"May":          "Mai",
+		"June":         "Juni",
+		"July":         "Juli",
+		"August":       "August",
+		"September":    "September",
+		"October":      "Oktober",
+		"November":     "November",
+		"December":     "Dezember",
+		"Field:":       "Feld:",
+		"Value:":       "Wert:",
+		"Operator:":    "Operator:",
+		"is":           "ist",
+		"is not":       "ist nicht",
+		"is set":       "ist gesetzt",
+		"after":        "nach",
+		"before":       "vor",
+		"(incomplete)": "(unvollständig)",
+		"tab next · ←/→ operator · enter add to the query · esc cancel":      "Tab weiter · ←/→ Operator · Enter zur Suche hinzufügen · Esc abbrechen",
+		"index looks stale (%d notes on disk, %d indexed), reindex now? y/n": "Index scheint veraltet (%d Notizen auf der Platte, %d indiziert), jetzt neu indizieren? y/n",
+		"can't open the source: %s":           "Quelle kann nicht geöffnet werden: %s",
+		"opened %s":                           "%s geöffnet",
+		"%s indexing…":                        "%s wird indiziert…",
+		"%s failed: %s":                       "%s fehlgeschlagen: %s",
+		"%s %d notes":                         "%s %d Notizen",
+		"opening %s once the reindex is done": "%s wird nach dem Indizieren geöffnet",
+		"Error: %s":                           "Fehler: %s",
+		"Type:":                               "Typ:",
+		"Size:":                               "Größe:",
+		"Modified:":                           "Geändert:",
+		"Referenced by:":                      "Verlinkt von:",
+		"ctrl+o opens the first note linking to it": "ctrl+o öffnet die erste Notiz, die darauf verlinkt",
+		"Line:":                          "Zeile:",
+		"no line %d":                     "keine Zeile %d",
+		"not a line number: %s":          "keine Zeilennummer: %s",
+		"open a preview to go to a line": "zum Springen erst die Vorschau öffnen",
+		"no tasks in the preview":        "keine Aufgaben in der Vorschau",
+		"move to a task with alt+up and alt+down first":                 "erst mit alt+up und alt+down zu einer Aufgabe gehen",
+		"toggling the task failed: %s":                                  "Abhaken fehlgeschlagen: %s",
+		"checked a task in %s":                                          "Aufgabe in %s abgehakt",
+		"unchecked a task in %s":                                        "Haken an Aufgabe in %s entfernt",
+		"%s isn't a date like 2024-01-31":                               "%s ist kein Datum wie 2024-01-31",
+		"separate the %s with commas, without # or spaces in them":      "%s mit Kommas trennen, ohne # oder Leerzeichen darin",
+		"saving the metadata failed: %s":                                "Speichern der Metadaten fehlgeschlagen: %s",
+		"saved the metadata of %s":                                      "Metadaten von %s gespeichert",
+		"can't edit the metadata: %s":                                   "Metadaten nicht bearbeitbar: %s",
+		"tab next · enter save · esc cancel · empty fields are removed": "Tab weiter · Enter speichern · Esc abbrechen · leere Felder werden entfernt",
+		"can't read %s: %s":                                             "%s nicht lesbar: %s",
+		"no notes in %s":                                                "keine Notizen in %s",
+		"can't browse: %s":                                              "Durchsuchen nicht möglich: %s",
+		"browsing %s · type to search":                                  "Ordner %s · tippen zum Suchen",
+		"notes in %s (ctrl+p goes up)":                                  "Notizen in %s (ctrl+p geht hoch)",
+		"already at the notes root":                                     "schon im Notizordner ganz oben",
+		"also at %s":                                                    "auch unter %s",
+		"macros":                                                        "Makros",
+		"Capture:":                                                      "Erfassen:",
+		"loading…":                                                      "lade…",
+		"Pinned":                                                        "Angeheftet",
+		"Recent":                                                        "Zuletzt geändert",
+		"%d notes indexed":                                              "%d Notizen indiziert",
+		"%d open tasks":                                                 "%d offene Aufgaben",
+		"reindexed in %s":                                               "neu indiziert in %s",
+		"/ search · enter preview · d daily note · c capture · ctrl+r reindex": "/ suchen · enter Vorschau · d Tagesnotiz · c erfassen · ctrl+r neu indizieren",
+		"%s indexing %d/%d…":           "%s wird indiziert %d/%d…",
+		"Notes folder:":                "Notizordner:",
+		"Editor:":                      "Editor:",
+		"Extensions:":                  "Endungen:",
+		"the notes folder is required": "der Notizordner fehlt",
+		"%s is not a folder":           "%s ist kein Ordner",
+		"at least one extension is required, e.g. .md": "mindestens eine Endung ist nötig, z.B. .md",
+		"can't write the config: %s":                   "Konfiguration kann nicht geschrieben werden: %s",
+		"can't open the index: %s":                     "Index kann nicht geöffnet werden: %s",
+		"Welcome to notes_search":                      "Willkommen bei notes_search",
+		"No config yet, a few questions to write %s:":  "Noch keine Konfiguration, ein paar Fragen für %s:",
+		"press any key to start searching":             "beliebige Taste startet die Suche",
+		"indexing…":                                    "wird indiziert…",
+		"enter next · shift+tab back · esc quit":       "enter weiter · shift+tab zurück · esc beenden",
+		"notes root %s doesn't exist (unmounted drive?): r retry · c change root · esc ignore": "Notizordner %s existiert nicht (Laufwerk nicht eingehängt?): r erneut prüfen · c anderer Ordner · esc ignorieren",
+		"can't read the notes root %s: %s · r retry · c change root · esc ignore":              "Notizordner %s nicht lesbar: %s · r erneut prüfen · c anderer Ordner · esc ignorieren",
+		"notes root %s is back":    "Notizordner %s ist wieder da",
+		"Notes root:":              "Notizordner:",
+		"can't use %s: %s":         "%s kann nicht verwendet werden: %s",
+		"notes root changed to %s": "Notizordner ist jetzt %s",
+		"%s %d notes, %d files skipped due to permissions":               "%s %d Notizen, %d Dateien mangels Berechtigung übersprungen",
+		"%d files skipped due to permissions":                            "%d Dateien mangels Berechtigung übersprungen",
+		"notes whose extension is in the group, see types in the config": "Notizen, deren Endung in der Gruppe ist, siehe types in der Konfiguration",
+		"%s is private, show it? y show · any other key cancels":         "%s ist privat, anzeigen? y anzeigen · jede andere Taste bricht ab",
+		"private note, enter to show it":                                 "private Notiz, Enter zeigt sie an",
+		"no results to copy":                                             "keine Ergebnisse zum Kopieren",
+		"can't copy to the clipboard: %s":                                "Kopieren in die Zwischenablage fehlgeschlagen: %s",
+		"copied %d paths to the clipboard":                               "%d Pfade in die Zwischenablage kopiert",
+		"no send_to commands configured":                                 "keine send_to-Befehle konfiguriert",
+		"sent %s to %s":                                                  "%s an %s gesendet",
+		"empty command":                                                  "leerer Befehl",
+		"Send %s to":                                                     "%s senden an",
+		"running…":                                                       "läuft…",
+		"enter send · esc cancel":                                        "Enter senden · Esc abbrechen",
+		"notes with the extension":                                       "Notizen mit der Endung",
+		"notes tagged work in their frontmatter":                         "Notizen mit dem Tag work in ihrem Frontmatter",
+		"%s needs a value":                                               "%s braucht einen Wert",
+		"%s is not a filter, use ext:md, path:work/ or tag:todo":         "%s ist kein Filter, z. B. ext:md, path:work/ oder tag:todo",
+		"filters cleared":                                                "Filter entfernt",
+		"every query is filtered (ctrl+l to change)":                     "jede Suche wird gefiltert (ctrl+l ändert es)",
+		"Filters:": "Filter:",
+		"ext:md path:work/ tag:todo, empty clears":                  "ext:md path:work/ tag:todo, leer entfernt sie",
+		"only search the named vaults":                              "nur die genannten Sammlungen durchsuchen",
+		"invalid query: %s":                                         "ungültige Suche: %s",
+		"mark two notes with ctrl+s to compare them":                "zum Vergleichen zwei Notizen mit ctrl+s markieren",
+		"can't compare: %s":                                         "Vergleich nicht möglich: %s",
+		"the notes are identical":                                   "die Notizen sind identisch",
+		"tab side by side/diff · ↑↓ pgup pgdown scroll · esc close": "Tab nebeneinander/Diff · ↑↓ Bild↑ Bild↓ blättern · Esc schließen",
+		"%s %d notes, %d failed to index":                           "%s %d Notizen, %d nicht indiziert",
+		"%d notes scanned":                                          "%d Notizen durchsucht",
+		"%d deleted":                                                "%d gelöscht",
+		"%d errors":                                                 "%d Fehler",
+		"%d notes failed to index":                                  "%d Notizen nicht indiziert",
+		"mark the notes to merge with ctrl+s":                       "Zusammenzuführende Notizen mit ctrl+s markieren",
+		"Merge %d notes into:":                                      "%d Notizen zusammenführen in:",
+		"merge cancelled":                                           "Zusammenführen abgebrochen",
+		"merge failed: %s":                                          "Zusammenführen fehlgeschlagen: %s",
+		"merged into %s, but moving %s to the trash failed: %s":     "in %s zusammengeführt, aber %s konnte nicht in den Papierkorb: %s",
+		"merged %d notes into %s, moved them to the trash (ctrl+z to undo one by one)": "%d Notizen in %s zusammengeführt und in den Papierkorb verschoben (ctrl+z macht einzeln rückgängig)",
+		"notes unchanged for longer than stale_after days, oldest first":               "Notizen, die seit mehr als stale_after Tagen unverändert sind, älteste zuerst",
+		"Tags of all the notes":         "Tags aller Notizen",
+		"Tags of the notes matching %s": "Tags der Notizen zu %s",
+		"can't count the tags: %s":      "Tags können nicht gezählt werden: %s",
+		"counting…":                     "zähle…",
+		"no tagged notes":               "keine Notizen mit Tags",
+		"enter add tag: to the query · ↑↓ move · esc close": "enter tag: zur Suche hinzufügen · ↑↓ bewegen · Esc schließen",
+		"Orphan notes":         "Verwaiste Notizen",
+		"Broken links":         "Defekte Links",
+		"no orphan notes":      "keine verwaisten Notizen",
+		"no broken links":      "keine defekten Links",
+		"resolving the links…": "Links werden aufgelöst…",
+		"tab broken links · enter preview · ↑↓ move · esc close":                                "Tab defekte Links · Enter Vorschau · ↑↓ bewegen · Esc schließen",
+		"tab orphan notes · enter preview the note · c create the target · ↑↓ move · esc close": "Tab verwaiste Notizen · Enter Vorschau der Notiz · c Ziel anlegen · ↑↓ bewegen · Esc schließen",
+		"notes in the work folder, path:*draft* for a glob on the whole path":                   "Notizen im Ordner work, path:*draft* für ein Muster auf dem ganzen Pfad",
+		"Links around %s, %d hops": "Links um %s, %d Schritte",
+		"links to":                 "verlinkt auf",
+		"linked from":              "verlinkt von",
+		"←→ side · ↑↓ move · enter center on the note · backspace back · +/- hops · p preview · esc close": "←→ Seite · ↑↓ bewegen · Enter Notiz in die Mitte · Rücktaste zurück · +/- Schritte · p Vorschau · Esc schließen",
+		"none": "keine",
+		"notes modified since or before a date (2024-01-31, 2024-01, 2024, today, yesterday) or h d w m y ago": "Notizen, seit oder vor einem Datum geändert (2024-01-31, 2024-01, 2024, today, yesterday) oder vor h d w m y",
+		"fuzzy search, words up to fuzziness edits away match too (alt+~ toggles it)":                          "unscharfe Suche, Wörter bis zu fuzziness Änderungen entfernt passen auch (Alt+~ schaltet sie um)",
+		"fuzzy search, words a few typos away match too (alt+~ to turn it off)":                                "unscharfe Suche, Wörter mit ein paar Tippfehlern passen auch (Alt+~ schaltet sie aus)",
+		"fuzzy search off": "unscharfe Suche aus",
+		"Created:":         "Erstellt:",
+		"notes created or modified in, after (>) or before (<) a date, this- or last-week, -month, -year, or newer than 7d": "Notizen, in, nach (>) oder vor (<) einem Datum erstellt oder geändert, this- oder last-week, -month, -year, oder neuer als 7d",
+		"%s is private, use its content? y continue · any other key cancels":                                                "%s ist privat, Inhalt verwenden? y fortfahren · jede andere Taste bricht ab",
+		"editing %s (ctrl+s save, esc discard, at most %d lines)":                                                           "bearbeite %s (ctrl+s speichern, esc verwerfen, höchstens %d Zeilen)",
+		"scratchpad (alt+p or esc to save and close, at most %d lines)":                                                     "Notizblock (alt+p oder esc speichert und schließt, höchstens %d Zeilen)",
+		"limited to the folder %s (ctrl+t to clear)":                                                                        "beschränkt auf den Ordner %s (ctrl+t hebt es auf)",
+		"limited to paths matching %s (ctrl+t to clear)":                                                                    "beschränkt auf Pfade passend zu %s (ctrl+t hebt es auf)",
+		"running %s…":         "%s läuft…",
+		"timed out after %s":  "Zeitüberschreitung nach %s",
+		"macros expanded: %s": "Makros aufgelöst: %s",
+	},
+}
+
+// The catalog of the selected language, nil for English.
+var messages map[string]string
+
+// tr translates the message and formats it with args.
+func tr(format string, args ...any) string {
+	if translated, ok := messages[format]; ok {
+		format = translated
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// setupLocale selects the catalog from the configured locale, falling back
+// to the usual LC_ALL, LC_MESSAGES and LANG environment variables.
+func setupLocale(locale string) {
+	for _, value := range []string{locale, os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")} {
+		if value != "" {
+			locale = value
+			break
+		}
+	}
+
+	// "de_DE.UTF-8" -> "de"
+	language, _, _ := strings.Cut(strings.ToLower(locale), ".")
+	language, _, _ = strings.Cut(language, "_")
+	language, _, _ = strings.Cut(language, "-")
+	messages = catalogs[language]
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/frontmatter"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// metadataField is a frontmatter field of the metadata form.
+type metadataField struct {
+	key   string
+	list  bool // comma separated, written as a list
+	date  bool // must be a date, see frontmatter.DateLayouts
+	input textinput.Model
+}
+
+// metadataState is the form editing the frontmatter of a note.
+type metadataState struct {
+	path   string
+	fields []metadataField
+	focus  int
+	err    string // why the form can't be saved, "" if it can
+}
+
+// newMetadataState starts editing the frontmatter of the note at path:
+// its title, tags, status and dates.
+func newMetadataState(path string) (*metadataState, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := string(body)
+	values := frontmatter.Fields(content)
+	values["tags"] = strings.Join(frontmatter.Tags(content), ", ")
+
+	fields := []metadataField{{key: "title"}, {key: "tags", list: true}, {key: "status"}, {key: "created", date: true}, {key: "updated", date: true}}
+	// Other dates of the note come after.
+	others := []string{}
+	for key, value := range values {
+		if _, ok := frontmatter.ParseDate(value); ok && !lo.ContainsBy(fields, func(f metadataField) bool { return f.key == key }) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	for _, key := range others {
+		fields = append(fields, metadataField{key: key, date: true})
+	}
+
+	width := lo.Max(lo.Map(fields, func(f metadataField, _ int) int { return len(f.key) }))
+	for i := range fields {
+		input := textinput.New()
+		input.Prompt = fields[i].key + ":" + strings.Repeat(" ", width-len(fields[i].key)+1)
+		input.SetValue(values[fields[i].key])
+		switch {
+		case fields[i].list:
+			input.Placeholder = "work, project/x"
+		case fields[i].date:
+			input.Placeholder = "2024-01-31"
+		}
+		fields[i].input = input
+	}
+	fields[0].input.Focus()
+	return &metadataState{path: path, fields: fields}, nil
+}
+
+// validate checks the values of the form, returning why they can't be
+// saved, "" when they can.
+func (s *metadataState) validate() string {
+	for _, f := range s.fields {
+		value := strings.TrimSpace(f.input.Value())
+		switch {
+		case value == "":
+		case f.date:
+			if _, ok := frontmatter.ParseDate(value); !ok {
+				return tr("%s isn't a date like 2024-01-31", f.key)
+			}
+		case f.list:
+			for _, item := range strings.Split(value, ",") {
+				if strings.ContainsAny(strings.TrimSpace(item), " \t#") {
+					return tr("separate the %s with commas, without # or spaces in them", f.key)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// updateMetadata handles key presses while the metadata form is open.
+// Keys: tab/shift+tab or up/down - next/previous field, enter - save,
+// esc - cancel.
+func (m Model) updateMetadata(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.metadata
+
+	switch key.String() {
+	case "esc", "ctrl+c":
+		m.metadata = nil
+		return m, nil
+	case "enter":
+		if s.err = s.validate(); s.err != "" {
+			return m, nil
+		}
+		fields := lo.Map(s.fields, func(f metadataField, _ int) frontmatter.Field {
+			return frontmatter.Field{Key: f.key, Value: f.input.Value(), List: f.list}
+		})
+		m.metadata = nil
+		if err := notes.SetFields(s.path, fields); err != nil {
+			m.status = tr("saving the metadata failed: %s", err)
+			return m, nil
+		}
+		m.status = tr("saved the metadata of %s", filepath.Base(s.path))
+		cmds := []tea.Cmd{m.indexChanged(s.path)}
+		if m.preview != nil && m.previewPath == s.path {
+			cmds = append(cmds, m.openPreview(s.path))
+		}
+		return m, tea.Batch(cmds...)
+	case "tab", "down", "shift+tab", "up":
+		s.fields[s.focus].input.Blur()
+		if key.String() == "tab" || key.String() == "down" {
+			s.focus = (s.focus + 1) % len(s.fields)
+		} else {
+			s.focus = (s.focus + len(s.fields) - 1) % len(s.fields)
+		}
+		return m, s.fields[s.focus].input.Focus()
+	}
+
+	var cmd tea.Cmd
+	s.fields[s.focus].input, cmd = s.fields[s.focus].input.Update(key)
+	s.err = ""
+	return m, cmd
+}
+
+// viewMetadata renders the metadata form.
+func (m Model) viewMetadata() string {
+	s := m.metadata
+	lines := []string{theme.matchStyle(0).Render(filepath.Base(s.path)), ""}
+	for _, f := range s.fields {
+		lines = append(lines, f.input.View())
+	}
+	lines = append(lines, "")
+	if s.err != "" {
+		lines = append(lines, theme.Error.Render(s.err), "")
+	}
+	lines = append(lines, theme.Status.Copy().UnsetPaddingLeft().Render(
+		tr("tab next · enter save · esc cancel · empty fields are removed")))
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
@@ -1,50 +1,188 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/acarl005/stripansi"
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/knipferrc/teacup/code"
 	"github.com/noelzubin/notes_search/editor"
+	"github.com/noelzubin/notes_search/export"
+	"github.com/noelzubin/notes_search/frontmatter"
+	"github.com/noelzubin/notes_search/notes"
 	"github.com/noelzubin/notes_search/search"
 	"github.com/noelzubin/notes_search/search/bleve_indexer"
+	"github.com/noelzubin/notes_search/search/grep_indexer"
+	"github.com/noelzubin/notes_search/search/remote"
+	"github.com/noelzubin/notes_search/stats"
+	"github.com/noelzubin/notes_search/trash"
 	"github.com/noelzubin/notes_search/utils"
+	"github.com/noelzubin/notes_search/watcher"
 	"github.com/samber/lo"
 )
 
-var ListStyle = lipgloss.NewStyle().MarginTop(1)
+var ListStyle = lipgloss.NewStyle()
 
 // Main app model for bubbletea
 type Model struct {
-	width        int                 // height of terminal
-	height       int                 // width of terminal
-	preview      *code.Bubble        // the preview widget model
-	list         list.Model          // the list widget model
-	textInput    textinput.Model     // the input search widget model
-	indexer      search.NotesIndexer // the indexer for searching and indexing notes.
-	editor       editor.Editor       // for opening up external editor.
-	isQueryValid bool                // if the query is valid
-	queryId      int                 // Unique id for the query.
+	width        int                  // height of terminal
+	height       int                  // width of terminal
+	preview      *code.Bubble         // the preview widget model
+	list         list.Model           // the list widget model
+	textInput    textinput.Model      // the input search widget model
+	indexer      search.NotesIndexer  // the indexer for searching and indexing notes.
+	editor       editor.Editor        // for opening up external editor.
+	isQueryValid bool                 // if the last query was searched without error
+	queryId      int                  // Unique id for the query.
+	status       string               // message shown above the list
+	trash        *trash.Trash         // where deleted notes go
+	rootPath     string               // root path of the notes
+	archiveDir   string               // where archived notes are moved to
+	replace      *replaceState        // search and replace mode, nil when inactive
+	tagEdit      *tagEditState        // bulk tag editing mode, nil when inactive
+	inline       *editor.InlineEditor // built-in editor, nil when inactive
+	scratchpad   string               // path of the scratchpad note
+	inboxDir     string               // where notes created from the clipboard go
+	previewPath  string               // path of the previewed note
+	previewText  string               // content of the previewed note, "" for a card
+	previewRaw   string               // the previewed note as written, shown with line numbers
+	previewKey   previewKey           // version of the previewed note, zero unless read from disk
+	previews     *previewCache        // rendered previews of the recent notes
+	lineNumbers  bool                 // number the lines of the preview
+	noWrap       bool                 // scroll long preview lines instead of wrapping them
+	previewX     int                  // first column shown when not wrapping
+	taskLine     int                  // line of the task item under the preview's cursor, -1 for none
+	exportDir    string               // where exports are written, next to the note if empty
+	pdfConverter string               // command converting exported HTML to PDF
+	diagrams     map[string]string    // commands rendering diagram fences as text, by language
+	graphHops    int                  // links followed from the center of the graph view
+	selected     map[string]bool      // paths of the notes marked for bulk actions
+	prompt       *promptState         // single line prompt in the status line, nil when inactive
+	results      []list.Item          // results of the query, before narrowing
+	narrow       string               // client side fuzzy filter over the results
+	filters      []string             // sticky filters added to every query, shown as chips
+	order        resultOrder          // how the results are sorted
+	cheatSheet   []search.SyntaxEntry // query syntax shown over the results, nil when hidden
+	calendar     *calendarState       // month grid of the daily notes, nil when hidden
+	builder      *builderState        // field query form, nil when inactive
+	metadata     *metadataState       // frontmatter form of a note, nil when inactive
+	browse       *browseState         // tree of the notes root, nil unless browsing
+	listedDir    string               // folder listed with ctrl+n and ctrl+p, "" for search results
+	extensions   []string             // extensions of the notes
+	macros       map[string]string    // queries typed as @name, see utils.ExpandMacros
+	startView    string               // what's listed while the query is empty, see emptyView
+	startQuery   string               // saved search of the start view
+	pinned       []string             // paths of the pinned notes
+	dashboard    *dashboardState      // start screen, nil when hidden
+	latency      time.Duration        // time the last search took, shown in the status line
+	latencies    *stats.Latencies     // where the search latencies are recorded, nil if not
+
+	sendToCommands map[string]string // commands the selected note can be sent to, by name
+	actions        map[string]string // commands run from the send to menu by their key
+	actionKeys     map[string]string // the actions also run by their key from the results, see boundActions
+	sendTo         *sendToState      // the send to menu, nil when closed
+	compare        *compareState     // two notes compared, nil unless comparing
+	tagFacets      *tagFacetState    // tag counts of the results, nil when hidden
+	linkReport     *linkReportState  // orphan notes and broken links, nil when hidden
+	graphView      *graphState       // links around a note, nil when hidden
+
+	reindexInterval time.Duration        // time between scheduled reindexes, 0 if disabled.
+	watcher         *watcher.Watcher     // reports the changes of the notes, nil unless watching
+	notesOnDisk     func() int           // counts the notes to index for the startup check, nil to skip it
+	openTasks       func() int           // counts the open tasks for the dashboard, nil to skip it
+	vaults          []*utils.Config      // the vaults whose links the link report resolves
+	staleIndex      bool                 // asking whether to reindex the stale index
+	missingRoot     bool                 // asking what to do about the missing notes root
+	privateNote     *privateConfirm      // private note waiting for confirmation to be used
+	indexProgress   bool                 // the status line shows the progress of the reindex
+	indexing        []search.IndexStatus // progress of the running reindex, nil until first polled
+	spinner         spinner.Model        // spins on the right of the status line while reindexing
+
+	ctx          context.Context    // cancelled on quit, stopping a running reindex
+	quitCancel   context.CancelFunc // cancels ctx
+	cancelSearch context.CancelFunc // cancels the search of the current query, nil if none
+	searchCtx    context.Context    // context of the search of the current query and its pages
+
+	indexState    indexState // whether a reindex or the editor is running, see edit
+	reindexQueued bool       // reindex once the editor or the running reindex is done
+	pendingEdit   []string   // notes to open in the editor once the reindex is done
+	editedPaths   []string   // notes open in the editor
+
+	dailyNote func(day time.Time) string // path of the daily note of day
 }
 
 // Create a new model for the app
 func New(indexer search.NotesIndexer, config *utils.Config) *Model {
-	return &Model{
+	m := &Model{
 		list:         create_list_model(),
 		textInput:    create_text_input(),
 		indexer:      indexer,
 		editor:       editor.Editor{Editing: false, EditorCmd: config.Editor},
-		isQueryValid: false,
+		isQueryValid: true,
 		queryId:      0,
+		trash:        trash.New(),
+		latencies:    lo.Ternary(config.SearchLatencies && !config.EncryptIndex, stats.NewLatencies(), nil),
+		previews:     newPreviewCache(previewCacheSize),
+		selected:     map[string]bool{},
+		rootPath:     config.RootPath,
+		extensions:   config.NoteExtensions(),
+		macros:       config.Macros,
+		startView:    config.StartView,
+		startQuery:   config.StartQuery,
+		pinned:       config.PinnedPaths(),
+		archiveDir:   config.ArchiveDir(),
+		scratchpad:   config.ScratchpadPath(),
+		inboxDir:     config.InboxDir(),
+		dailyNote:    config.DailyNotePath,
+		exportDir:    config.ExportDir,
+		pdfConverter: config.PDFConverter,
+		diagrams:     config.Diagrams,
+		graphHops:    config.GraphHops,
+
+		sendToCommands: config.SendTo,
+		actions:        config.Actions,
+		actionKeys:     boundActions(config.Actions),
+
+		reindexInterval: config.ReindexEvery(),
+		watcher:         startWatching(config),
+		spinner:         spinner.New(spinner.WithSpinner(spinner.MiniDot)),
+		vaults:          config.VaultConfigs(),
 	}
+	// Counting the notes walks every root, which low I/O roots are spared.
+	if !lo.SomeBy(config.VaultConfigs(), func(c *utils.Config) bool { return c.LowIO() }) {
+		m.notesOnDisk = func() int { return countNotes(config) }
+		m.openTasks = func() int { return countOpenTasks(config) }
+	}
+	if config.StartView == "dashboard" {
+		m.dashboard = &dashboardState{}
+	}
+	m.ctx, m.quitCancel = context.WithCancel(context.Background())
+	m.searchCtx = m.ctx
+	return m
+}
+
+// quit stops the running reindex and searches, and quits.
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.quitCancel()
+	return m, tea.Quit
 }
 
 func (m *Model) setListSize() {
@@ -55,13 +193,24 @@ func (m *Model) setListSize() {
 	if m.preview != nil {
 		width = m.width / 2
 	}
+	// The chips of the sticky filters go above the list.
+	if len(m.filters) > 0 {
+		height--
+	}
 
 	m.list.SetSize(width, height-2)
 }
 
+func (m *Model) setInlineSize() {
+	if m.inline != nil {
+		m.inline.SetSize(m.width-2, m.height-3)
+	}
+}
+
 func (m *Model) setPreviewSize() {
 	if m.preview != nil {
-		m.preview.SetSize(m.width/2, m.height)
+		// The breadcrumbs go above it.
+		m.preview.SetSize(m.width/2, m.height-1)
 	}
 }
 
@@ -74,13 +223,272 @@ func (m *Model) updateSize(width, height int) {
 
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(tea.EnterAltScreen,
-		func() tea.Msg {
-			results := m.indexer.Search("")
-			return ResultMsg{results: results, queryId: 0}
-		},
+		m.emptyView(0),
+		m.dashboardCmd(),
+		m.scheduleReindex(),
+		m.waitForChanges(),
+		m.checkRoot(),
+		m.checkIndex(),
 	)
 }
 
+// checkIndex compares the number of notes on disk with the number of
+// indexed notes, and reports with staleIndexMsg when they differ.
+func (m *Model) checkIndex() tea.Cmd {
+	indexer, notesOnDisk, root := m.indexer, m.notesOnDisk, m.rootPath
+	if notesOnDisk == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		// A missing root is reported by checkRoot, not as a stale index.
+		if bleve_indexer.Reachable(root) != nil {
+			return nil
+		}
+		indexed, err := indexer.DocCount()
+		if err != nil {
+			return nil
+		}
+		if onDisk := notesOnDisk(); uint64(onDisk) != indexed {
+			return staleIndexMsg{onDisk: onDisk, indexed: int(indexed)}
+		}
+		return nil
+	}
+}
+
+// countNotes counts the notes of every vault.
+func countNotes(config *utils.Config) int {
+	count := 0
+	for _, vault := range config.VaultConfigs() {
+		count += len(notes.Paths(vault))
+	}
+	return count
+}
+
+// indexState coordinates the reindexes with the editor, which needs the
+// index closed: neither starts while the other runs.
+//
+//	idle --reindex--> indexing --IndexedMsg--> idle, or editing if an edit is pending
+//	idle --edit--> editing --EditingFinished--> idle, or indexing if a reindex was queued
+//
+// A reindex asked for meanwhile is queued, an edit waits for the reindex.
+type indexState int
+
+const (
+	indexIdle indexState = iota
+	indexIndexing
+	indexEditing
+)
+
+// reindex indexes the notes in the background and reports back with
+// IndexedMsg. While the editor or another reindex runs it's queued.
+func (m *Model) reindex() tea.Cmd {
+	if m.indexState != indexIdle {
+		m.reindexQueued = true
+		return nil
+	}
+	m.indexState = indexIndexing
+	m.reindexQueued = false
+	m.indexing = nil
+	indexer, ctx := m.indexer, m.ctx
+	return tea.Batch(func() tea.Msg {
+		indexer.IndexNotes(ctx)
+		return IndexedMsg{statuses: indexer.IndexStatus()}
+	}, m.pollIndex(), m.spinner.Tick)
+}
+
+// indexEdited indexes the notes just edited, so the edits show up in the
+// results right away, then every note if a reindex was queued meanwhile.
+func (m *Model) indexEdited(paths []string) tea.Cmd {
+	all := m.reindexQueued
+	m.indexState = indexIndexing
+	m.reindexQueued = false
+	m.indexing = nil
+	indexer, ctx := m.indexer, m.ctx
+	return tea.Batch(func() tea.Msg {
+		for _, path := range paths {
+			if err := indexer.IndexFile(path); err != nil {
+				slog.Error("indexing the edited note failed", "path", path, "err", err)
+			}
+		}
+		if all {
+			indexer.IndexNotes(ctx)
+		}
+		return IndexedMsg{statuses: indexer.IndexStatus()}
+	}, m.pollIndex(), m.spinner.Tick)
+}
+
+// indexChanged indexes the note just changed in place, or reindexes once
+// the running reindex is done.
+func (m *Model) indexChanged(path string) tea.Cmd {
+	if m.indexState != indexIdle {
+		return m.reindex()
+	}
+	return m.indexEdited([]string{path})
+}
+
+// edit opens the notes in a single editor session, closing the index
+// meanwhile. While a reindex runs the editor opens once it's done.
+func (m *Model) edit(paths ...string) tea.Cmd {
+	if m.indexState == indexIndexing {
+		m.pendingEdit = paths
+		m.status = tr("opening %s once the reindex is done", filepath.Base(paths[0]))
+		return nil
+	}
+	m.indexState = indexEditing
+	m.pendingEdit = nil
+	m.editedPaths = paths
+	m.indexer.CloseIndex()
+	return m.editor.EditFile(paths...)
+}
+
+// indexingDone moves on from a finished reindex or edit: to the pending
+// edit, then to the queued reindex.
+func (m *Model) indexingDone() tea.Cmd {
+	m.indexState = indexIdle
+	switch {
+	case len(m.pendingEdit) > 0:
+		m.status = ""
+		return m.edit(m.pendingEdit...)
+	case m.reindexQueued:
+		return m.reindex()
+	}
+	return nil
+}
+
+// pollIndex reports the state of the running reindex with indexProgressMsg.
+func (m *Model) pollIndex() tea.Cmd {
+	indexer := m.indexer
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		return indexProgressMsg{statuses: indexer.IndexStatus()}
+	})
+}
+
+// indexSummary describes the reindex of each root, e.g.
+// "notes 120 notes · work indexing… · nas failed: ...".
+func indexSummary(statuses []search.IndexStatus) string {
+	parts := lo.Map(statuses, func(status search.IndexStatus, _ int) string {
+		name := status.Vault
+		if name == "" {
+			name = filepath.Base(status.Root)
+		}
+		switch {
+		case status.Indexing && status.ToIndex > 0:
+			return tr("%s indexing %d/%d…", name, status.Indexed, status.ToIndex)
+		case status.Indexing:
+			return tr("%s indexing…", name)
+		case status.Err != "":
+			return tr("%s failed: %s", name, status.Err)
+		case status.Errors > 0:
+			return tr("%s %d notes, %d failed to index", name, status.Notes, status.Errors)
+		case status.Skipped > 0:
+			return tr("%s %d notes, %d files skipped due to permissions", name, status.Notes, status.Skipped)
+		}
+		return tr("%s %d notes", name, status.Notes)
+	})
+	return strings.Join(parts, " · ")
+}
+
+// Columns of the progress bar of the reindex.
+const progressWidth = 20
+
+// viewIndexProgress renders the progress of the running reindex over
+// every root, e.g. "⠋ ████░░░░ 120/300 · 2 deleted · 1 error". Until the
+// roots are walked there's nothing to count yet.
+func (m Model) viewIndexProgress() string {
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	spin := m.spinner
+	spin.Style = faint
+	scanned := lo.SumBy(m.indexing, func(s search.IndexStatus) int { return s.Scanned })
+	if scanned == 0 {
+		return spin.View() + " " + faint.Render(tr("indexing…"))
+	}
+
+	indexed := lo.SumBy(m.indexing, func(s search.IndexStatus) int { return s.Indexed })
+	toIndex := lo.SumBy(m.indexing, func(s search.IndexStatus) int { return s.ToIndex })
+	parts := []string{tr("%d notes scanned", scanned)}
+	if toIndex > 0 {
+		parts = []string{fmt.Sprintf("%d/%d", indexed, toIndex)}
+	}
+	if deleted := lo.SumBy(m.indexing, func(s search.IndexStatus) int { return s.Deleted }); deleted > 0 {
+		parts = append(parts, tr("%d deleted", deleted))
+	}
+	if failed := lo.SumBy(m.indexing, func(s search.IndexStatus) int { return s.Errors }); failed > 0 {
+		parts = append(parts, theme.Error.Render(tr("%d errors", failed)))
+	}
+
+	line := spin.View() + " "
+	// The bar has colours of its own, the counts say it all without them.
+	if toIndex > 0 && !theme.NoColor {
+		bar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(progressWidth), progress.WithoutPercentage())
+		line += bar.ViewAs(float64(indexed)/float64(toIndex)) + " "
+	}
+	return line + faint.Render(strings.Join(parts, " · "))
+}
+
+// scheduleReindex fires a reindexTickMsg after the configured interval.
+func (m *Model) scheduleReindex() tea.Cmd {
+	if m.reindexInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.reindexInterval, func(time.Time) tea.Msg {
+		return reindexTickMsg{}
+	})
+}
+
+// search runs the query as a new request, superseding the older ones,
+// which are cancelled. Results are fetched in pages so the first ones show
+// up right away: a small first page, then bigger ones up to maxResults.
+const (
+	firstPageSize = 20
+	pageSize      = 100
+	maxResults    = 500
+)
+
+func (m *Model) search(query string) tea.Cmd {
+	m.queryId++
+	m.listedDir = ""
+	if m.cancelSearch != nil {
+		m.cancelSearch()
+	}
+	m.searchCtx, m.cancelSearch = context.WithCancel(m.ctx)
+	if strings.TrimSpace(query) == "" && m.startView != "recent" && len(m.filters) == 0 {
+		return m.emptyView(m.queryId)
+	}
+	if expanded := utils.ExpandMacros(query, m.macros); expanded != query {
+		m.status = tr("macros expanded: %s", expanded)
+		query = expanded
+	}
+	return m.fetchPage(m.withFilters(query), m.queryId, 0, firstPageSize)
+}
+
+// fetchPage fetches size results of the query starting at from. The
+// latency of the first page is recorded for the stats command.
+func (m *Model) fetchPage(query string, queryId, from, size int) tea.Cmd {
+	indexer, latencies, ctx := m.indexer, m.latencies, m.searchCtx
+	return func() tea.Msg {
+		start := time.Now()
+		results := indexer.SearchPage(ctx, query, from, size)
+		took := time.Since(start)
+		if from == 0 && results.Err == nil && latencies != nil {
+			if err := latencies.Record(took); err != nil {
+				slog.Warn("recording the latency failed", "err", err)
+			}
+		}
+		return ResultMsg{results: results, queryId: queryId, query: query, from: from, size: size, took: took}
+	}
+}
+
+// similar lists the notes related to the note at path in place of the
+// results, until the query changes.
+func (m *Model) similar(path string) tea.Cmd {
+	m.queryId++
+	queryId := m.queryId
+	return func() tea.Msg {
+		results := m.indexer.Similar(path)
+		return ResultMsg{results: results, queryId: queryId}
+	}
+}
+
 // Formats the content of the file
 // removes newslines and replaces tabs with single space.
 func formatContent(content string) string {
@@ -95,6 +503,152 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
+	if m.cheatSheet != nil {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			m.cheatSheet = nil
+			return m, nil
+		}
+	}
+
+	// One key answers whether to reindex the stale index.
+	if m.staleIndex {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			m.staleIndex = false
+			m.status = ""
+			if key.String() == "y" {
+				return m, m.reindex()
+			}
+			return m, nil
+		}
+	}
+
+	if m.missingRoot {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateMissingRoot(key)
+		}
+	}
+
+	// One key confirms showing a private note.
+	if m.privateNote != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			confirm := m.privateNote
+			m.privateNote = nil
+			m.status = ""
+			if key.String() == "y" {
+				return confirm.then(m)
+			}
+			return m, nil
+		}
+	}
+
+	if m.calendar != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateCalendar(key)
+		}
+	}
+
+	if m.builder != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateBuilder(key)
+		}
+		m.builder.field, cmd = m.builder.field.Update(msg)
+		cmds = append(cmds, cmd)
+		m.builder.value, cmd = m.builder.value.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	if m.browse != nil {
+		if key, ok := msg.(tea.KeyMsg); ok && !lo.Contains(browsePreviewKeys, key.String()) {
+			if key.Type != tea.KeyRunes || key.Alt {
+				return m.updateBrowse(key)
+			}
+			// Typing leaves the tree for the results.
+			m.browse = nil
+			m.status = ""
+		}
+	}
+
+	if m.metadata != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateMetadata(key)
+		}
+		for i := range m.metadata.fields {
+			m.metadata.fields[i].input, cmd = m.metadata.fields[i].input.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	if m.replace != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateReplace(key)
+		}
+		m.replace.input, cmd = m.replace.input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	if m.prompt != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updatePrompt(key)
+		}
+		m.prompt.input, cmd = m.prompt.input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	if m.dashboard != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			if key.Type != tea.KeyRunes || key.Alt || lo.Contains([]string{"d", "c", "/"}, key.String()) {
+				return m.updateDashboard(key)
+			}
+			// Typing leaves the dashboard for the results.
+			m.dashboard = nil
+		}
+	}
+
+	if m.inline != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateInline(key)
+		}
+		cmds = append(cmds, m.inline.Update(msg))
+	}
+
+	if m.sendTo != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateSendTo(key)
+		}
+	}
+
+	if m.compare != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateCompare(key)
+		}
+	}
+
+	if m.tagFacets != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTagFacets(key)
+		}
+	}
+
+	if m.linkReport != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateLinkReport(key)
+		}
+	}
+
+	if m.graphView != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateGraph(key)
+		}
+	}
+
+	if m.tagEdit != nil {
+		if key, ok := msg.(tea.KeyMsg); ok {
+			return m.updateTagEdit(key)
+		}
+		m.tagEdit.input, cmd = m.tagEdit.input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
 	switch msg := msg.(type) {
 	case ResultMsg:
 		// Ignore this slow result
@@ -102,16 +656,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		text_style := lipgloss.Color("255")
+		text_style := theme.Text
 		if msg.results.Err != nil {
-			text_style = lipgloss.Color("9")
+			text_style = theme.Error
 		}
 
-		m.textInput.TextStyle = lipgloss.NewStyle().Foreground(text_style)
-		m.list.SetItems(lo.Map(msg.results.Hits, func(hit search.DocumentMatch, _ int) list.Item {
+		m.textInput.TextStyle = text_style
+		// A malformed query keeps the results of the last valid one
+		// listed, and says what's wrong with it.
+		if msg.results.Err != nil {
+			m.isQueryValid = false
+			m.status = tr("invalid query: %s", msg.results.Err)
+			return m, nil
+		}
+		if !m.isQueryValid {
+			m.isQueryValid = true
+			m.status = ""
+		}
+		terms := queryTerms(m.textInput.Value())
+		page := lo.Map(msg.results.Hits, func(hit search.DocumentMatch, _ int) list.Item {
 			content := formatContent(hit.Content)
-			return Note{hit.Path, content}
-		}))
+			return Note{path: hit.Path, content: content, selected: m.selected[hit.Path], terms: terms, matches: hit.Matches, title: hit.Title, vault: hit.Vault, hash: hit.Hash, pinned: lo.Contains(m.pinned, hit.Path), private: hit.Private, referencedBy: hit.ReferencedBy}
+		})
+		if msg.from == 0 {
+			m.results = foldCopies(nil, page)
+			m.latency = msg.took
+		} else {
+			m.results = foldCopies(m.results, page)
+		}
+		m.showResults()
+
+		// A full page means there may be more, fetch them in the background.
+		if next := msg.from + len(page); msg.size > 0 && len(page) == msg.size && next < maxResults {
+			cmds = append(cmds, m.fetchPage(msg.query, msg.queryId, next, lo.Min([]int{pageSize, maxResults - next})))
+		}
 	case tea.KeyMsg:
 		// Keybindings:
 		// Tab - move down in the list
@@ -121,8 +699,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Ctrl+R - refresh the index
 		// Ctrl+K - Preview lineup
 		// Ctrl+J - Preview line down
-		// Ctrl+O - Open the file in the editor
+		// Ctrl+O - Open the file, or all the marked ones, in the editor
+		// Ctrl+X - move the selected note to the trash
+		// Ctrl+Z - restore the last deleted note
+		// Alt+A - move the selected note to the archive
+		// Alt+R - search and replace across the results
+		// Ctrl+S - mark the selected note for bulk actions
+		// Ctrl+Y - copy the paths of the listed results to the clipboard
+		// Alt+T - add/remove tags of the marked notes
+		// Alt+S - surprise me, preview a random note
+		// Alt+E - edit the selected note inside the TUI
+		// Alt+P - toggle the scratchpad
+		// Alt+X - export the previewed note to HTML (and PDF if configured)
+		// Alt+N - exclude a term of the selected result from the query
+		// Ctrl+F - narrow the results without searching again
+		// Ctrl+T - limit the query to a path, press again to clear it
+		// Ctrl+L - set the sticky filters applied to every query
+		// Alt+M - list notes similar to the selected one
+		// Alt+C - sort the results by match count, then by path, then relevance
+		// Ctrl+G - cheat sheet of the query syntax
+		// Alt+L - toggle line numbers in the preview
+		// Alt+W - toggle wrapping long preview lines
+		// Alt+K - switch the colours between a light and dark background
+		// Alt+Left/Alt+Right - scroll the preview sideways when not wrapping
+		// Alt+G - go to a line of the previewed note
+		// Alt+Up/Alt+Down - move the cursor over the task items of the preview
+		// Alt+Enter - check or uncheck the task item under the cursor
+		// Alt+V - create a note in the inbox from the clipboard
+		// Alt+I - append a line, or the clipboard, to the selected note
+		// Alt+J - calendar of the daily notes
+		// Alt+Q - build a frontmatter field query with a form
+		// Alt+O - edit the title, tags, status and dates of the selected note
+		// Ctrl+N - list the notes in the folder of the previewed note
+		// Ctrl+P - list the notes in the folder above
+		// Alt+H - browse the folders of the notes root as a tree
+		// Alt+Y - show the dashboard
+		// Alt+U - open the source_url of a web clipping in the browser
+		// Alt+Z - send the selected note to a configured command or run an action on it
+		// the ctrl+ and alt+ keys of actions that aren't taken - run the action on the selected note
+		// Alt+D - compare the two marked notes, or the marked and the selected one
+		// Alt+B - merge the marked notes into one and move them to the trash
+		// Alt+# - count the tags of the results, enter narrows to one
+		// Alt+] - list the orphan notes and the broken links
+		// Alt+@ - graph of the notes linked to and from the selected one
+		// Alt+~ - toggle fuzzy search, matching words a few typos away
+		// Alt+1 ... Alt+9 - preview the labeled result, open it if it's previewed
 		// Ctrl+C - quit the application
+		if i := quickOpenIndex(msg); i >= 0 {
+			return m, m.quickOpen(i)
+		}
+		if command, ok := m.actionKeys[msg.String()]; ok && m.list.SelectedItem() != nil {
+			return m.runActionKey(msg.String(), command)
+		}
 		switch msg.String() {
 		case "tab":
 			m.list.CursorDown()
@@ -130,44 +758,436 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.list.CursorUp()
 		case "enter":
 			if m.list.SelectedItem() != nil {
-				path := m.list.SelectedItem().(Note).path
-				codeModel := code.New(false, true, lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"})
-				codeModel.SetSize(m.width/1, m.height)
-				cmds = append(cmds, codeModel.SetFileName(path))
-				m.preview = &codeModel
+				cmds = append(cmds, m.openPreview(m.list.SelectedItem().(Note).path))
 			}
 		case "esc":
 			m.preview = nil
 		case "ctrl+c":
-			return m, tea.Quit
+			return m.quit()
 		case "ctrl+r":
-			return m, func() tea.Msg {
-				m.indexer.IndexNotes()
-				return nil
-			}
+			return m, m.reindex()
 		case "ctrl+k":
 			m.preview.Viewport.LineUp(5)
 		case "ctrl+j":
 			m.preview.Viewport.LineDown(5)
 		case "ctrl+o":
+			// The marked notes are opened together.
+			if len(m.selected) > 0 {
+				cmds = append(cmds, m.edit(m.targetPaths()...))
+			} else if m.list.SelectedItem() != nil && m.list.SelectedItem().(Note).target() != "" {
+				cmds = append(cmds, m.edit(m.list.SelectedItem().(Note).target()))
+			}
+		case "ctrl+x":
+			if m.list.SelectedItem() != nil {
+				path := m.list.SelectedItem().(Note).path
+				if _, err := m.trash.Delete(path); err != nil {
+					m.status = tr("delete failed: %s", err)
+				} else {
+					m.status = tr("moved %s to the trash (ctrl+z to undo)", filepath.Base(path))
+					m.preview = nil
+					cmds = append(cmds, m.reindex())
+				}
+			}
+		case "ctrl+z":
+			if entry, err := m.trash.Restore(); err != nil {
+				m.status = tr("undo failed: %s", err)
+			} else {
+				m.status = tr("restored %s", filepath.Base(entry.Path))
+				cmds = append(cmds, m.reindex())
+			}
+		case "ctrl+s":
+			if m.list.SelectedItem() != nil {
+				path := m.list.SelectedItem().(Note).path
+				if m.selected[path] {
+					delete(m.selected, path)
+				} else {
+					m.selected[path] = true
+				}
+				m.refreshSelection()
+				m.list.CursorDown()
+			}
+		case "ctrl+y":
+			paths := lo.Map(m.list.Items(), func(item list.Item, _ int) string { return item.(Note).path })
+			if len(paths) == 0 {
+				m.status = tr("no results to copy")
+			} else if err := clipboard.WriteAll(strings.Join(paths, "\n") + "\n"); err != nil {
+				m.status = tr("can't copy to the clipboard: %s", err)
+			} else {
+				m.status = tr("copied %d paths to the clipboard", len(paths))
+			}
+		case "alt+t":
+			if paths := m.targetPaths(); len(paths) > 0 {
+				m.tagEdit = newTagEditState(paths)
+				return m, textinput.Blink
+			}
+		case "alt+e":
+			if m.list.SelectedItem() != nil {
+				return m.confirmPrivate([]string{m.list.SelectedItem().(Note).path}, Model.editInline)
+			}
+		case "alt+p":
+			inline, err := editor.NewInlineEditor(m.scratchpad, true)
+			if err != nil {
+				m.status = tr("can't open scratchpad: %s", err)
+			} else {
+				m.inline = inline
+				m.status = tr("scratchpad (alt+p or esc to save and close, at most %d lines)", editor.MaxInlineLines)
+				cmds = append(cmds, m.inline.Init())
+			}
+		case "alt+x":
+			if m.preview != nil {
+				return m.confirmPrivate([]string{m.previewPath}, func(m Model) (Model, tea.Cmd) {
+					return m, m.exportNote(m.previewPath)
+				})
+			}
+			m.status = tr("open a preview to export it")
+		case "ctrl+f":
+			m.prompt = newPrompt(tr("Narrow:"), m.narrow, func(m Model, value string) (Model, tea.Cmd) {
+				return m.setNarrow(value), nil
+			})
+			m.prompt.onChange = func(m Model, value string) Model {
+				return m.setNarrow(value)
+			}
+			return m, textinput.Blink
+		case "ctrl+t":
+			if query, ok := withoutPathFilter(m.textInput.Value()); ok {
+				m.textInput.SetValue(query)
+				m.textInput.CursorEnd()
+				m.status = tr("path filter cleared")
+				return m, m.search(query)
+			}
+			m.prompt = newPrompt(tr("Path:"), "", func(m Model, value string) (Model, tea.Cmd) {
+				return m.filterPath(value)
+			})
+			return m, textinput.Blink
+		case "ctrl+l":
+			m.prompt = newPrompt(tr("Filters:"), strings.Join(m.filters, " "), func(m Model, value string) (Model, tea.Cmd) {
+				return m.setFilters(value)
+			})
+			m.prompt.input.Placeholder = tr("ext:md path:work/ tag:todo, empty clears")
+			return m, textinput.Blink
+		case "alt+n":
+			term := ""
+			if m.list.SelectedItem() != nil {
+				term = highlightedTerm(m.list.SelectedItem().(Note).content)
+			}
+			m.prompt = newPrompt(tr("Exclude:"), term, func(m Model, value string) (Model, tea.Cmd) {
+				return m.excludeTerm(value)
+			})
+			return m, textinput.Blink
+		case "alt+m":
+			if m.list.SelectedItem() != nil {
+				path := m.list.SelectedItem().(Note).path
+				m.status = tr("notes similar to %s", filepath.Base(path))
+				cmds = append(cmds, m.similar(path))
+			}
+		case "ctrl+g":
+			return m, m.showCheatSheet()
+		case "alt+l":
+			m.lineNumbers = !m.lineNumbers
+			// The numbered note is shown as written, its tasks move.
+			m.taskLine = -1
+			m.renderPreview()
+			return m, nil
+		case "alt+k":
+			toggleBackground()
+			if m.preview != nil {
+				m.preview.SetBorderColor(theme.Border)
+				m.preview.SetSyntaxTheme(theme.SyntaxTheme)
+				m.renderPreview()
+			}
+			return m, nil
+		case "alt+up", "alt+down":
+			if m.preview != nil {
+				m.moveTaskCursor(msg.String() == "alt+down")
+			}
+			return m, nil
+		case "alt+enter":
+			if m.preview != nil {
+				return m, m.toggleTask()
+			}
+			return m, nil
+		case "alt+w":
+			m.noWrap = !m.noWrap
+			m.previewX = 0
+			m.renderPreview()
+			return m, nil
+		case "alt+left", "alt+right":
+			// Otherwise they move the cursor by word in the search box.
+			if m.preview != nil && m.noWrap {
+				if msg.String() == "alt+left" {
+					m.previewX = lo.Max([]int{m.previewX - scrollStep, 0})
+				} else {
+					m.previewX += scrollStep
+				}
+				m.renderPreview()
+				return m, nil
+			}
+		case "alt+g":
+			if m.preview == nil || m.previewText == "" {
+				m.status = tr("open a preview to go to a line")
+				return m, nil
+			}
+			m.prompt = newPrompt(tr("Line:"), "", func(m Model, value string) (Model, tea.Cmd) {
+				n, err := strconv.Atoi(strings.TrimSpace(value))
+				if err != nil || n < 1 {
+					m.status = tr("not a line number: %s", value)
+					return m, nil
+				}
+				m.gotoLine(n)
+				return m, nil
+			})
+			return m, textinput.Blink
+		case "alt+v":
+			return m, m.noteFromClipboard()
+		case "alt+j":
+			m.calendar = m.newCalendarState(time.Now())
+			return m, nil
+		case "alt+u":
+			if m.list.SelectedItem() != nil {
+				path := m.list.SelectedItem().(Note).path
+				if source, err := sourceURL(path); err != nil {
+					m.status = tr("can't open the source: %s", err)
+				} else if err := editor.OpenURL(source); err != nil {
+					m.status = tr("can't open the source: %s", err)
+				} else {
+					m.status = tr("opened %s", source)
+					cmds = append(cmds, m.openPreview(path))
+				}
+			}
+			return m, tea.Batch(cmds...)
+		case "alt+z":
+			if m.list.SelectedItem() == nil {
+				return m, nil
+			}
+			if len(m.sendToCommands) == 0 && len(m.actions) == 0 {
+				m.status = tr("no send_to commands or actions configured")
+				return m, nil
+			}
+			path := m.list.SelectedItem().(Note).path
+			return m.confirmPrivate([]string{path}, func(m Model) (Model, tea.Cmd) {
+				m.sendTo = newSendToState(path, m.textInput.Value(), m.sendToCommands, m.actions)
+				return m, nil
+			})
+		case "alt+d":
+			paths, ok := m.comparedPaths()
+			if !ok {
+				m.status = tr("mark two notes with ctrl+s to compare them")
+				return m, nil
+			}
+			return m.confirmPrivate(paths, func(m Model) (Model, tea.Cmd) {
+				compare, err := newCompareState(paths[0], paths[1])
+				if err != nil {
+					m.status = tr("can't compare: %s", err)
+					return m, nil
+				}
+				m.compare = compare
+				return m, nil
+			})
+		case "alt+#":
+			return m, m.showTagFacets()
+		case "alt+]":
+			return m, m.showLinkReport()
+		case "alt+~":
+			return m.toggleFuzzy()
+		case "alt+@":
+			if m.list.SelectedItem() != nil {
+				return m, m.showGraph(m.list.SelectedItem().(Note).path)
+			}
+		case "alt+y":
+			m.dashboard = &dashboardState{}
+			return m, m.loadDashboard()
+		case "alt+q":
+			m.builder = newBuilderState()
+			return m, textinput.Blink
+		case "ctrl+n":
+			if dir := m.siblingsFolder(); dir != "" {
+				return m, m.listFolder(dir)
+			}
+			return m, nil
+		case "ctrl+p":
+			return m, m.listParent()
+		case "alt+h":
+			browse, err := m.newBrowseState()
+			if err != nil {
+				m.status = tr("can't browse: %s", err)
+				return m, nil
+			}
+			m.browse = browse
+			m.status = tr("browsing %s · type to search", m.rootPath)
+			return m, nil
+		case "alt+o":
+			if m.list.SelectedItem() != nil && !notes.IsAttachment(m.list.SelectedItem().(Note).path) {
+				path := m.list.SelectedItem().(Note).path
+				return m.confirmPrivate([]string{path}, func(m Model) (Model, tea.Cmd) {
+					metadata, err := newMetadataState(path)
+					if err != nil {
+						m.status = tr("can't edit the metadata: %s", err)
+						return m, nil
+					}
+					m.metadata = metadata
+					return m, textinput.Blink
+				})
+			}
+			return m, nil
+		case "alt+i":
+			if m.list.SelectedItem() != nil && !notes.IsAttachment(m.list.SelectedItem().(Note).path) {
+				path := m.list.SelectedItem().(Note).path
+				m.prompt = newPrompt(tr("Append to %s (empty pastes the clipboard):", filepath.Base(path)), "", func(m Model, value string) (Model, tea.Cmd) {
+					return m.appendTo(path, value)
+				})
+				return m, textinput.Blink
+			}
+		case "alt+b":
+			return m.promptMerge()
+		case "alt+c":
+			m.order = (m.order + 1) % 3
+			switch m.order {
+			case byMatches:
+				m.status = tr("sorted by match count (alt+c for path)")
+			case byPath:
+				m.status = tr("sorted by path (alt+c for relevance)")
+			default:
+				m.status = tr("sorted by relevance")
+			}
+			m.showResults()
+			return m, nil
+		case "alt+s":
+			return m, func() tea.Msg {
+				return RandomMsg{m.indexer.Random()}
+			}
+		case "alt+r":
+			m.replace = newReplaceState()
+			return m, textinput.Blink
+		case "alt+a":
 			if m.list.SelectedItem() != nil {
 				path := m.list.SelectedItem().(Note).path
-				m.indexer.CloseIndex()
-				cmd = m.editor.EditFile(path)
-				cmds = append(cmds, cmd)
+				if _, err := notes.Archive(m.rootPath, m.archiveDir, path); err != nil {
+					m.status = tr("archive failed: %s", err)
+				} else {
+					m.status = tr("archived %s (search is:archived to find it)", filepath.Base(path))
+					m.preview = nil
+					cmds = append(cmds, m.reindex())
+				}
 			}
 		default:
-			log.Print(msg.String())
+			slog.Debug("unhandled key", "key", msg.String())
+		}
+	case StatusMsg:
+		m.status = string(msg)
+	case staleIndexMsg:
+		m.staleIndex = true
+		m.status = tr("index looks stale (%d notes on disk, %d indexed), reindex now? y/n", msg.onDisk, msg.indexed)
+	case dashboardMsg:
+		if m.dashboard != nil {
+			msg.dashboard.cursor = m.dashboard.cursor
+			m.dashboard = &msg.dashboard
+		}
+	case rootMissingMsg:
+		m.missingRoot = true
+		m.staleIndex = false
+		m.status = rootMissingStatus(m.rootPath, msg.err)
+	case cheatSheetMsg:
+		m.cheatSheet = msg.backend
+	case sentMsg:
+		return m.sent(msg)
+	case tagsCountedMsg:
+		return m.tagsCounted(msg), nil
+	case linkGraphMsg:
+		if m.linkReport != nil {
+			m.linkReport.graph = msg.graph
+		}
+		if m.graphView != nil {
+			m.graphView.graph = msg.graph
+		}
+	case CreatedMsg:
+		if msg.err != nil {
+			m.status = tr("can't create note: %s", msg.err)
+			return m, nil
+		}
+		m.status = tr("created %s", filepath.ToSlash(msg.path))
+		cmds = append(cmds, m.reindex(), m.openPreview(msg.path))
+	case RandomMsg:
+		if msg.result.Err != nil || len(msg.result.Hits) == 0 {
+			m.status = tr("no notes to pick from")
+			return m, nil
+		}
+		path := msg.result.Hits[0].Path
+		m.status = tr("random note: %s", filepath.ToSlash(path))
+		cmds = append(cmds, m.openPreview(path))
+	case notesChangedMsg:
+		return m, tea.Batch(m.indexChanges(msg.changes), m.waitForChanges())
+	case reindexTickMsg:
+		// Queued while the editor is open.
+		return m, tea.Batch(m.reindex(), m.scheduleReindex())
+	case spinner.TickMsg:
+		// The spinner stops with the reindex.
+		if msg.ID == m.spinner.ID() {
+			if m.indexState != indexIndexing {
+				return m, nil
+			}
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+	case indexProgressMsg:
+		// The progress is shown when the reindex is slow, along with each
+		// root when there are several. Failures and skipped files are
+		// always reported once it's done.
+		indexing := lo.SomeBy(msg.statuses, func(s search.IndexStatus) bool { return s.Indexing })
+		if indexing && m.indexState == indexIndexing {
+			m.indexing = msg.statuses
+			m.indexProgress = true
+		}
+		if indexing && len(msg.statuses) > 1 {
+			m.status = indexSummary(msg.statuses)
+		}
+		if indexing {
+			return m, m.pollIndex()
+		}
+	case IndexedMsg:
+		if m.indexProgress || lo.SomeBy(msg.statuses, func(s search.IndexStatus) bool { return s.Err != "" || s.Skipped > 0 || s.Errors > 0 }) {
+			m.status = indexSummary(msg.statuses)
 		}
+		m.indexProgress = false
+		m.indexing = nil
+		if lo.SomeBy(msg.statuses, func(s search.IndexStatus) bool { return s.Root == m.rootPath && s.Err != "" }) {
+			cmds = append(cmds, m.checkRoot())
+		}
+		// The index is closed for a pending edit, the results are
+		// refreshed once the editor is done.
+		if len(m.pendingEdit) > 0 {
+			return m, tea.Batch(append(cmds, m.indexingDone())...)
+		}
+		// Refresh the results of the current query.
+		return m, tea.Batch(append(cmds, m.search(m.textInput.Value()), m.dashboardCmd(), m.indexingDone())...)
+	case previewLoadedMsg:
+		// Ignore a note that is no longer previewed.
+		if m.preview == nil || msg.path != m.previewPath {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.previewText = tr("Error: %s", msg.err)
+		} else {
+			m.previewText = msg.content
+		}
+		m.previewRaw = msg.raw
+		m.previewKey = msg.key
+		m.renderPreview()
 	case editor.EditingFinished:
 		m.indexer.OpenIndex()
+		cmds = append(cmds, m.indexEdited(m.editedPaths))
 	case tea.WindowSizeMsg:
 		m.updateSize(msg.Width, msg.Height)
+		// The lines are cut to the width when not wrapping.
+		if m.noWrap && m.previewText != "" {
+			m.setPreviewSize()
+			m.renderPreview()
+		}
 	}
 
 	// Update the widgets sizes
 	m.setListSize()
 	m.setPreviewSize()
+	m.setInlineSize()
 
 	// save to commpare if changed
 	oldValue := m.textInput.Value()
@@ -189,12 +1209,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// If input has changed, search for the new value
 	newValue := m.textInput.Value()
 	if oldValue != newValue {
+		// A new query starts with all of its results.
+		m.narrow = ""
 		// This returns a funciton that returns a message(ResultMsg) eventually
-		m.queryId++
-		return m, func() tea.Msg {
-			results := m.indexer.Search(newValue)
-			return ResultMsg{results: results, queryId: m.queryId}
-		}
+		return m, m.search(newValue)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -204,11 +1222,378 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 type ResultMsg struct {
 	results search.SearchResult
 	queryId int
+
+	// The page of the query, size is 0 when the results aren't paged.
+	query      string
+	from, size int
+	took       time.Duration // time the page took, 0 if not measured
+}
+
+// updateInline handles key presses while the built-in editor is open.
+// Keys: ctrl+s - save and close, esc - discard and close.
+// The scratchpad is always saved, with alt+p or esc.
+func (m Model) updateInline(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	keyName := key.String()
+	if m.inline.Path == m.scratchpad && (keyName == "alt+p" || keyName == "esc") {
+		if !m.inline.Modified() {
+			m.inline = nil
+			m.status = ""
+			return m, nil
+		}
+		keyName = "ctrl+s"
+	}
+
+	switch keyName {
+	case "ctrl+s":
+		path := m.inline.Path
+		if err := m.inline.Save(); err != nil {
+			m.status = tr("save failed: %s", err)
+			return m, nil
+		}
+		m.inline = nil
+		m.status = tr("saved %s", filepath.Base(path))
+		return m, m.reindex()
+	case "esc":
+		if m.inline.Modified() {
+			m.status = tr("discarded changes to %s", filepath.Base(m.inline.Path))
+		} else {
+			m.status = ""
+		}
+		m.inline = nil
+		return m, nil
+	}
+
+	return m, m.inline.Update(key)
+}
+
+// excludeTerm adds -term to the query and searches again.
+func (m Model) excludeTerm(term string) (Model, tea.Cmd) {
+	fields := strings.Fields(term)
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	query := strings.TrimSpace(m.textInput.Value()) + " -" + fields[0] + " "
+	m.textInput.SetValue(strings.TrimLeft(query, " "))
+	m.textInput.CursorEnd()
+	m.status = tr("excluding %s", fields[0])
+	return m, m.search(m.textInput.Value())
+}
+
+// filterPath adds path:sub to the query and searches again, see
+// search.Query.Paths for folders and globs.
+func (m Model) filterPath(sub string) (Model, tea.Cmd) {
+	fields := strings.Fields(sub)
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	query := strings.TrimSpace(m.textInput.Value()) + " path:" + fields[0] + " "
+	m.textInput.SetValue(strings.TrimLeft(query, " "))
+	m.textInput.CursorEnd()
+	if strings.ContainsAny(fields[0], "*?") {
+		m.status = tr("limited to paths matching %s (ctrl+t to clear)", fields[0])
+	} else {
+		m.status = tr("limited to the folder %s (ctrl+t to clear)", fields[0])
+	}
+	return m, m.search(m.textInput.Value())
+}
+
+// withoutPathFilter drops the path: clauses from the query and reports
+// whether there were any.
+func withoutPathFilter(query string) (string, bool) {
+	fields := strings.Fields(query)
+	kept := lo.Filter(fields, func(field string, _ int) bool {
+		return !strings.HasPrefix(strings.ToLower(field), "path:")
+	})
+	if len(kept) == len(fields) {
+		return query, false
+	}
+	if len(kept) == 0 {
+		return "", true
+	}
+	return strings.Join(kept, " ") + " ", true
+}
+
+// highlightedTerm returns the first highlighted term of a snippet.
+func highlightedTerm(snippet string) string {
+	match := regexp.MustCompile(`<mark>(.*?)</mark>`).FindStringSubmatch(snippet)
+	if match == nil {
+		return ""
+	}
+	return strings.ToLower(match[1])
+}
+
+// openPreview shows the note at path in the preview pane. Private notes
+// are shown once confirmed, unless they're shown already.
+func (m *Model) openPreview(path string) tea.Cmd {
+	if (m.preview == nil || m.previewPath != path) && notes.IsPrivateFile(path) {
+		m.privateNote = &privateConfirm{path: path, then: func(m Model) (Model, tea.Cmd) {
+			cmd := m.showPreview(path)
+			m.setListSize()
+			return m, cmd
+		}}
+		m.status = tr("%s is private, show it? y show · any other key cancels", filepath.Base(path))
+		return nil
+	}
+	return m.showPreview(path)
+}
+
+// privateConfirm is a private note waiting for confirmation, and what to
+// do once confirmed.
+type privateConfirm struct {
+	path string
+	then func(m Model) (Model, tea.Cmd)
+}
+
+// confirmPrivate runs then once the user confirms using the private notes
+// among paths, right away when there are none. Everything that shows,
+// edits or passes on the content of notes goes through it or openPreview.
+func (m Model) confirmPrivate(paths []string, then func(m Model) (Model, tea.Cmd)) (Model, tea.Cmd) {
+	private, ok := lo.Find(paths, notes.IsPrivateFile)
+	if !ok {
+		return then(m)
+	}
+	m.privateNote = &privateConfirm{path: private, then: then}
+	m.status = tr("%s is private, use its content? y continue · any other key cancels", filepath.Base(private))
+	return m, nil
+}
+
+// editInline opens the selected note in the inline editor.
+func (m Model) editInline() (Model, tea.Cmd) {
+	path := m.list.SelectedItem().(Note).path
+	inline, err := editor.NewInlineEditor(path, false)
+	if err != nil {
+		m.status = tr("can't edit inline: %s", err)
+		return m, nil
+	}
+	m.inline = inline
+	m.status = tr("editing %s (ctrl+s save, esc discard, at most %d lines)", filepath.Base(path), editor.MaxInlineLines)
+	return m, m.inline.Init()
+}
+
+// showPreview shows the note at path in the preview pane.
+func (m *Model) showPreview(path string) tea.Cmd {
+	codeModel := code.New(false, true, theme.Border)
+	codeModel.SetSyntaxTheme(theme.SyntaxTheme)
+	codeModel.SetSize(m.width/1, m.height)
+	m.preview = &codeModel
+	m.previewPath = path
+	m.previewText = ""
+	m.previewRaw = ""
+	m.previewKey = previewKey{}
+	m.taskLine = -1
+
+	// Images, pdfs and other binary files get a card about them instead,
+	// as do the files that can't be read, with the error.
+	if notes.IsAttachment(path) || !notes.IsText(path) {
+		codeModel.HighlightedContent = m.metadataCard(path)
+		codeModel.SetSize(m.width/1, m.height)
+		return nil
+	}
+	return loadPreview(path, m.diagrams, m.previews)
+}
+
+// exportNote exports the note to HTML, and PDF when a converter is set.
+func (m *Model) exportNote(path string) tea.Cmd {
+	dir, converter := m.exportDir, m.pdfConverter
+	return func() tea.Msg {
+		out, err := export.HTML(path, dir)
+		if err == nil && converter != "" {
+			out, err = export.PDF(path, dir, converter)
+		}
+		if err != nil {
+			return StatusMsg(tr("export failed: %s", err))
+		}
+		return StatusMsg(tr("exported to %s", filepath.ToSlash(out)))
+	}
+}
+
+// noteFromClipboard creates a note in the inbox from the clipboard.
+func (m *Model) noteFromClipboard() tea.Cmd {
+	dir, ext := m.inboxDir, notes.NewNoteExtension(m.extensions)
+	return func() tea.Msg {
+		content, err := clipboard.ReadAll()
+		if err != nil {
+			return CreatedMsg{err: err}
+		}
+		path, err := notes.Create(dir, ext, content)
+		return CreatedMsg{path, err}
+	}
+}
+
+// appendTo appends the line to the note, or the clipboard when the line is
+// empty, and reindexes.
+func (m Model) appendTo(path, line string) (Model, tea.Cmd) {
+	if line == "" {
+		content, err := clipboard.ReadAll()
+		if err != nil {
+			m.status = tr("append failed: %s", err)
+			return m, nil
+		}
+		line = content
+	}
+
+	if err := notes.Append(path, line); err != nil {
+		m.status = tr("append failed: %s", err)
+		return m, nil
+	}
+	m.status = tr("appended to %s", filepath.Base(path))
+
+	cmds := []tea.Cmd{m.reindex()}
+	if m.preview != nil && m.previewPath == path {
+		cmds = append(cmds, m.openPreview(path))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// sourceURL returns the source_url of a web clipping, which must be an
+// http(s) URL.
+func sourceURL(path string) (string, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	source := strings.TrimSpace(frontmatter.Fields(string(body))["source_url"])
+	if source == "" {
+		return "", fmt.Errorf("%s has no source_url", filepath.Base(path))
+	}
+	if u, err := url.Parse(source); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("%s is not a web address", source)
+	}
+	return source, nil
+}
+
+// refreshSelection updates the marks shown in the list.
+func (m *Model) refreshSelection() {
+	for i, item := range m.results {
+		note := item.(Note)
+		note.selected = m.selected[note.path]
+		m.results[i] = note
+	}
+	m.showResults()
+}
+
+// foldCopies appends the page to results, folding the notes with the same
+// content as one listed before into its alsoAt.
+func foldCopies(results, page []list.Item) []list.Item {
+	byHash := map[string]int{}
+	for i, item := range results {
+		if hash := item.(Note).hash; hash != "" {
+			byHash[hash] = i
+		}
+	}
+	for _, item := range page {
+		note := item.(Note)
+		if i, ok := byHash[note.hash]; ok && note.hash != "" {
+			first := results[i].(Note)
+			first.alsoAt = append(first.alsoAt, note.path)
+			results[i] = first
+			continue
+		}
+		if note.hash != "" {
+			byHash[note.hash] = len(results)
+		}
+		results = append(results, item)
+	}
+	return results
+}
+
+// showResults lists the results in the chosen order, fuzzy filtered by
+// the narrow filter.
+func (m *Model) showResults() {
+	results := m.results
+	switch m.order {
+	case byMatches:
+		results = append([]list.Item{}, m.results...)
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].(Note).matches > results[j].(Note).matches
+		})
+	case byPath:
+		results = append([]list.Item{}, m.results...)
+		sort.SliceStable(results, func(i, j int) bool {
+			return naturalLess(results[i].(Note).path, results[j].(Note).path)
+		})
+	}
+
+	if m.narrow == "" {
+		m.list.SetItems(withHotkeys(results))
+		return
+	}
+
+	targets := lo.Map(results, func(item list.Item, _ int) string {
+		note := item.(Note)
+		return note.path + " " + strings.NewReplacer("<mark>", "", "</mark>", "").Replace(note.content)
+	})
+	ranks := list.DefaultFilter(m.narrow, targets)
+	m.list.SetItems(withHotkeys(lo.Map(ranks, func(rank list.Rank, _ int) list.Item {
+		return results[rank.Index]
+	})))
+}
+
+// setNarrow changes the narrow filter and shows the matching results.
+func (m Model) setNarrow(value string) Model {
+	m.narrow = value
+	m.showResults()
+	if value == "" {
+		m.status = ""
+	} else {
+		m.status = tr("narrowed to %d of %d results (ctrl+f to change)", len(m.list.Items()), len(m.results))
+	}
+	return m
+}
+
+// targetPaths returns the marked notes, or the selected one if none are marked.
+func (m *Model) targetPaths() []string {
+	if len(m.selected) > 0 {
+		paths := lo.Keys(m.selected)
+		sort.Strings(paths)
+		return paths
+	}
+	if m.list.SelectedItem() != nil {
+		return []string{m.list.SelectedItem().(Note).path}
+	}
+	return nil
+}
+
+// This is emitted by background actions to report back in the status line
+type StatusMsg string
+
+// This is emitted on startup when the index doesn't match the notes on disk
+type staleIndexMsg struct {
+	onDisk, indexed int
+}
+
+// This is emitted when a note was created from the clipboard
+type CreatedMsg struct {
+	path string
+	err  error
+}
+
+// This is emitted with the note picked by "surprise me"
+type RandomMsg struct {
+	result search.SearchResult
+}
+
+// This is emitted when a reindex has finished
+type IndexedMsg struct {
+	statuses []search.IndexStatus // how the reindex of each root went
 }
 
+// This is emitted while a reindex runs
+type indexProgressMsg struct {
+	statuses []search.IndexStatus
+}
+
+// This is emitted when a scheduled reindex is due
+type reindexTickMsg struct{}
+
 // View fn for bubbletea model
 func (m Model) View() string {
 	listContent := ListStyle.Render(m.list.View())
+	if len(m.filters) > 0 {
+		listContent = lipgloss.JoinVertical(lipgloss.Left, m.viewFilters(), listContent)
+	}
 
 	// render list
 	innerContent := listContent
@@ -216,60 +1601,290 @@ func (m Model) View() string {
 	// if preview then preview takes up half the width
 	if m.preview != nil {
 		innerContent = lipgloss.JoinHorizontal(lipgloss.Left,
-			listContent,      // render list
-			m.preview.View(), // render preview.
+			listContent,     // render list
+			m.previewView(), // render preview.
 		)
 	}
 
-	// render the input box and the content
+	if m.browse != nil {
+		innerContent = m.viewBrowse()
+	}
+	if m.replace != nil {
+		innerContent = m.viewReplace()
+	}
+	if m.tagEdit != nil {
+		innerContent = m.viewTagEdit()
+	}
+	if m.inline != nil {
+		innerContent = m.inline.View()
+	}
+	if m.cheatSheet != nil {
+		innerContent = m.viewCheatSheet()
+	}
+	if m.calendar != nil {
+		innerContent = m.viewCalendar()
+	}
+	if m.builder != nil {
+		innerContent = m.viewBuilder()
+	}
+	if m.metadata != nil {
+		innerContent = m.viewMetadata()
+	}
+	if m.dashboard != nil {
+		innerContent = m.viewDashboard()
+	}
+	if m.sendTo != nil {
+		innerContent = m.viewSendTo()
+	}
+	if m.compare != nil {
+		innerContent = m.viewCompare()
+	}
+	if m.tagFacets != nil {
+		innerContent = m.viewTagFacets()
+	}
+	if m.linkReport != nil {
+		innerContent = m.viewLinkReport()
+	}
+	if m.graphView != nil {
+		innerContent = m.viewGraph()
+	}
+
+	statusLine := theme.Status.Render(m.status)
+	// The progress of the reindex, or else the latency of the last search,
+	// goes on the right.
+	right := ""
+	switch {
+	case m.indexState == indexIndexing && m.indexProgress:
+		right = theme.Status.Render(m.viewIndexProgress())
+	case m.latency > 0:
+		right = theme.Status.Render(stats.Round(m.latency))
+	}
+	if gap := m.width - lipgloss.Width(statusLine) - lipgloss.Width(right); right != "" && gap > 0 {
+		statusLine += strings.Repeat(" ", gap) + right
+	}
+	if m.prompt != nil {
+		statusLine = m.prompt.input.View()
+	}
+
+	// render the input box, the status line and the content
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.textInput.View(), // render the text input
+		statusLine,         // render the status line
 		innerContent,       // render the main content
 	)
 }
 
+// Address of the daemon to search through, set by --connect.
+var connectAddr string
+
 func main() {
+	flag.StringVar(&connectAddr, "connect", "", "search through a notes_search daemon at host:port or a unix socket path")
+	flag.Usage = usage
+	flag.Parse()
+
+	// The first run asks for the settings instead of failing on the
+	// missing config file.
+	if _, err := os.Stat(utils.ConfigPath()); os.IsNotExist(err) {
+		if flag.Arg(0) != "" {
+			fmt.Fprintf(os.Stderr, "no config file at %s, run notes_search without a command to set it up\n", utils.ConfigPath())
+			os.Exit(1)
+		}
+		if !firstRun() {
+			return
+		}
+	}
+
+	// read application config
+	config := utils.NewConfig()
+
 	// Setup logging.
-	homedir, _ := os.UserHomeDir()
-	log_path := path.Join(homedir, "/.config/notes_search/debug.log")
-	f, err := tea.LogToFile(log_path, "debug")
+	log_path := filepath.Join(utils.ConfigDir(), "debug.log")
+	f, err := utils.SetupLog(log_path, config.LogLevel)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	defer f.Close()
 
-	// read application config
-	config := utils.NewConfig()
+	setupTheme(config.Theme, config.SyntaxTheme, config.Background)
+	setupLocale(config.Locale)
+
+	// run a command instead of the TUI.
+	if name := flag.Arg(0); name != "" {
+		cmd, ok := commands[name]
+		if !ok {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := cmd.run(config, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// create the indexer.
-	indexer, err := bleve_indexer.NewBleveIndexer(config)
+	indexer, err := newIndexer(config)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// Create a new bubbletea Model
-	m := New(&indexer, config)
+	m := New(indexer, config)
 	p := tea.NewProgram(m)
 	if _, err := p.Run(); err != nil {
 		panic(err)
 	}
+	// Waits for a reindex cancelled on quit to stop.
+	indexer.CloseIndex()
+	if m.latencies != nil {
+		if err := m.latencies.Flush(); err != nil {
+			slog.Warn("recording the latencies failed", "err", err)
+		}
+	}
 }
 
+// newIndexer creates the indexer, remote if --connect was given and
+// federated over the vaults if there are several. Usage isn't recorded
+// along with an encrypted index.
+func newIndexer(config *utils.Config) (search.NotesIndexer, error) {
+	indexer, err := newSearchIndexer(config)
+	if err != nil || !config.UsageMetrics || config.EncryptIndex {
+		return indexer, err
+	}
+	return stats.NewUsageIndexer(indexer, stats.NewUsage()), nil
+}
+
+// newSearchIndexer creates the indexer without the usage metrics.
+func newSearchIndexer(config *utils.Config) (search.NotesIndexer, error) {
+	if connectAddr != "" {
+		return remote.NewRemoteIndexer(connectAddr, config.Server)
+	}
+
+	var indexer search.NotesIndexer
+	configs := config.VaultConfigs()
+	if len(configs) == 1 {
+		vaultIndexer, err := newVaultIndexer(config)
+		if err != nil {
+			return nil, err
+		}
+		indexer = vaultIndexer
+	} else {
+		vaults := []search.Vault{}
+		for _, vault := range configs {
+			vaultIndexer, err := newVaultIndexer(vault)
+			if err != nil {
+				return nil, err
+			}
+			vaults = append(vaults, search.Vault{Name: vault.VaultName(), Indexer: vaultIndexer})
+		}
+		indexer = search.NewFederatedIndexer(vaults)
+	}
+
+	// The grep backend reads the notes at every search, cached results
+	// would go stale.
+	if config.Backend == "grep" {
+		return indexer, nil
+	}
+	return search.NewCachedIndexer(indexer, queryCacheSize), nil
+}
+
+// newVaultIndexer returns the backend of the config searching its notes
+// root.
+func newVaultIndexer(config *utils.Config) (search.NotesIndexer, error) {
+	switch config.Backend {
+	case "grep":
+		return grep_indexer.NewGrepIndexer(config), nil
+	case "bleve":
+		indexer, err := bleve_indexer.NewBleveIndexer(config)
+		if err != nil {
+			return nil, err
+		}
+		return &indexer, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q, use bleve or grep", config.Backend)
+}
+
+// Number of recent queries whose results are kept in memory.
+const queryCacheSize = 64
+
 // Note implements list.Item interface
 type Note struct {
-	path    string
-	content string
+	path     string
+	content  string
+	selected bool     // marked for bulk actions
+	terms    []string // query terms, to colour the matches
+	matches  int      // occurrences of the query terms, 0 if unknown
+	title    string   // title of the note, shown before the path if set
+	vault    string   // vault of the note when searching several
+	hash     string   // digest of the content, to fold copies together
+	pinned   bool     // listed first while the query is empty
+	private  bool     // its content isn't shown, see notes.IsPrivate
+	alsoAt   []string // copies of the note, hard links or identical files
+	hotkey   int      // digit of its alt+digit quick open key, 0 for none
+
+	referencedBy []string // notes linking to the hit when it's an attachment
+}
+
+// target returns the note to open for the hit: the first note linking to
+// it for attachments, "" when none does.
+func (n Note) target() string {
+	if !notes.IsAttachment(n.path) {
+		return n.path
+	}
+	if len(n.referencedBy) == 0 {
+		return ""
+	}
+	return n.referencedBy[0]
 }
 
-func (n Note) Title() string       { return n.path }
-func (n Note) Description() string { return format_string(n.content) }
+func (n Note) Title() string {
+	title := filepath.ToSlash(n.path)
+	if n.title != "" {
+		title = n.title + " · " + title
+	}
+	if n.vault != "" {
+		title = "[" + n.vault + "] " + title
+	}
+	if n.pinned {
+		title = "★ " + title
+	}
+	if n.selected {
+		title = "● " + title
+	}
+	if n.hotkey > 0 {
+		title = strconv.Itoa(n.hotkey) + " " + title
+	}
+	switch {
+	case n.matches == 1:
+		title += "  " + tr("(1 match)")
+	case n.matches > 1:
+		title += "  " + tr("(%d matches)", n.matches)
+	}
+	if len(n.alsoAt) > 0 {
+		title += "  " + tr("also at %s", strings.Join(lo.Map(n.alsoAt, func(path string, _ int) string { return filepath.ToSlash(path) }), ", "))
+	}
+	return title
+}
+
+func (n Note) Description() string {
+	if n.private {
+		return theme.Snippet.Render(tr("private note, enter to show it"))
+	}
+	if notes.IsAttachment(n.path) {
+		if len(n.referencedBy) == 0 {
+			return theme.Snippet.Render(tr("attachment, not referenced by any note"))
+		}
+		names := lo.Map(n.referencedBy, func(path string, _ int) string { return filepath.Base(path) })
+		return theme.Snippet.Render(tr("referenced by %s", strings.Join(names, ", ")))
+	}
+	return format_string(n.content, n.terms)
+}
 func (n Note) FilterValue() string { return "" }
 
 // Create the list model
 func create_list_model() list.Model {
-	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l := list.New([]list.Item{}, listDelegate(), 0, 0)
 	l.SetShowFilter(false)
 	l.SetShowHelp(false)
 	l.SetShowTitle(false)
@@ -280,22 +1895,17 @@ func create_list_model() list.Model {
 // Create the text input model
 func create_text_input() textinput.Model {
 	ti := textinput.New()
-	ti.Placeholder = "query"
-	ti.Prompt = "Search:"
-	ti.PromptStyle = lipgloss.NewStyle().
-		Background(lipgloss.Color("62")).
-		Foreground(lipgloss.Color("230")).
-		MarginRight(1).
-		MarginLeft(2).
-		Padding(0, 1)
+	ti.Placeholder = tr("query")
+	ti.Prompt = tr("Search:")
+	ti.PromptStyle = theme.Prompt
 	ti.Focus()
 	return ti
 }
 
 // format the string returned by bleve with simple highligting
 // example: "This is a <mark>test</mark> string" -> "This is a test string" with
-// test in pink.
-func format_string(input string) string {
+// test in the colour of the query term it matches.
+func format_string(input string, terms []string) string {
 	re := regexp.MustCompile(`<mark>(.*?)</mark>`)
 
 	matches := re.FindAllStringSubmatchIndex(input, -1)
@@ -303,8 +1913,7 @@ func format_string(input string) string {
 	var result []string
 	prevIndex := 0
 
-	pinkText := lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
-	grayText := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
+	grayText := theme.Snippet
 
 	for _, match := range matches {
 		// Append the text before the match
@@ -313,7 +1922,9 @@ func format_string(input string) string {
 		}
 
 		// Append the matched text
-		result = append(result, pinkText.Render(input[match[0]+6:match[1]-7]))
+		word := input[match[0]+6 : match[1]-7]
+		style := theme.matchStyle(lo.Max([]int{termIndex(word, terms), 0}))
+		result = append(result, style.Render(word))
 
 		// Update the previous index to the end of the match
 		prevIndex = match[1]
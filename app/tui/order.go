@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// resultOrder is how the results are sorted, switched with alt+c.
+type resultOrder int
+
+const (
+	byRelevance resultOrder = iota // as ranked by the index
+	byMatches                      // most matches first
+	byPath                         // by path, numbers in natural order
+)
+
+// naturalLess compares paths case-insensitively with the numbers in them
+// compared by value, so note2 comes before note10.
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := leadingNumber(a)
+			numB, restB := leadingNumber(b)
+			// Without leading zeros, the longer number is the larger one.
+			trimA, trimB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+			if len(trimA) != len(trimB) {
+				return len(trimA) < len(trimB)
+			}
+			if trimA != trimB {
+				return trimA < trimB
+			}
+			if len(numA) != len(numB) {
+				return len(numA) < len(numB)
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// leadingNumber splits the digits at the start of s from the rest.
+func leadingNumber(s string) (number, rest string) {
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) || r > unicode.MaxASCII })
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// isDigit tells whether the byte is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
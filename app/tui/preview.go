@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/knipferrc/teacup/code"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/reflow/truncate"
+	"github.com/samber/lo"
+)
+
+// This is emitted when the previewed note was read
+type previewLoadedMsg struct {
+	path    string
+	key     previewKey // version of the note read, zero if it couldn't be
+	content string
+	raw     string // the note with its lines as on disk, for numbering them
+	err     error
+}
+
+// loadPreview reads the note at path for the preview, rendering its
+// diagrams with the commands in diagrams and, for markdown, its tables,
+// footnotes and reference links. Unchanged notes come from the cache.
+// Rendering changes the line count, so the note is also kept as written
+// to number its lines.
+func loadPreview(path string, diagrams map[string]string, cache *previewCache) tea.Cmd {
+	return func() tea.Msg {
+		key, err := previewKeyOf(path)
+		if err != nil {
+			return previewLoadedMsg{path: path, err: err}
+		}
+		if content, raw, ok := cache.content(key); ok {
+			return previewLoadedMsg{path: path, key: key, content: content, raw: raw}
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return previewLoadedMsg{path: path, err: err}
+		}
+		raw := string(data)
+		content := renderDiagrams(raw, diagrams)
+		if isMarkdown(path) {
+			// Resolving the references keeps the lines.
+			raw = resolveReferences(raw)
+			content = renderTables(resolveReferences(content))
+		}
+		cache.setContent(key, content, raw)
+		return previewLoadedMsg{path: path, key: key, content: content, raw: raw}
+	}
+}
+
+// previewSource returns the text of the previewed note shown. With the line
+// numbers on it's the note as written, so they are the lines of the file,
+// else its diagrams and tables are rendered.
+func (m *Model) previewSource() string {
+	if m.lineNumbers && m.previewRaw != "" {
+		return m.previewRaw
+	}
+	return m.previewText
+}
+
+// renderPreview highlights the previewed note and the query terms in it,
+// numbering the lines if enabled.
+func (m *Model) renderPreview() {
+	if m.preview == nil {
+		return
+	}
+	content := m.highlighted()
+	content = highlightTerms(content, queryTerms(m.textInput.Value()))
+	content = m.markTask(content)
+	if m.noWrap {
+		width := m.preview.Viewport.Width
+		if m.lineNumbers {
+			width -= len(strconv.Itoa(strings.Count(m.previewSource(), "\n")+1)) + 3
+		}
+		content = scrollLines(content, m.previewX, width)
+	}
+	if m.lineNumbers {
+		content = numberLines(content)
+	}
+	m.preview.HighlightedContent = content
+	m.setPreviewSize()
+}
+
+// highlighted returns the shown text of the previewed note highlighted
+// with the syntax theme. Notes read from disk are highlighted once per
+// version.
+func (m *Model) highlighted() string {
+	source, raw := m.previewSource(), m.lineNumbers && m.previewRaw != ""
+	if content, ok := m.previews.highlighted(m.previewKey, theme.SyntaxTheme, raw); ok {
+		return content
+	}
+	content, err := code.Highlight(source, filepath.Ext(m.previewPath), theme.SyntaxTheme)
+	if err != nil {
+		return source
+	}
+	if m.previewKey.path != "" {
+		m.previews.setHighlighted(m.previewKey, theme.SyntaxTheme, raw, content)
+	}
+	return content
+}
+
+// Columns scrolled sideways by alt+left and alt+right.
+const scrollStep = 8
+
+// scrollLines cuts the lines of content to the columns from x on that fit
+// in width, keeping their colours.
+func scrollLines(content string, x, width int) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = truncate.String(cutLeft(line, x), uint(lo.Max([]int{width, 1})))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cutLeft drops the first x columns of line, keeping its escape sequences.
+func cutLeft(line string, x int) string {
+	if x <= 0 {
+		return line
+	}
+	var out strings.Builder
+	for len(line) > 0 {
+		if loc := sgr.FindStringIndex(line); loc != nil && loc[0] == 0 {
+			out.WriteString(line[:loc[1]])
+			line = line[loc[1]:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line)
+		if x > 0 {
+			x -= runewidth.RuneWidth(r)
+		} else {
+			out.WriteRune(r)
+		}
+		line = line[size:]
+	}
+	return out.String()
+}
+
+// numberLines prefixes the lines of content with their number.
+func numberLines(content string) string {
+	lines := strings.Split(content, "\n")
+	count := len(lines)
+	// The line after the final newline isn't one.
+	if count > 1 && sgr.ReplaceAllString(lines[count-1], "") == "" {
+		count--
+	}
+	width := len(strconv.Itoa(count))
+	style := theme.Status.Copy().UnsetPaddingLeft()
+	for i, line := range lines[:count] {
+		lines[i] = style.Render(fmt.Sprintf("%*d │", width, i+1)) + " " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gotoLine scrolls the preview to the line of the note, turning the line
+// numbers on. Wrapped lines are taken into account through the numbers.
+func (m *Model) gotoLine(n int) {
+	if !m.lineNumbers {
+		m.lineNumbers = true
+		m.taskLine = -1
+		m.renderPreview()
+	}
+	vp := &m.preview.Viewport
+	rendered := lipgloss.NewStyle().Width(vp.Width).Height(vp.Height).Render(m.preview.HighlightedContent)
+	prefix := strconv.Itoa(n) + " │"
+	for i, line := range strings.Split(rendered, "\n") {
+		if strings.HasPrefix(strings.TrimLeft(sgr.ReplaceAllString(line, ""), " "), prefix) {
+			vp.SetYOffset(i)
+			return
+		}
+	}
+	m.status = tr("no line %d", n)
+}
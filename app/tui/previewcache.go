@@ -0,0 +1,124 @@
+package main
+
+import (
+	"container/list"
+	"os"
+	"sync"
+	"time"
+)
+
+// Number of notes whose rendered previews are kept in memory.
+const previewCacheSize = 32
+
+// previewKey identifies a version of a note: an edit changes its
+// modification time or size, so it's rendered anew.
+type previewKey struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// previewKeyOf returns the key of the note at path as it is on disk.
+func previewKeyOf(path string) (previewKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return previewKey{}, err
+	}
+	return previewKey{path, info.ModTime(), info.Size()}, nil
+}
+
+type previewEntry struct {
+	key            previewKey
+	content        string            // the note with its diagrams and tables rendered
+	raw            string            // the note with its lines as written
+	highlighted    map[string]string // content highlighted, by syntax theme
+	rawHighlighted map[string]string // raw highlighted, by syntax theme
+}
+
+// previewCache keeps the rendered previews of the recent notes, so going
+// back to a note, e.g. while comparing hits, doesn't read and highlight it
+// again. It's filled by the preview commands, hence the lock.
+type previewCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[previewKey]*list.Element
+}
+
+// newPreviewCache keeps the previews of size notes.
+func newPreviewCache(size int) *previewCache {
+	return &previewCache{size: size, order: list.New(), entries: map[previewKey]*list.Element{}}
+}
+
+// content returns the rendered content of the note and the note as written.
+func (c *previewCache) content(key previewKey) (string, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.get(key); entry != nil {
+		return entry.content, entry.raw, true
+	}
+	return "", "", false
+}
+
+// setContent caches the rendered content of the note and the note as written.
+func (c *previewCache) setContent(key previewKey, content, raw string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.put(key)
+	entry.content, entry.raw = content, raw
+}
+
+// highlighted returns the content of the note, or the note as written if
+// raw, highlighted with the syntax theme.
+func (c *previewCache) highlighted(key previewKey, syntaxTheme string, raw bool) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.get(key); entry != nil {
+		highlighted, ok := entry.highlightedBy(raw)[syntaxTheme]
+		return highlighted, ok
+	}
+	return "", false
+}
+
+// setHighlighted caches the content of the note, or the note as written if
+// raw, highlighted with the syntax theme.
+func (c *previewCache) setHighlighted(key previewKey, syntaxTheme string, raw bool, highlighted string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(key).highlightedBy(raw)[syntaxTheme] = highlighted
+}
+
+// highlightedBy returns the highlighted versions of the content, or of the
+// note as written if raw.
+func (e *previewEntry) highlightedBy(raw bool) map[string]string {
+	if raw {
+		return e.rawHighlighted
+	}
+	return e.highlighted
+}
+
+// get returns the entry of key, nil if it isn't cached.
+func (c *previewCache) get(key previewKey) *previewEntry {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*previewEntry)
+}
+
+// put returns the entry of key, adding it if needed and dropping the
+// least recently used ones past the size.
+func (c *previewCache) put(key previewKey) *previewEntry {
+	if entry := c.get(key); entry != nil {
+		return entry
+	}
+	entry := &previewEntry{key: key, highlighted: map[string]string{}, rawHighlighted: map[string]string{}}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*previewEntry).key)
+		c.order.Remove(oldest)
+	}
+	return entry
+}
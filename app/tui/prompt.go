@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// promptState asks for a single line of input in the status line,
+// e.g. the term to exclude. Enter submits, esc cancels.
+type promptState struct {
+	input    textinput.Model
+	onSubmit func(m Model, value string) (Model, tea.Cmd)
+	onChange func(m Model, value string) Model // optional, live updates while typing
+}
+
+// newPrompt starts a prompt prefilled with value.
+func newPrompt(prompt, value string, onSubmit func(m Model, value string) (Model, tea.Cmd)) *promptState {
+	input := create_text_input()
+	input.Prompt = prompt
+	input.Placeholder = ""
+	input.SetValue(value)
+	input.CursorEnd()
+	return &promptState{input: input, onSubmit: onSubmit}
+}
+
+// updatePrompt handles key presses while a prompt is open.
+func (m Model) updatePrompt(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.prompt
+
+	switch key.String() {
+	case "esc", "ctrl+c":
+		m.prompt = nil
+		// Undo the live updates.
+		if p.onChange != nil {
+			m = p.onChange(m, "")
+		}
+		return m, nil
+	case "enter":
+		m.prompt = nil
+		return p.onSubmit(m, p.input.Value())
+	}
+
+	var cmd tea.Cmd
+	old := p.input.Value()
+	p.input, cmd = p.input.Update(key)
+	if p.onChange != nil && p.input.Value() != old {
+		m = p.onChange(m, p.input.Value())
+	}
+	return m, cmd
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Results labeled with the digit of their alt+1 ... alt+9 shortcut.
+const quickOpenKeys = 9
+
+// withHotkeys labels the first results with their quick open digit, and
+// clears the label of the others.
+func withHotkeys(items []list.Item) []list.Item {
+	labeled := make([]list.Item, len(items))
+	for i, item := range items {
+		note := item.(Note)
+		note.hotkey = 0
+		if i < quickOpenKeys {
+			note.hotkey = i + 1
+		}
+		labeled[i] = note
+	}
+	return labeled
+}
+
+// quickOpenIndex returns the result an alt+digit key picks, -1 for other
+// keys.
+func quickOpenIndex(key tea.KeyMsg) int {
+	name := key.String()
+	if len(name) != len("alt+1") || !strings.HasPrefix(name, "alt+") || name[4] < '1' || name[4] > '9' {
+		return -1
+	}
+	return int(name[4] - '1')
+}
+
+// quickOpen previews the i-th result, or opens it in the editor when it's
+// previewed already.
+func (m *Model) quickOpen(i int) tea.Cmd {
+	if i >= len(m.list.Items()) {
+		return nil
+	}
+	m.list.Select(i)
+	note := m.list.SelectedItem().(Note)
+	if m.preview != nil && m.previewPath == note.path && note.target() != "" {
+		return m.edit(note.target())
+	}
+	return m.openPreview(note.path)
+}
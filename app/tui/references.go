@@ -0,0 +1,102 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// footnoteDef matches footnote definitions, e.g. "[^1]: Knuth, 1984".
+	footnoteDef = regexp.MustCompile(`^\[\^([^\]\s]+)\]:\s*(.*)$`)
+	// linkDef matches reference link definitions, e.g.
+	// `[1]: https://example.com "Title"`.
+	linkDef = regexp.MustCompile(`^ {0,3}\[([^\]^][^\]]*)\]:\s*<?([^\s>]+)>?`)
+	// footnoteRef matches footnote references, e.g. "[^1]".
+	footnoteRef = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	// linkRef matches reference links, "[text][label]", "[text][]" and
+	// "[label]".
+	linkRef = regexp.MustCompile(`\[([^\[\]]+)\](?:\[([^\[\]]*)\])?`)
+)
+
+// resolveReferences inlines the footnotes and reference links of the
+// markdown content where they are used: "[^1]" becomes "[^1]^[text]" and
+// "[text][1]" becomes "[text](url)". The definitions stay where they are,
+// so the lines keep their numbers. Code fences are left alone.
+func resolveReferences(content string) string {
+	lines := strings.Split(content, "\n")
+	footnotes, links := map[string]string{}, map[string]string{}
+	definitions := map[int]bool{}
+	fenced := false
+	for i, line := range lines {
+		if isFence(line) {
+			fenced = !fenced
+		}
+		if fenced {
+			continue
+		}
+		if match := footnoteDef.FindStringSubmatch(line); match != nil {
+			footnotes[match[1]] = strings.TrimSpace(match[2])
+			definitions[i] = true
+		} else if match := linkDef.FindStringSubmatch(line); match != nil {
+			links[strings.ToLower(match[1])] = match[2]
+			definitions[i] = true
+		}
+	}
+	if len(footnotes) == 0 && len(links) == 0 {
+		return content
+	}
+
+	fenced = false
+	for i, line := range lines {
+		if isFence(line) {
+			fenced = !fenced
+		}
+		if fenced || definitions[i] {
+			continue
+		}
+		line = footnoteRef.ReplaceAllStringFunc(line, func(ref string) string {
+			if text, ok := footnotes[footnoteRef.FindStringSubmatch(ref)[1]]; ok {
+				return ref + "^[" + text + "]"
+			}
+			return ref
+		})
+		lines[i] = resolveLinks(line, links)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolveLinks turns the reference links of line with a definition in
+// links into inline links. Inline links, images and wiki links are left
+// alone.
+func resolveLinks(line string, links map[string]string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range linkRef.FindAllStringSubmatchIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		// "[[wiki]]", "[text](url)" and the footnotes aren't reference links.
+		if start > 0 && line[start-1] == '[' || end < len(line) && (line[end] == ']' || line[end] == '(') ||
+			strings.HasPrefix(line[start:], "[^") {
+			continue
+		}
+		text := line[loc[2]:loc[3]]
+		label := text
+		if loc[4] >= 0 && loc[5] > loc[4] {
+			label = line[loc[4]:loc[5]]
+		}
+		url, ok := links[strings.ToLower(label)]
+		if !ok {
+			continue
+		}
+		out.WriteString(line[last:start])
+		out.WriteString("[" + text + "](" + url + ")")
+		last = end
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+// isFence tells whether line opens or closes a code fence.
+func isFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// Steps of the search and replace mode.
+const (
+	replaceStepPattern     = iota // asking for the pattern
+	replaceStepReplacement        // asking for the replacement
+	replaceStepConfirm            // confirming each note
+)
+
+// replaceState is the search and replace mode over the current results.
+type replaceState struct {
+	step     int                 // current step
+	input    textinput.Model     // pattern / replacement input
+	pattern  string              // the text to replace
+	plans    []notes.Replacement // planned edits, one per note
+	current  int                 // index of the plan being confirmed
+	replaced int                 // notes written so far
+}
+
+// newReplaceState starts the replace mode by asking for the pattern.
+func newReplaceState() *replaceState {
+	input := create_text_input()
+	input.Prompt = tr("Replace:")
+	input.Placeholder = tr("text to replace")
+	return &replaceState{step: replaceStepPattern, input: input}
+}
+
+// updateReplace handles key presses while the replace mode is active.
+// Keys: enter - next step, y - replace in this note, n - skip it,
+// a - replace in all remaining notes, esc - stop.
+func (m Model) updateReplace(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.replace
+
+	if key.String() == "esc" || key.String() == "ctrl+c" {
+		return m.finishReplace(nil)
+	}
+
+	switch r.step {
+	case replaceStepPattern:
+		if key.String() == "enter" && r.input.Value() != "" {
+			r.pattern = r.input.Value()
+			r.input.Reset()
+			r.input.Prompt = tr("With:")
+			r.input.Placeholder = tr("replacement")
+			r.step = replaceStepReplacement
+			return m, nil
+		}
+	case replaceStepReplacement:
+		if key.String() == "enter" {
+			paths := lo.Map(m.list.Items(), func(item list.Item, _ int) string {
+				return item.(Note).path
+			})
+			plans, err := notes.PlanReplace(paths, r.pattern, r.input.Value(), false)
+			if err != nil {
+				m.status = tr("replace failed: %s", err)
+				m.replace = nil
+				return m, nil
+			}
+			if len(plans) == 0 {
+				m.status = tr("no occurrences of %s in the results", r.pattern)
+				m.replace = nil
+				return m, nil
+			}
+			r.plans = plans
+			r.step = replaceStepConfirm
+			return m, nil
+		}
+	case replaceStepConfirm:
+		switch key.String() {
+		case "y":
+			return m.applyReplace(1)
+		case "n":
+			r.current++
+		case "a":
+			return m.applyReplace(len(r.plans) - r.current)
+		}
+		if r.current >= len(r.plans) {
+			return m.finishReplace(nil)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	r.input, cmd = r.input.Update(key)
+	return m, cmd
+}
+
+// applyReplace writes the next n planned edits.
+func (m Model) applyReplace(n int) (tea.Model, tea.Cmd) {
+	r := m.replace
+	for i := 0; i < n && r.current < len(r.plans); i++ {
+		if err := r.plans[r.current].Apply(); err != nil {
+			return m.finishReplace(err)
+		}
+		r.replaced++
+		r.current++
+	}
+
+	if r.current >= len(r.plans) {
+		return m.finishReplace(nil)
+	}
+	return m, nil
+}
+
+// finishReplace leaves the replace mode and reindexes the touched notes.
+func (m Model) finishReplace(err error) (tea.Model, tea.Cmd) {
+	r := m.replace
+	m.replace = nil
+
+	if err != nil {
+		m.status = tr("replace failed: %s", err)
+	} else if r.replaced > 0 {
+		m.status = tr("replaced %s in %d notes", r.pattern, r.replaced)
+	}
+
+	if r.replaced == 0 {
+		return m, nil
+	}
+	return m, m.reindex()
+}
+
+// viewReplace renders the replace mode in place of the results.
+func (m Model) viewReplace() string {
+	r := m.replace
+	if r.step != replaceStepConfirm {
+		return r.input.View()
+	}
+
+	plan := r.plans[r.current]
+	lines := []string{
+		fmt.Sprintf("(%d/%d) %s", r.current+1, len(r.plans), filepath.ToSlash(plan.Path)),
+		"",
+	}
+	for _, o := range plan.Occurrences {
+		lines = append(lines,
+			theme.Removed.Render(fmt.Sprintf("%4d - %s", o.Line, o.Before)),
+			theme.Added.Render(fmt.Sprintf("%4d + %s", o.Line, o.After)),
+		)
+	}
+	lines = append(lines, "", tr("y replace  n skip  a replace all remaining  esc stop"))
+
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/noelzubin/notes_search/search/bleve_indexer"
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// This is emitted when the notes root can't be read
+type rootMissingMsg struct {
+	err error
+}
+
+// checkRoot reports with rootMissingMsg when the notes root can't be read,
+// e.g. because its drive isn't mounted. The daemon checks its own roots.
+func (m *Model) checkRoot() tea.Cmd {
+	if connectAddr != "" {
+		return nil
+	}
+	root := m.rootPath
+	return func() tea.Msg {
+		if err := bleve_indexer.Reachable(root); err != nil {
+			return rootMissingMsg{err}
+		}
+		return nil
+	}
+}
+
+// rootMissingStatus explains the missing notes root and what can be done.
+func rootMissingStatus(root string, err error) string {
+	if os.IsNotExist(err) {
+		return tr("notes root %s doesn't exist (unmounted drive?): r retry · c change root · esc ignore", root)
+	}
+	return tr("can't read the notes root %s: %s · r retry · c change root · esc ignore", root, err)
+}
+
+// updateMissingRoot answers what to do about the missing notes root:
+// r - check again and reindex if it's back, c - pick another root, any
+// other key leaves it be.
+func (m Model) updateMissingRoot(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.missingRoot = false
+	switch key.String() {
+	case "r":
+		if err := bleve_indexer.Reachable(m.rootPath); err != nil {
+			m.missingRoot = true
+			m.status = rootMissingStatus(m.rootPath, err)
+			return m, nil
+		}
+		m.status = tr("notes root %s is back", m.rootPath)
+		return m, m.reindex()
+	case "c":
+		m.status = ""
+		m.prompt = newPrompt(tr("Notes root:"), m.rootPath, func(m Model, value string) (Model, tea.Cmd) {
+			return m.changeRoot(value)
+		})
+		return m, textinput.Blink
+	}
+	m.status = ""
+	return m, nil
+}
+
+// changeRoot writes root as the notes root of the config and starts over
+// with it, reindexing.
+func (m Model) changeRoot(root string) (Model, tea.Cmd) {
+	root, err := filepath.Abs(expandHome(strings.TrimSpace(root)))
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(root); err == nil && !info.IsDir() {
+			m.status = tr("%s is not a folder", root)
+			return m, nil
+		}
+	}
+	if err != nil {
+		m.status = tr("can't use %s: %s", root, err)
+		return m, nil
+	}
+	if err := utils.SetRootPath(root); err != nil {
+		m.status = tr("can't write the config: %s", err)
+		return m, nil
+	}
+
+	config := utils.NewConfig()
+	// The reindex of the old root stops before its index is closed.
+	m.quitCancel()
+	m.indexer.CloseIndex()
+	indexer, err := newIndexer(config)
+	if err != nil {
+		m.indexer.OpenIndex()
+		m.status = tr("can't open the index: %s", err)
+		return m, nil
+	}
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	// The scheduled reindexes go on with the new model.
+	fresh := *New(indexer, config)
+	fresh.updateSize(m.width, m.height)
+	fresh.status = tr("notes root changed to %s", root)
+	return fresh, tea.Batch(fresh.emptyView(0), fresh.dashboardCmd(), fresh.waitForChanges(), fresh.reindex())
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// sendToState is the "send to" menu of the configured commands the
+// selected note can be piped to, followed by the actions run by their key.
+type sendToState struct {
+	path    string      // note to send
+	query   string      // query of the results, for {query} in actions
+	entries []menuEntry // commands of the menu, send to ones first
+	cursor  int         // selected command
+	running bool        // the command hasn't returned yet
+	output  string      // what the command printed, shown until a key is pressed
+}
+
+// menuEntry is a command of the send to menu.
+type menuEntry struct {
+	name    string // name of the send to command, key of the action
+	command string
+	action  bool // run with placeholders rather than fed the note
+}
+
+// This is emitted when a send to command or an action returned
+type sentMsg struct {
+	path   string
+	name   string
+	output string
+	err    error
+	action bool
+}
+
+// newSendToState opens the menu of commands and actions for the note at
+// path, both sorted.
+func newSendToState(path, query string, commands, actions map[string]string) *sendToState {
+	entries := lo.MapToSlice(commands, func(name, command string) menuEntry { return menuEntry{name: name, command: command} })
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	keys := lo.Keys(actions)
+	sort.Strings(keys)
+	for _, key := range keys {
+		entries = append(entries, menuEntry{name: key, command: actions[key], action: true})
+	}
+	return &sendToState{path: path, query: query, entries: entries}
+}
+
+// updateSendTo handles key presses of the send to menu.
+// Keys: up/down or tab/shift+tab - move, enter - run the command,
+// esc - close, the key of an action - run it. Once the output is shown
+// any key closes it.
+func (m Model) updateSendTo(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.sendTo
+	if s.output != "" || s.running {
+		if !s.running || key.String() == "esc" {
+			m.sendTo = nil
+		}
+		return m, nil
+	}
+
+	switch key.String() {
+	case "esc", "alt+z":
+		m.sendTo = nil
+	case "ctrl+c":
+		return m.quit()
+	case "up", "shift+tab":
+		s.cursor = lo.Max([]int{s.cursor - 1, 0})
+	case "down", "tab":
+		s.cursor = lo.Min([]int{s.cursor + 1, len(s.entries) - 1})
+	case "enter":
+		return m, s.run(s.entries[s.cursor])
+	default:
+		if entry, ok := lo.Find(s.entries, func(e menuEntry) bool { return e.action && e.name == key.String() }); ok {
+			return m, s.run(entry)
+		}
+	}
+	return m, nil
+}
+
+// run runs the command of the entry on the note in the background and
+// reports with sentMsg.
+func (s *sendToState) run(entry menuEntry) tea.Cmd {
+	s.running = true
+	return runEntry(entry, s.path, s.query)
+}
+
+// runEntry runs the command of the entry on the note at path in the
+// background and reports with sentMsg.
+func runEntry(entry menuEntry, path, query string) tea.Cmd {
+	return func() tea.Msg {
+		var output string
+		var err error
+		if entry.action {
+			output, err = runAction(entry.command, path, query)
+		} else {
+			output, err = runSendTo(entry.command, path)
+		}
+		return sentMsg{path: path, name: entry.name, output: output, err: err, action: entry.action}
+	}
+}
+
+// sent shows how the send to command or the action went: its output in
+// the menu, or a status when it printed nothing. The note is indexed again
+// after an action, which may have changed it.
+func (m Model) sent(msg sentMsg) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg.action {
+		cmd = m.indexChanged(msg.path)
+	}
+	if m.sendTo == nil || m.sendTo.path != msg.path {
+		m.sendTo = nil
+	}
+	switch {
+	case msg.err != nil:
+		m.sendTo = nil
+		m.status = tr("%s failed: %s", msg.name, msg.err)
+	case msg.output == "" || m.sendTo == nil:
+		m.sendTo = nil
+		m.status = tr("sent %s to %s", filepath.Base(msg.path), msg.name)
+	default:
+		m.sendTo.running = false
+		m.sendTo.output = msg.output
+		m.status = tr("sent %s to %s", filepath.Base(msg.path), msg.name)
+	}
+	if msg.action && msg.err == nil {
+		m.status = tr("ran %s on %s", msg.name, filepath.Base(msg.path))
+	}
+	return m, cmd
+}
+
+// boundKeys are the keys of the results and the query input, which
+// actions can't take.
+var boundKeys = []string{
+	"tab", "shift+tab", "enter", "esc", "up", "down", "left", "right", "pgup", "pgdown", "home", "end",
+	"backspace", "delete", "ctrl+a", "ctrl+b", "ctrl+c", "ctrl+d", "ctrl+e", "ctrl+f", "ctrl+g", "ctrl+h",
+	"ctrl+j", "ctrl+k", "ctrl+l", "ctrl+n", "ctrl+o", "ctrl+p", "ctrl+r", "ctrl+s", "ctrl+t", "ctrl+u",
+	"ctrl+v", "ctrl+w", "ctrl+x", "ctrl+y", "ctrl+z", "alt+a", "alt+b", "alt+c", "alt+d", "alt+e",
+	"alt+f", "alt+g", "alt+h", "alt+i", "alt+j", "alt+k", "alt+l", "alt+m", "alt+n", "alt+o", "alt+p",
+	"alt+q", "alt+r", "alt+s", "alt+t", "alt+u", "alt+v", "alt+w", "alt+x", "alt+y", "alt+z", "alt+#",
+	"alt+]", "alt+~", "alt+@", "alt+up", "alt+down", "alt+left", "alt+right", "alt+enter",
+	"alt+backspace", "alt+delete", "alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8",
+	"alt+9",
+}
+
+// boundActions returns the actions run by their key from the results:
+// those of a ctrl+ or alt+ key that isn't taken. Plain keys would be typed
+// into the query, so they, like the taken ones, only run from the alt+z
+// menu.
+func boundActions(actions map[string]string) map[string]string {
+	bound := map[string]string{}
+	for key, command := range actions {
+		if !strings.HasPrefix(key, "ctrl+") && !strings.HasPrefix(key, "alt+") {
+			continue
+		}
+		if lo.Contains(boundKeys, key) {
+			slog.Warn("the key of the action is taken, it only runs from the alt+z menu", "key", key)
+			continue
+		}
+		bound[key] = command
+	}
+	return bound
+}
+
+// runActionKey runs the action bound to key on the selected note,
+// reporting in the status line.
+func (m Model) runActionKey(key, command string) (Model, tea.Cmd) {
+	path, query := m.list.SelectedItem().(Note).path, m.textInput.Value()
+	return m.confirmPrivate([]string{path}, func(m Model) (Model, tea.Cmd) {
+		m.status = tr("running %s…", key)
+		return m, runEntry(menuEntry{name: key, command: command, action: true}, path, query)
+	})
+}
+
+// Time a send to command or an action may run before it's killed.
+const sendToTimeout = 2 * time.Minute
+
+// runSendTo runs the command on the note at path and returns what it
+// printed. {path} in the command is replaced by the path of the note,
+// the note is piped in otherwise.
+func runSendTo(command, path string) (string, error) {
+	args := utils.SplitCommand(command)
+	if len(args) == 0 {
+		return "", errors.New(tr("empty command"))
+	}
+
+	var stdin *os.File
+	if strings.Contains(command, "{path}") {
+		for i, arg := range args {
+			args[i] = strings.ReplaceAll(arg, "{path}", path)
+		}
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		stdin = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendToTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	return output(ctx, cmd)
+}
+
+// runAction runs the command of an action on the note at path and returns
+// what it printed. {file}, {dir} and {query} in the command are replaced by
+// the path of the note, its folder and the query.
+func runAction(command, path, query string) (string, error) {
+	args := utils.SplitCommand(command)
+	if len(args) == 0 {
+		return "", errors.New(tr("empty command"))
+	}
+	replacer := strings.NewReplacer("{file}", path, "{dir}", filepath.Dir(path), "{query}", query)
+	for i, arg := range args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendToTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(path)
+	return output(ctx, cmd)
+}
+
+// output runs cmd and returns what it printed, or what it complained
+// about when it failed. ctx is the one cmd was made with.
+func output(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", errors.New(tr("timed out after %s", sendToTimeout))
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// viewSendTo renders the send to menu, or the output of the command, in
+// place of the results.
+func (m Model) viewSendTo() string {
+	s := m.sendTo
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	lines := []string{theme.Status.Copy().UnsetPaddingLeft().Bold(true).Render(tr("Send %s to", m.relPath(s.path))), ""}
+	switch {
+	case s.output != "":
+		output := strings.Split(s.output, "\n")
+		lines = append(lines, output[:lo.Min([]int{len(output), lo.Max([]int{m.height - 7, 1})})]...)
+		lines = append(lines, "", faint.Render(tr("press any key to close")))
+	default:
+		for i, entry := range s.entries {
+			name := lo.Ternary(entry.action, "["+entry.name+"]", entry.name)
+			line := "  " + name + "  " + faint.Render(entry.command)
+			if i == s.cursor {
+				line = theme.matchStyle(0).Render("› "+name) + "  " + faint.Render(entry.command)
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, "", faint.Render(lo.Ternary(s.running, tr("running…"), tr("enter send · [key] run the action · esc cancel"))))
+	}
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// setupModel is the wizard run when there is no config file yet: it asks
+// for the notes root, the editor and the extensions, writes the config and
+// indexes the notes.
+type setupModel struct {
+	inputs   []textinput.Model    // notes root, editor and extensions
+	labels   []string             // what each input asks for
+	step     int                  // input being answered, len(inputs) once indexing
+	err      string               // why the answer was refused
+	indexer  search.NotesIndexer  // the indexer of the new config, nil until written
+	statuses []search.IndexStatus // progress of the initial index
+	done     bool                 // the notes are indexed
+}
+
+// This is emitted when the initial index is done
+type setupIndexedMsg struct {
+	statuses []search.IndexStatus
+}
+
+// newSetupModel starts the wizard with suggested answers.
+func newSetupModel() setupModel {
+	homedir, _ := os.UserHomeDir()
+	editorCmd := os.Getenv("VISUAL")
+	if editorCmd == "" {
+		editorCmd = os.Getenv("EDITOR")
+	}
+	values := []string{filepath.Join(homedir, "notes"), lo.Ternary(editorCmd == "", "vim", editorCmd), ".md"}
+	s := setupModel{labels: []string{tr("Notes folder:"), tr("Editor:"), tr("Extensions:")}}
+	for _, value := range values {
+		input := textinput.New()
+		input.Prompt = ""
+		input.SetValue(value)
+		input.CursorEnd()
+		s.inputs = append(s.inputs, input)
+	}
+	s.inputs[0].Focus()
+	return s
+}
+
+func (s setupModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles the wizard's keys: enter - next question, shift+tab or
+// up - previous one, esc or ctrl+c - leave without writing the config.
+// Once the notes are indexed any key starts the TUI.
+func (s setupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if s.step == len(s.inputs) {
+			// Indexing, any key leaves once it's done.
+			if s.done || msg.String() == "ctrl+c" {
+				return s, tea.Quit
+			}
+			return s, nil
+		}
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			return s, tea.Quit
+		case "shift+tab", "up":
+			if s.step > 0 {
+				s.focus(s.step - 1)
+			}
+			return s, nil
+		case "enter", "tab", "down":
+			if err := s.check(s.step); err != nil {
+				s.err = err.Error()
+				return s, nil
+			}
+			s.err = ""
+			if s.step < len(s.inputs)-1 {
+				s.focus(s.step + 1)
+				return s, nil
+			}
+			return s.finish()
+		}
+	case setupIndexedMsg:
+		s.statuses, s.done = msg.statuses, true
+		return s, nil
+	case indexProgressMsg:
+		s.statuses = msg.statuses
+		if !s.done {
+			return s, s.pollIndex()
+		}
+		return s, nil
+	}
+
+	if s.step < len(s.inputs) {
+		var cmd tea.Cmd
+		s.inputs[s.step], cmd = s.inputs[s.step].Update(msg)
+		return s, cmd
+	}
+	return s, nil
+}
+
+// focus moves to the i-th question.
+func (s *setupModel) focus(i int) {
+	s.inputs[s.step].Blur()
+	s.step = i
+	s.inputs[i].Focus()
+}
+
+// check validates the answer to the i-th question.
+func (s setupModel) check(i int) error {
+	value := strings.TrimSpace(s.inputs[i].Value())
+	switch i {
+	case 0:
+		if value == "" {
+			return errors.New(tr("the notes folder is required"))
+		}
+		if info, err := os.Stat(expandHome(value)); err == nil && !info.IsDir() {
+			return errors.New(tr("%s is not a folder", value))
+		}
+	case 2:
+		if len(s.extensions()) == 0 {
+			return errors.New(tr("at least one extension is required, e.g. .md"))
+		}
+	}
+	return nil
+}
+
+// extensions returns the extensions answered, with their dots.
+func (s setupModel) extensions() []string {
+	fields := strings.FieldsFunc(s.inputs[2].Value(), func(r rune) bool { return r == ',' || r == ' ' })
+	return lo.Map(fields, func(ext string, _ int) string { return "." + strings.TrimLeft(ext, ".") })
+}
+
+// finish creates the notes folder if need be, writes the config and starts
+// indexing the notes.
+func (s setupModel) finish() (tea.Model, tea.Cmd) {
+	root, err := filepath.Abs(expandHome(strings.TrimSpace(s.inputs[0].Value())))
+	if err == nil {
+		err = os.MkdirAll(root, 0755)
+	}
+	if err == nil {
+		err = utils.WriteConfig(root, strings.TrimSpace(s.inputs[1].Value()), s.extensions())
+	}
+	if err != nil {
+		s.err = tr("can't write the config: %s", err)
+		return s, nil
+	}
+
+	config := utils.NewConfig()
+	indexer, err := newIndexer(config)
+	if err != nil {
+		s.err = tr("can't open the index: %s", err)
+		return s, nil
+	}
+	s.inputs[s.step].Blur()
+	s.step = len(s.inputs)
+	s.indexer = indexer
+	return s, tea.Batch(func() tea.Msg {
+		indexer.IndexNotes(context.Background())
+		return setupIndexedMsg{statuses: indexer.IndexStatus()}
+	}, s.pollIndex())
+}
+
+// pollIndex reports the progress of the initial index with
+// indexProgressMsg.
+func (s setupModel) pollIndex() tea.Cmd {
+	indexer := s.indexer
+	return tea.Tick(200*time.Millisecond, func(time.Time) tea.Msg {
+		return indexProgressMsg{statuses: indexer.IndexStatus()}
+	})
+}
+
+func (s setupModel) View() string {
+	heading := theme.Status.Copy().UnsetPaddingLeft().Bold(true)
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	lines := []string{heading.Render(tr("Welcome to notes_search")), faint.Render(tr("No config yet, a few questions to write %s:", utils.ConfigPath())), ""}
+	for i, input := range s.inputs {
+		label := fmt.Sprintf("  %-14s", s.labels[i])
+		if i == s.step {
+			label = theme.matchStyle(0).Render(fmt.Sprintf("› %-14s", s.labels[i]))
+		}
+		lines = append(lines, label+" "+input.View())
+	}
+	if s.err != "" {
+		lines = append(lines, "", theme.Error.Render(s.err))
+	}
+
+	switch {
+	case s.done:
+		lines = append(lines, "", indexSummary(s.statuses), "", faint.Render(tr("press any key to start searching")))
+	case s.step == len(s.inputs):
+		lines = append(lines, "", lo.Ternary(len(s.statuses) > 0, indexSummary(s.statuses), tr("indexing…")))
+	default:
+		lines = append(lines, "", faint.Render(tr("enter next · shift+tab back · esc quit")))
+	}
+	return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
+
+// runSetup runs the wizard and reports whether the TUI should start: the
+// config is written and the notes indexed. The index it opened is closed
+// again for the TUI.
+func runSetup() (bool, error) {
+	final, err := tea.NewProgram(newSetupModel(), tea.WithAltScreen()).Run()
+	if err != nil {
+		return false, err
+	}
+	s := final.(setupModel)
+	if !s.done {
+		return false, nil
+	}
+	s.indexer.CloseIndex()
+	return true, nil
+}
+
+// firstRun runs the wizard with the default look and logging, and
+// reports whether the TUI should start.
+func firstRun() bool {
+	setupTheme("", "", "auto")
+	setupLocale("")
+	if err := os.MkdirAll(utils.ConfigDir(), 0755); err != nil {
+		log.Fatal(err)
+	}
+	f, err := utils.SetupLog(filepath.Join(utils.ConfigDir(), "debug.log"), "info")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	ok, err := runSetup()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return ok
+}
+
+// expandHome replaces a leading ~ of path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homedir, _ := os.UserHomeDir()
+	return filepath.Join(homedir, path[1:])
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// emptyView lists what start_view asks for while the query is empty: the
+// recently modified notes, the pinned ones, the results of start_query or
+// all of them.
+func (m Model) emptyView(queryId int) tea.Cmd {
+	indexer, view, pinned, ctx := m.indexer, m.startView, m.pinned, m.searchCtx
+	startQuery := utils.ExpandMacros(m.startQuery, m.macros)
+	return func() tea.Msg {
+		var hits []search.DocumentMatch
+		switch view {
+		case "pinned":
+			hits = pinnedHits(pinned)
+		case "stale":
+			result := indexer.Search(ctx, "is:stale")
+			if result.Err != nil {
+				return ResultMsg{results: result, queryId: queryId}
+			}
+			hits = result.Hits
+		case "search":
+			result := indexer.Search(ctx, startQuery)
+			if result.Err != nil {
+				return ResultMsg{results: result, queryId: queryId}
+			}
+			hits = result.Hits
+		case "dashboard":
+			hits = pinnedHits(pinned)
+			if strings.TrimSpace(startQuery) != "" {
+				result := indexer.Search(ctx, startQuery)
+				if result.Err != nil {
+					slog.Warn("the start query failed", "query", startQuery, "err", result.Err)
+				}
+				hits = append(hits, result.Hits...)
+			}
+			hits = append(hits, indexer.Search(ctx, "").Hits...)
+			hits = lo.UniqBy(hits, func(hit search.DocumentMatch) string { return hit.Path })
+		default:
+			return ResultMsg{results: indexer.Search(ctx, ""), queryId: queryId}
+		}
+		return ResultMsg{results: search.SearchResult{Hits: hits}, queryId: queryId}
+	}
+}
+
+// pinnedHits lists the pinned notes that exist, in the configured order.
+func pinnedHits(pinned []string) []search.DocumentMatch {
+	hits := []search.DocumentMatch{}
+	for _, path := range pinned {
+		if _, err := os.Stat(path); err != nil {
+			slog.Warn("pinned note is missing", "path", path)
+			continue
+		}
+		hits = append(hits, fileHit(path))
+	}
+	return hits
+}
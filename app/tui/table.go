@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/samber/lo"
+)
+
+// tableDelimiter matches the row under the header of a markdown table,
+// e.g. "| --- | :-: | --: |".
+var tableDelimiter = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// isMarkdown tells whether the note at path is markdown.
+func isMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// renderTables draws the pipe tables of the markdown content with aligned
+// columns and borders. Tables in code fences are left alone.
+func renderTables(content string) string {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+	fenced := false
+	for i := 0; i < len(lines); i++ {
+		if isFence(lines[i]) {
+			fenced = !fenced
+		}
+		if fenced || !strings.Contains(lines[i], "|") || i+1 >= len(lines) ||
+			!strings.Contains(lines[i+1], "-") || !tableDelimiter.MatchString(lines[i+1]) {
+			out = append(out, lines[i])
+			continue
+		}
+
+		header := tableCells(lines[i])
+		aligns := tableCells(lines[i+1])
+		if len(header) != len(aligns) {
+			out = append(out, lines[i])
+			continue
+		}
+		rows := [][]string{header}
+		end := i + 2
+		for ; end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != ""; end++ {
+			rows = append(rows, tableCells(lines[end]))
+		}
+		out = append(out, drawTable(rows, aligns)...)
+		i = end - 1
+	}
+	return strings.Join(out, "\n")
+}
+
+// tableCells splits a table row into its trimmed cells, keeping escaped
+// pipes in them.
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if !strings.HasSuffix(row, `\|`) {
+		row = strings.TrimSuffix(row, "|")
+	}
+	cells := []string{}
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// drawTable draws the rows, the first being the header, with box drawing
+// borders. aligns are the delimiter cells giving the column alignments.
+func drawTable(rows [][]string, aligns []string) []string {
+	widths := make([]int, len(aligns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = lo.Max([]int{widths[i], runewidth.StringWidth(cell)})
+			}
+		}
+	}
+
+	border := func(left, middle, right string) string {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat("─", width+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+	line := func(row []string) string {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			parts[i] = " " + alignCell(cell, width, aligns[i]) + " "
+		}
+		return "│" + strings.Join(parts, "│") + "│"
+	}
+
+	lines := []string{border("┌", "┬", "┐"), line(rows[0]), border("├", "┼", "┤")}
+	for _, row := range rows[1:] {
+		lines = append(lines, line(row))
+	}
+	return append(lines, border("└", "┴", "┘"))
+}
+
+// alignCell pads cell to width as the delimiter cell says: ":-:" centers,
+// "-:" aligns right, anything else left.
+func alignCell(cell string, width int, delimiter string) string {
+	pad := width - runewidth.StringWidth(cell)
+	switch {
+	case strings.HasPrefix(delimiter, ":") && strings.HasSuffix(delimiter, ":"):
+		return strings.Repeat(" ", pad/2) + cell + strings.Repeat(" ", pad-pad/2)
+	case strings.HasSuffix(delimiter, ":"):
+		return strings.Repeat(" ", pad) + cell
+	}
+	return cell + strings.Repeat(" ", pad)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// tagFacetState lists the tags of the results with the number of notes
+// having each, in place of the results. Picking one narrows the query to it.
+type tagFacetState struct {
+	query  string            // query the tags are counted over
+	tags   []search.TagCount // nil until counted
+	err    error
+	cursor int // selected tag
+	offset int // first tag shown
+}
+
+// This is emitted when the tags of the results were counted
+type tagsCountedMsg struct {
+	tags []search.TagCount
+	err  error
+}
+
+// showTagFacets counts the tags of the notes matching the query, filters
+// included.
+func (m *Model) showTagFacets() tea.Cmd {
+	query := m.withFilters(utils.ExpandMacros(m.textInput.Value(), m.macros))
+	m.tagFacets = &tagFacetState{query: query}
+	indexer, ctx := m.indexer, m.ctx
+	return func() tea.Msg {
+		tags, err := indexer.Tags(ctx, query)
+		return tagsCountedMsg{tags: tags, err: err}
+	}
+}
+
+// updateTagFacets handles key presses while the tags are listed.
+// Keys: up/down - move, enter - add tag: to the query, esc or alt+# - close.
+func (m Model) updateTagFacets(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.tagFacets
+	switch key.String() {
+	case "esc", "alt+#":
+		m.tagFacets = nil
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	case "up", "k", "shift+tab":
+		f.cursor--
+	case "down", "j", "tab":
+		f.cursor++
+	case "pgup":
+		f.cursor -= m.tagRows()
+	case "pgdown":
+		f.cursor += m.tagRows()
+	case "enter":
+		if len(f.tags) == 0 {
+			return m, nil
+		}
+		m.tagFacets = nil
+		query := strings.TrimRight(m.textInput.Value(), " ")
+		query = strings.TrimLeft(query+" tag:"+f.tags[f.cursor].Tag+" ", " ")
+		m.textInput.SetValue(query)
+		m.textInput.CursorEnd()
+		return m, m.search(query)
+	}
+	f.cursor = lo.Clamp(f.cursor, 0, lo.Max([]int{len(f.tags) - 1, 0}))
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	}
+	if f.cursor >= f.offset+m.tagRows() {
+		f.offset = f.cursor - m.tagRows() + 1
+	}
+	return m, nil
+}
+
+// tagRows returns how many tags fit on the screen.
+func (m Model) tagRows() int {
+	return lo.Max([]int{m.height - 7, 1})
+}
+
+// viewTagFacets renders the tags with their counts and a bar of the
+// share of the most used one.
+func (m Model) viewTagFacets() string {
+	f := m.tagFacets
+	faint := theme.Status.Copy().UnsetPaddingLeft()
+	title := tr("Tags of all the notes")
+	if strings.TrimSpace(f.query) != "" {
+		title = tr("Tags of the notes matching %s", strings.TrimSpace(f.query))
+	}
+	lines := []string{faint.Copy().Bold(true).Render(title), ""}
+
+	switch {
+	case f.err != nil:
+		lines = append(lines, theme.Error.Render(tr("can't count the tags: %s", f.err)))
+	case f.tags == nil:
+		lines = append(lines, faint.Render(tr("counting…")))
+	case len(f.tags) == 0:
+		lines = append(lines, faint.Render(tr("no tagged notes")))
+	default:
+		width := lo.Max(lo.Map(f.tags, func(t search.TagCount, _ int) int { return lipgloss.Width(t.Tag) }))
+		digits := len(strconv.Itoa(f.tags[0].Count))
+		shown := f.tags[f.offset:lo.Min([]int{f.offset + m.tagRows(), len(f.tags)})]
+		for i, tag := range shown {
+			bar := strings.Repeat("▪", lo.Max([]int{tag.Count * 20 / f.tags[0].Count, 1}))
+			count := strings.Repeat(" ", digits-len(strconv.Itoa(tag.Count))) + strconv.Itoa(tag.Count)
+			line := "  " + pad(tag.Tag, width) + "  " + count + " " + faint.Render(bar)
+			if f.offset+i == f.cursor {
+				line = theme.matchStyle(0).Render("› "+pad(tag.Tag, width)) + "  " + count + " " + faint.Render(bar)
+			}
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, "", faint.Render(tr("enter add tag: to the query · ↑↓ move · esc close")))
+	return lipgloss.NewStyle().PaddingLeft(2).Height(m.height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// tagsCounted shows the counted tags, unless the list was closed meanwhile.
+func (m Model) tagsCounted(msg tagsCountedMsg) Model {
+	if m.tagFacets == nil {
+		return m
+	}
+	m.tagFacets.tags, m.tagFacets.err = msg.tags, msg.err
+	if msg.err == nil && msg.tags == nil {
+		m.tagFacets.tags = []search.TagCount{}
+	}
+	return m
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// tagEditState is the bulk tag editing mode over the marked notes.
+// The tags are typed as "+add -remove", then a dry run of the edits is
+// shown for confirmation.
+type tagEditState struct {
+	input textinput.Model // the tags to add and remove
+	paths []string        // notes to edit
+	edits []notes.TagEdit // dry run of the edits, nil until planned
+}
+
+// newTagEditState starts editing the tags of the given notes.
+func newTagEditState(paths []string) *tagEditState {
+	input := create_text_input()
+	input.Prompt = tr("Tags:")
+	input.Placeholder = tr("+add -remove")
+	return &tagEditState{input: input, paths: paths}
+}
+
+// parseTagChanges splits "+a -b c" into tags to add and to remove.
+// Tags without a sign are added.
+func parseTagChanges(input string) (add, remove []string) {
+	for _, field := range strings.Fields(input) {
+		switch {
+		case strings.HasPrefix(field, "-"):
+			remove = append(remove, strings.TrimPrefix(field, "-"))
+		default:
+			add = append(add, strings.TrimPrefix(field, "+"))
+		}
+	}
+	return add, remove
+}
+
+// updateTagEdit handles key presses while the tag editing mode is active.
+// Keys: enter - preview the edits, y - apply them, esc - cancel.
+func (m Model) updateTagEdit(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.tagEdit
+
+	switch key.String() {
+	case "esc", "ctrl+c":
+		m.tagEdit = nil
+		return m, nil
+	case "enter":
+		if t.edits == nil {
+			add, remove := parseTagChanges(t.input.Value())
+			edits, err := notes.PlanTagEdit(t.paths, add, remove)
+			if err != nil {
+				m.status = tr("tag edit failed: %s", err)
+				m.tagEdit = nil
+				return m, nil
+			}
+			t.edits = lo.Filter(edits, func(e notes.TagEdit, _ int) bool { return e.Changed() })
+			if len(t.edits) == 0 {
+				m.status = tr("tags unchanged")
+				m.tagEdit = nil
+			}
+			return m, nil
+		}
+	case "y":
+		if t.edits != nil {
+			m.tagEdit = nil
+			for _, edit := range t.edits {
+				if err := edit.Apply(); err != nil {
+					m.status = tr("tag edit failed: %s", err)
+					return m, m.reindex()
+				}
+			}
+			m.status = tr("updated tags of %d notes", len(t.edits))
+			m.selected = map[string]bool{}
+			m.refreshSelection()
+			return m, m.reindex()
+		}
+	}
+
+	if t.edits != nil {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	t.input, cmd = t.input.Update(key)
+	return m, cmd
+}
+
+// viewTagEdit renders the tag editing mode in place of the results.
+func (m Model) viewTagEdit() string {
+	t := m.tagEdit
+	if t.edits == nil {
+		return t.input.View() + "\n\n" + theme.Status.Render(tr("editing tags of %d notes", len(t.paths)))
+	}
+
+	lines := []string{tr("dry run:"), ""}
+	for _, e := range t.edits {
+		line := fmt.Sprintf("%s  [%s] → [%s]", filepath.ToSlash(e.Path), strings.Join(e.Before, ", "), strings.Join(e.After, ", "))
+		if e.Created {
+			line += tr(" (new frontmatter)")
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", tr("y apply  esc cancel"))
+
+	return lipgloss.NewStyle().PaddingLeft(2).Render(strings.Join(lines, "\n"))
+}
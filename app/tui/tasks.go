@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/samber/lo"
+)
+
+// moveTaskCursor moves the cursor to the next or previous task item of the
+// preview, wrapping around, and scrolls it into view.
+func (m *Model) moveTaskCursor(down bool) {
+	tasks := notes.Tasks(m.previewSource())
+	if len(tasks) == 0 {
+		m.status = tr("no tasks in the preview")
+		return
+	}
+
+	i := lo.IndexOf(tasks, m.taskLine)
+	switch {
+	case i < 0 && down:
+		i = 0
+	case i < 0:
+		i = len(tasks) - 1
+	case down:
+		i = (i + 1) % len(tasks)
+	default:
+		i = (i - 1 + len(tasks)) % len(tasks)
+	}
+	m.taskLine = tasks[i]
+	m.renderPreview()
+	m.scrollToTask()
+}
+
+// scrollToTask scrolls the preview so the line under the task cursor is
+// shown, taking wrapped lines into account.
+func (m *Model) scrollToTask() {
+	vp := &m.preview.Viewport
+	width := lo.Max([]int{vp.Width, 1})
+	row := 0
+	for _, line := range strings.Split(m.preview.HighlightedContent, "\n")[:m.taskLine] {
+		if m.noWrap {
+			row++
+		} else {
+			row += lo.Max([]int{1, (lipgloss.Width(line) + width - 1) / width})
+		}
+	}
+	if row < vp.YOffset || row >= vp.YOffset+vp.Height {
+		vp.SetYOffset(lo.Max([]int{row - vp.Height/2, 0}))
+	}
+}
+
+// markTask shows the line of content under the task cursor reversed.
+func (m *Model) markTask(content string) string {
+	lines := strings.Split(content, "\n")
+	if m.taskLine < 0 || m.taskLine >= len(lines) {
+		return content
+	}
+	line := sgr.ReplaceAllString(lines[m.taskLine], "")
+	lines[m.taskLine] = lipgloss.NewStyle().Reverse(true).Render(line)
+	return strings.Join(lines, "\n")
+}
+
+// toggleTask checks or unchecks the task item under the cursor in the
+// note, then reloads the preview and indexes the note.
+func (m *Model) toggleTask() tea.Cmd {
+	n := lo.IndexOf(notes.Tasks(m.previewSource()), m.taskLine)
+	if n < 0 {
+		m.status = tr("move to a task with alt+up and alt+down first")
+		return nil
+	}
+
+	path := m.previewPath
+	checked, err := notes.ToggleTask(path, n)
+	if err != nil {
+		m.status = tr("toggling the task failed: %s", err)
+		return nil
+	}
+	if checked {
+		m.status = tr("checked a task in %s", filepath.Base(path))
+	} else {
+		m.status = tr("unchecked a task in %s", filepath.Base(path))
+	}
+
+	line := m.taskLine
+	cmd := m.openPreview(path)
+	m.taskLine = line
+	return tea.Batch(cmd, m.indexChanged(path))
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/alecthomas/chroma/styles"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the styles of everything the app colours.
+type Theme struct {
+	Prompt  lipgloss.Style   // the search prompt
+	Text    lipgloss.Style   // the query
+	Error   lipgloss.Style   // the query when it is invalid
+	Matches []lipgloss.Style // highlighted query terms, one style per term
+	Snippet lipgloss.Style   // the rest of the snippet
+	Status  lipgloss.Style   // the status line
+	Added   lipgloss.Style   // added lines in diffs
+	Removed lipgloss.Style   // removed lines in diffs
+
+	SyntaxTheme string                 // chroma style of the preview
+	Border      lipgloss.AdaptiveColor // border of the preview
+	NoColor     bool                   // widgets should drop their own colours too
+}
+
+// The theme in use, set from the config on startup.
+var theme = newTheme("default", true)
+
+// themeConfig is what the theme was set up from, kept for switching the
+// background.
+var themeConfig struct {
+	name, syntaxTheme string
+	dark              bool
+}
+
+// newTheme returns the named theme for a dark or light background, the
+// default one for unknown names.
+//
+//	high-contrast  black/white with bold and reversed highlights, for light
+//	               and dark terminals alike
+//	colorblind     blue/orange instead of red/green/pink
+//	none           no colours at all, highlights use bold and underline
+func newTheme(name string, dark bool) Theme {
+	base := lipgloss.NewStyle()
+	prompt := base.MarginRight(1).MarginLeft(2).Padding(0, 1)
+	status := base.PaddingLeft(2)
+	fg := lipgloss.AdaptiveColor{Light: "0", Dark: "15"}
+	border := lipgloss.AdaptiveColor{Light: "#000000", Dark: "#ffffff"}
+	syntax := "dracula"
+	if !dark {
+		syntax = "github"
+	}
+
+	switch name {
+	case "high-contrast":
+		return Theme{
+			Prompt: prompt.Reverse(true).Bold(true),
+			Text:   base.Foreground(fg),
+			Error:  base.Foreground(fg).Underline(true).Bold(true),
+			Matches: []lipgloss.Style{
+				base.Reverse(true).Bold(true),
+				base.Reverse(true).Underline(true),
+				base.Bold(true).Underline(true),
+				base.Reverse(true).Italic(true),
+			},
+			Snippet: base.Foreground(fg),
+			Status:  status.Foreground(fg).Bold(true),
+			Added:   base.Foreground(fg).Bold(true),
+			Removed: base.Foreground(fg).Strikethrough(true),
+
+			SyntaxTheme: "bw",
+			Border:      border,
+		}
+	case "colorblind":
+		return Theme{
+			Prompt:  prompt.Background(lipgloss.Color("33")).Foreground(lipgloss.Color("15")),
+			Text:    base.Foreground(fg),
+			Error:   base.Foreground(lipgloss.Color("208")).Underline(true),
+			Matches: colors(base.Bold(true), "33", "208", "220", "45", "170"),
+			Snippet: base.Foreground(lipgloss.Color("245")),
+			Status:  status.Foreground(lipgloss.Color("245")),
+			Added:   base.Foreground(lipgloss.Color("33")),
+			Removed: base.Foreground(lipgloss.Color("208")),
+
+			SyntaxTheme: syntax,
+			Border:      border,
+		}
+	case "none":
+		return Theme{
+			Prompt: prompt.Reverse(true),
+			Text:   base,
+			Error:  base.Underline(true),
+			Matches: []lipgloss.Style{
+				base.Bold(true).Underline(true),
+				base.Bold(true),
+				base.Underline(true),
+				base.Italic(true),
+			},
+			Snippet: base,
+			Status:  status,
+			Added:   base.Bold(true),
+			Removed: base.Strikethrough(true),
+
+			SyntaxTheme: "bw",
+			Border:      border,
+			NoColor:     true,
+		}
+	}
+
+	return Theme{
+		Prompt:  prompt.Background(lipgloss.Color("62")).Foreground(lipgloss.Color("230")),
+		Text:    base.Foreground(lipgloss.AdaptiveColor{Light: "235", Dark: "255"}),
+		Error:   base.Foreground(lipgloss.Color("9")),
+		Matches: colors(base, "205", "214", "39", "118", "141", "226"),
+		Snippet: base.Foreground(lipgloss.Color("242")),
+		Status:  status.Foreground(lipgloss.Color("242")),
+		Added:   base.Foreground(lipgloss.Color("10")),
+		Removed: base.Foreground(lipgloss.Color("9")),
+
+		SyntaxTheme: syntax,
+		Border:      border,
+	}
+}
+
+// colors returns a copy of style per foreground colour.
+func colors(style lipgloss.Style, colors ...string) []lipgloss.Style {
+	styles := make([]lipgloss.Style, len(colors))
+	for i, color := range colors {
+		styles[i] = style.Copy().Foreground(lipgloss.Color(color))
+	}
+	return styles
+}
+
+// matchStyle returns the highlight of the i-th query term.
+// The styles repeat when there are more terms than styles.
+func (t Theme) matchStyle(i int) lipgloss.Style {
+	return t.Matches[i%len(t.Matches)]
+}
+
+// setupTheme picks the theme from the config, honouring NO_COLOR
+// (https://no-color.org) over it. background is auto, light or dark; auto
+// asks the terminal. An unknown syntaxTheme keeps the theme's own.
+func setupTheme(name, syntaxTheme, background string) {
+	if os.Getenv("NO_COLOR") != "" {
+		name = "none"
+	}
+	dark := true
+	switch background {
+	case "light":
+		dark = false
+	case "dark":
+	default:
+		dark = lipgloss.HasDarkBackground()
+	}
+	if _, ok := styles.Registry[syntaxTheme]; syntaxTheme != "" && !ok {
+		slog.Warn("unknown syntax theme, using the default", "syntax_theme", syntaxTheme)
+		syntaxTheme = ""
+	}
+	themeConfig.name, themeConfig.syntaxTheme, themeConfig.dark = name, syntaxTheme, dark
+	applyTheme()
+}
+
+// applyTheme sets the theme from themeConfig.
+func applyTheme() {
+	// The adaptive colours follow the background too.
+	lipgloss.SetHasDarkBackground(themeConfig.dark)
+	theme = newTheme(themeConfig.name, themeConfig.dark)
+
+	if themeConfig.syntaxTheme != "" && !theme.NoColor {
+		theme.SyntaxTheme = themeConfig.syntaxTheme
+	}
+}
+
+// toggleBackground switches the theme between a light and dark background.
+func toggleBackground() {
+	themeConfig.dark = !themeConfig.dark
+	applyTheme()
+}
+
+// listDelegate returns the list item delegate styled for the theme.
+func listDelegate() list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	if !theme.NoColor {
+		return d
+	}
+
+	normal := lipgloss.NewStyle().Padding(0, 0, 0, 2)
+	selected := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		Padding(0, 0, 0, 1)
+
+	d.Styles.NormalTitle = normal
+	d.Styles.NormalDesc = normal
+	d.Styles.DimmedTitle = normal
+	d.Styles.DimmedDesc = normal
+	d.Styles.SelectedTitle = selected.Copy().Bold(true)
+	d.Styles.SelectedDesc = selected
+	return d
+}
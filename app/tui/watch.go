@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log/slog"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/noelzubin/notes_search/watcher"
+)
+
+// This is emitted when notes changed on disk while watching
+type notesChangedMsg struct {
+	changes watcher.Changes
+}
+
+// startWatching watches the roots of the config when it asks for it. The
+// daemon watches its own roots.
+func startWatching(config *utils.Config) *watcher.Watcher {
+	roots := config.WatchedRoots()
+	if len(roots) == 0 || connectAddr != "" {
+		return nil
+	}
+	w, err := watcher.New(roots)
+	if err != nil {
+		slog.Warn("can't watch the notes, changes are picked up by reindexing", "err", err)
+		return nil
+	}
+	return w
+}
+
+// waitForChanges reports the next changes of the watched notes with
+// notesChangedMsg.
+func (m *Model) waitForChanges() tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	changes := m.watcher.Changes()
+	return func() tea.Msg {
+		c, ok := <-changes
+		if !ok {
+			return nil
+		}
+		return notesChangedMsg{c}
+	}
+}
+
+// indexChanges indexes the changed notes, or every note when folders came
+// or went or a reindex is running. The results are refreshed once done.
+func (m *Model) indexChanges(changes watcher.Changes) tea.Cmd {
+	if changes.Rescan || m.indexState != indexIdle {
+		return m.reindex()
+	}
+	return m.indexEdited(changes.Paths)
+}
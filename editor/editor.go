@@ -1,9 +1,12 @@
 package editor
 
 import (
+	"os"
 	"os/exec"
+	"runtime"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/noelzubin/notes_search/utils"
 )
 
 type Editor struct {
@@ -16,29 +19,73 @@ type EditingFinished struct{}
 
 // this opens up an external editor.
 func openEditor(app string, args ...string) tea.Cmd {
-	return tea.ExecProcess(exec.Command(app, args...), func(err error) tea.Msg {
+	return tea.ExecProcess(editorCommand(app, args...), func(err error) tea.Msg {
 		return EditingFinished{}
 	})
 }
 
+// editorCommand builds the command used to launch the editor.
+// The configured editor may carry its own flags (e.g. "code --wait"),
+// split like a shell does, see utils.SplitCommand.
+// When no editor is configured $EDITOR is used, falling back to the
+// system file association on Windows.
+func editorCommand(app string, args ...string) *exec.Cmd {
+	if app == "" {
+		app = os.Getenv("EDITOR")
+	}
+
+	if runtime.GOOS == "windows" {
+		if app == "" {
+			return exec.Command("cmd", append([]string{"/C", "start", "", "/WAIT"}, args...)...)
+		}
+		// Run through cmd so .cmd/.bat shims (e.g. code.cmd) resolve.
+		fields := append([]string{"/C"}, utils.SplitCommand(app)...)
+		return exec.Command("cmd", append(fields, args...)...)
+	}
+
+	if app == "" {
+		app = "vi"
+	}
+	fields := utils.SplitCommand(app)
+	if len(fields) == 0 {
+		fields = []string{"vi"}
+	}
+	return exec.Command(fields[0], append(fields[1:], args...)...)
+}
+
+// OpenURL opens the URL in the default browser without waiting for it.
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Reap the opener once it exits, the browser lives on by itself.
+	go cmd.Wait()
+	return nil
+}
+
 func (m *Editor) Init() tea.Cmd {
 	return nil
 }
 
-func (m *Editor) EditFile(filepath string) tea.Cmd {
+// EditFile opens the files in a single editor session, e.g. `nvim a b`.
+func (m *Editor) EditFile(paths ...string) tea.Cmd {
 	m.Editing = true
-	return openEditor(m.EditorCmd, filepath)
+	return openEditor(m.EditorCmd, paths...)
 }
 
 func (m Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
-	if m.Editing {
-		return m, nil
-	}
-
 	switch msg.(type) {
 	case EditingFinished:
 		m.Editing = false
-		return m, nil
 	}
 
 	return m, nil
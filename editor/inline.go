@@ -0,0 +1,90 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MaxInlineLines is the longest note the inline editor can hold. The
+// textarea of bubbles v0.15 has no way to raise it and silently drops the
+// lines past it, so longer notes are refused and the limit is shown.
+const MaxInlineLines = 99
+
+// InlineEditor edits a note inside the TUI, for small edits that don't
+// warrant suspending the program for the external editor.
+type InlineEditor struct {
+	Path     string         // note being edited
+	textarea textarea.Model // the editing widget
+	original string         // content when opened, to detect changes
+}
+
+// NewInlineEditor loads the note at path into a textarea.
+// A missing note is created on save when create is set.
+func NewInlineEditor(path string, create bool) (*InlineEditor, error) {
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		body, err = []byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(body)
+	if lines := strings.Count(content, "\n") + 1; lines > MaxInlineLines {
+		return nil, fmt.Errorf("note has %d lines, the inline editor holds %d", lines, MaxInlineLines)
+	}
+
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.ShowLineNumbers = true
+	ta.SetValue(content)
+	ta.Focus()
+
+	return &InlineEditor{Path: path, textarea: ta, original: content}, nil
+}
+
+// SetSize sets the size of the textarea.
+func (e *InlineEditor) SetSize(width, height int) {
+	e.textarea.SetWidth(width)
+	e.textarea.SetHeight(height)
+}
+
+// Modified reports whether the content differs from the note on disk.
+func (e *InlineEditor) Modified() bool {
+	return e.textarea.Value() != e.original
+}
+
+// Save writes the content back to the note.
+func (e *InlineEditor) Save() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(e.Path); err == nil {
+		mode = info.Mode()
+	} else if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(e.Path, []byte(e.textarea.Value()), mode); err != nil {
+		return err
+	}
+	e.original = e.textarea.Value()
+	return nil
+}
+
+func (e *InlineEditor) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+func (e *InlineEditor) Update(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	e.textarea, cmd = e.textarea.Update(msg)
+	return cmd
+}
+
+func (e *InlineEditor) View() string {
+	return e.textarea.View()
+}
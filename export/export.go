@@ -0,0 +1,108 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// page wraps the rendered note into a standalone document.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%TITLE%</title>
+<style>
+body { max-width: 46em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; }
+</style>
+</head>
+<body>
+%BODY%
+</body>
+</html>
+`
+
+// markdown renders GitHub flavoured markdown.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// outputPath returns where the export of path with the given extension goes,
+// next to the note unless dir is set. A note that already has the extension
+// is exported to name.export.ext, so it isn't overwritten by its export.
+func outputPath(path, dir, ext string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	out := filepath.Join(dir, base+ext)
+	// Compared case-insensitively for file systems like macOS' and Windows'.
+	if strings.EqualFold(filepath.Clean(out), filepath.Clean(path)) {
+		out = filepath.Join(dir, base+".export"+ext)
+	}
+	return out
+}
+
+// HTML renders the note at path to a standalone HTML file and returns its path.
+// Markdown is rendered, any other note is shown preformatted.
+func HTML(path, dir string) (string, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		if err := markdown.Convert(source, &body); err != nil {
+			return "", err
+		}
+	default:
+		body.WriteString("<pre>" + html.EscapeString(string(source)) + "</pre>")
+	}
+
+	out := outputPath(path, dir, ".html")
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return "", err
+	}
+
+	doc := strings.Replace(page, "%TITLE%", html.EscapeString(filepath.Base(path)), 1)
+	doc = strings.Replace(doc, "%BODY%", body.String(), 1)
+	return out, os.WriteFile(out, []byte(doc), 0644)
+}
+
+// PDF exports the note to HTML and converts that to PDF with the configured
+// converter command, e.g. "wkhtmltopdf {in} {out}". The HTML is kept.
+func PDF(path, dir, converter string) (string, error) {
+	if converter == "" {
+		return "", errors.New("no pdf converter configured")
+	}
+
+	in, err := HTML(path, dir)
+	if err != nil {
+		return "", err
+	}
+	out := outputPath(path, dir, ".pdf")
+
+	args := strings.Fields(converter)
+	for i, arg := range args {
+		arg = strings.ReplaceAll(arg, "{in}", in)
+		args[i] = strings.ReplaceAll(arg, "{out}", out)
+	}
+
+	if output, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return out, nil
+}
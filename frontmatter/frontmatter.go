@@ -0,0 +1,332 @@
+package frontmatter
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// The line opening and closing a frontmatter block.
+const delimiter = "---"
+
+// DateLayouts are the layouts frontmatter dates are written in.
+var DateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// ParseDate parses a frontmatter date in one of the DateLayouts, in local
+// time unless it has a zone.
+func ParseDate(value string) (time.Time, bool) {
+	for _, layout := range DateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Split separates the YAML frontmatter at the top of content from the body.
+// found is false when content doesn't start with a frontmatter block.
+func Split(content string) (front, body string, found bool) {
+	first, rest, ok := strings.Cut(content, "\n")
+	if !ok || strings.TrimRight(first, "\r") != delimiter {
+		return "", content, false
+	}
+
+	offset := 0
+	for {
+		line, next, ok := strings.Cut(rest[offset:], "\n")
+		if strings.TrimRight(line, "\r") == delimiter {
+			return rest[:offset], next, true
+		}
+		if !ok {
+			return "", content, false
+		}
+		offset += len(line) + 1
+	}
+}
+
+// parse returns the frontmatter as a YAML mapping node,
+// an empty one when there is no frontmatter.
+func parse(front string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(front), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// field returns the value node of key in the mapping, whatever its case,
+// nil if absent.
+func field(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// stringList reads a list field given either as a YAML sequence or
+// as a single comma or space separated string.
+func stringList(node *yaml.Node) []string {
+	if node == nil {
+		return []string{}
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		return lo.FilterMap(node.Content, func(n *yaml.Node, _ int) (string, bool) {
+			return n.Value, n.Kind == yaml.ScalarNode && n.Value != ""
+		})
+	}
+
+	return strings.FieldsFunc(node.Value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// Tags returns the tags listed in the frontmatter of content.
+func Tags(content string) []string {
+	front, _, found := Split(content)
+	if !found {
+		return []string{}
+	}
+	mapping, err := parse(front)
+	if err != nil {
+		return []string{}
+	}
+	return stringList(field(mapping, "tags"))
+}
+
+// Aliases returns the other names of the note listed in the frontmatter
+// of content. Aliases may contain spaces, so a single string is only
+// split on commas.
+func Aliases(content string) []string {
+	front, _, found := Split(content)
+	if !found {
+		return []string{}
+	}
+	mapping, err := parse(front)
+	if err != nil {
+		return []string{}
+	}
+	return aliasList(mapping)
+}
+
+// aliasList reads the aliases, or alias, field of the mapping.
+func aliasList(mapping *yaml.Node) []string {
+	node := field(mapping, "aliases")
+	if node == nil {
+		node = field(mapping, "alias")
+	}
+	if node != nil && node.Kind == yaml.ScalarNode {
+		return lo.FilterMap(strings.Split(node.Value, ","), func(alias string, _ int) (string, bool) {
+			alias = strings.TrimSpace(alias)
+			return alias, alias != ""
+		})
+	}
+	return stringList(node)
+}
+
+// Metadata holds the frontmatter fields indexed on their own.
+type Metadata struct {
+	Title   string
+	Tags    []string
+	Date    time.Time // zero when missing or not in one of the DateLayouts
+	Aliases []string
+}
+
+// Parse returns the title, tags, date and aliases in the frontmatter of
+// content, reading it once.
+func Parse(content string) Metadata {
+	meta := Metadata{Tags: []string{}, Aliases: []string{}}
+	front, _, found := Split(content)
+	if !found {
+		return meta
+	}
+	mapping, err := parse(front)
+	if err != nil {
+		return meta
+	}
+
+	if title := field(mapping, "title"); title != nil && title.Kind == yaml.ScalarNode {
+		meta.Title = strings.TrimSpace(title.Value)
+	}
+	if date := field(mapping, "date"); date != nil && date.Kind == yaml.ScalarNode {
+		meta.Date, _ = ParseDate(date.Value)
+	}
+	meta.Tags = stringList(field(mapping, "tags"))
+	meta.Aliases = aliasList(mapping)
+	return meta
+}
+
+// Fields returns the scalar fields of the frontmatter of content, keys
+// lowercased. Lists of scalars are joined with spaces, nested mappings
+// are left out.
+func Fields(content string) map[string]string {
+	fields := map[string]string{}
+	front, _, found := Split(content)
+	if !found {
+		return fields
+	}
+	mapping, err := parse(front)
+	if err != nil {
+		return fields
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := strings.ToLower(mapping.Content[i].Value), mapping.Content[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			fields[key] = value.Value
+		case yaml.SequenceNode:
+			fields[key] = strings.Join(stringList(value), " ")
+		}
+	}
+	return fields
+}
+
+// EditTags adds and removes tags in the frontmatter of content, creating
+// the frontmatter when there is none. It returns the new content along with
+// the tags before and after the edit. Other fields are left untouched.
+func EditTags(content string, add, remove []string) (edited string, before, after []string, err error) {
+	return editTags(content, func(before []string) []string {
+		return lo.Uniq(append(lo.Without(before, remove...), lo.Without(add, remove...)...))
+	})
+}
+
+// RenameTag renames the tag old, and the tags nested below it such as
+// old/sub, to new in the frontmatter of content, in place. Tags are
+// compared case-insensitively. Content without the tag is returned as is.
+func RenameTag(content, old, new string) (edited string, before, after []string, err error) {
+	before = Tags(content)
+	if !lo.ContainsBy(before, func(tag string) bool { return IsTagOrChild(tag, old) }) {
+		return content, before, before, nil
+	}
+	return editTags(content, func(before []string) []string {
+		return lo.Uniq(lo.Map(before, func(tag string, _ int) string {
+			if IsTagOrChild(tag, old) {
+				return new + tag[len(old):]
+			}
+			return tag
+		}))
+	})
+}
+
+// IsTagOrChild reports whether tag is parent or nested below it, ignoring case.
+func IsTagOrChild(tag, parent string) bool {
+	return len(tag) >= len(parent) && strings.EqualFold(tag[:len(parent)], parent) &&
+		(len(tag) == len(parent) || tag[len(parent)] == '/')
+}
+
+// editTags replaces the tags in the frontmatter of content with the result
+// of edit, creating the frontmatter when there is none.
+func editTags(content string, edit func(before []string) []string) (edited string, before, after []string, err error) {
+	front, body, found := Split(content)
+
+	mapping, err := parse(front)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	before = stringList(field(mapping, "tags"))
+	after = edit(before)
+
+	tags := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, tag := range after {
+		tags.Content = append(tags.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tag})
+	}
+
+	setField(mapping, "tags", tags)
+	edited, err = render(mapping, content, body, found)
+	return edited, before, after, err
+}
+
+// Field is a frontmatter field to set with SetFields.
+type Field struct {
+	Key   string
+	Value string // comma separated for lists, empty to remove the field
+	List  bool   // written as a list, such as tags
+}
+
+// SetFields sets the fields in the frontmatter of content, creating the
+// frontmatter when there is none. Other fields are left untouched.
+func SetFields(content string, fields []Field) (string, error) {
+	front, body, found := Split(content)
+
+	mapping, err := parse(front)
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range fields {
+		value := strings.TrimSpace(f.Value)
+		if value == "" {
+			removeField(mapping, f.Key)
+			continue
+		}
+		if !f.List {
+			// Tagged as strings, so a title such as "true" or "123" keeps
+			// its type. Dates are left untagged, so they stay unquoted.
+			node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+			if _, ok := ParseDate(value); ok {
+				node.Tag = ""
+			}
+			setField(mapping, f.Key, node)
+			continue
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+			}
+		}
+		setField(mapping, f.Key, list)
+	}
+	return render(mapping, content, body, found)
+}
+
+// setField replaces the value of key in the mapping, adding the key when
+// it's absent. Block lists stay block lists.
+func setField(mapping *yaml.Node, key string, value *yaml.Node) {
+	node := field(mapping, key)
+	if node == nil {
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+		return
+	}
+	if node.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode {
+		value.Style = node.Style
+	}
+	*node = *value
+}
+
+// removeField removes key from the mapping, whatever its case.
+func removeField(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// render puts the mapping as frontmatter on top of the body of content,
+// or of the whole content when it had no frontmatter.
+func render(mapping *yaml.Node, content, body string, found bool) (string, error) {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(mapping); err != nil {
+		return "", err
+	}
+
+	if !found {
+		body = content
+	}
+	return delimiter + "\n" + out.String() + delimiter + "\n" + body, nil
+}
@@ -0,0 +1,95 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		front, body string
+		found       bool
+	}{
+		{"frontmatter", "---\ntitle: Plan\n---\n# Plan\n", "title: Plan\n", "# Plan\n", true},
+		{"crlf", "---\r\ntitle: Plan\r\n---\r\nbody", "title: Plan\r\n", "body", true},
+		{"empty block", "---\n---\nbody", "", "body", true},
+		{"nothing after", "---\ntags: [a]\n---", "tags: [a]\n", "", true},
+		{"no frontmatter", "# Plan\n---\n", "", "# Plan\n---\n", false},
+		{"unclosed", "---\ntitle: Plan\nbody", "", "---\ntitle: Plan\nbody", false},
+		{"delimiter alone", "---", "", "---", false},
+		{"not at the top", "\n---\ntitle: Plan\n---\n", "", "\n---\ntitle: Plan\n---\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, body, found := Split(tt.content)
+			if front != tt.front || body != tt.body || found != tt.found {
+				t.Errorf("Split(%q) = %q, %q, %v, want %q, %q, %v", tt.content, front, body, found, tt.front, tt.body, tt.found)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Metadata
+	}{
+		{"none", "# Plan", Metadata{Tags: []string{}, Aliases: []string{}}},
+		{"fields", "---\ntitle: ' Plan '\ntags: [work, q3]\ndate: 2024-05-15\naliases: [Roadmap]\n---\n", Metadata{
+			Title:   "Plan",
+			Tags:    []string{"work", "q3"},
+			Date:    time.Date(2024, 5, 15, 0, 0, 0, 0, time.Local),
+			Aliases: []string{"Roadmap"},
+		}},
+		{"string lists", "---\ntags: work, q3 later\nalias: The plan, Roadmap\n---\n", Metadata{
+			Tags:    []string{"work", "q3", "later"},
+			Aliases: []string{"The plan", "Roadmap"},
+		}},
+		{"bad date", "---\ndate: someday\n---\n", Metadata{Tags: []string{}, Aliases: []string{}}},
+		{"bad yaml", "---\ntags: [work\n---\n", Metadata{Tags: []string{}, Aliases: []string{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	content := "---\nStatus: draft\ntags: [a, b]\nmeta:\n  nested: x\n---\nbody"
+	want := map[string]string{"status": "draft", "tags": "a b"}
+	if got := Fields(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields(%q) = %v, want %v", content, got, want)
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fields  []Field
+		want    string
+	}{
+		{"new frontmatter", "body", []Field{{Key: "title", Value: "Plan"}}, "---\ntitle: Plan\n---\nbody"},
+		{"strings keep their type", "body", []Field{{Key: "title", Value: "true"}, {Key: "status", Value: "123"}, {Key: "owner", Value: "null"}},
+			"---\ntitle: \"true\"\nstatus: \"123\"\nowner: \"null\"\n---\nbody"},
+		{"dates unquoted", "body", []Field{{Key: "due", Value: "2024-05-15"}}, "---\ndue: 2024-05-15\n---\nbody"},
+		{"keys of any case", "---\nTitle: Old\nTags: [a]\n---\nbody", []Field{{Key: "title", Value: "New"}, {Key: "tags", Value: ""}},
+			"---\nTitle: New\n---\nbody"},
+		{"lists", "---\ntags:\n  - a\n---\nbody", []Field{{Key: "tags", Value: "b, c", List: true}}, "---\ntags:\n  - b\n  - c\n---\nbody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetFields(tt.content, tt.fields)
+			if err != nil || got != tt.want {
+				t.Errorf("SetFields(%q) = %q, %v, want %q", tt.content, got, err, tt.want)
+			}
+		})
+	}
+}
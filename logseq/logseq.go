@@ -0,0 +1,112 @@
+package logseq
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var (
+	// key:: value, optionally on a bullet
+	property = regexp.MustCompile(`^(?:- )?([A-Za-z][\w-]*):: ?(.*)$`)
+	// ((block uuid))
+	blockRef = regexp.MustCompile(`\(\(([0-9a-fA-F-]{36})\)\)`)
+)
+
+// Page is a Logseq outline: page properties followed by bullet blocks.
+type Page struct {
+	Title      string            // the title:: property, empty if none
+	Properties map[string]string // page and block properties, keys lowercased
+	Blocks     []Block
+}
+
+// Block is a bullet of the outline with its nested lines.
+type Block struct {
+	ID      string // the id:: property, empty if none
+	Content string // text without the bullet, the properties and the indentation
+}
+
+// IsOutline reports whether content looks like a Logseq page, i.e. has
+// properties or block references.
+func IsOutline(content string) bool {
+	if blockRef.MatchString(content) {
+		return true
+	}
+	return lo.ContainsBy(strings.Split(content, "\n"), func(line string) bool {
+		return property.MatchString(strings.TrimSpace(line))
+	})
+}
+
+// Parse splits content into the page properties and its blocks.
+// The properties of the page and of its blocks are collected together,
+// the first value of a key wins; id:: identifies the block it's in.
+func Parse(content string) Page {
+	page := Page{Properties: map[string]string{}, Blocks: []Block{}}
+	var current *Block
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if match := property.FindStringSubmatch(trimmed); match != nil {
+			// A property on its own bullet is a block of its own.
+			if strings.HasPrefix(trimmed, "- ") {
+				page.Blocks = append(page.Blocks, Block{})
+				current = &page.Blocks[len(page.Blocks)-1]
+			}
+
+			key, value := strings.ToLower(match[1]), strings.TrimSpace(match[2])
+			if key == "id" && current != nil {
+				current.ID = value
+			} else if _, ok := page.Properties[key]; !ok {
+				page.Properties[key] = value
+			}
+			continue
+		}
+
+		switch {
+		case trimmed == "-" || strings.HasPrefix(trimmed, "- "):
+			page.Blocks = append(page.Blocks, Block{Content: strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))})
+			current = &page.Blocks[len(page.Blocks)-1]
+		case trimmed == "":
+		case current == nil:
+			page.Blocks = append(page.Blocks, Block{Content: trimmed})
+			current = &page.Blocks[len(page.Blocks)-1]
+		default:
+			current.Content = strings.TrimSpace(current.Content + "\n" + trimmed)
+		}
+	}
+
+	page.Title = page.Properties["title"]
+	return page
+}
+
+// Text returns the content of the blocks, one per line, with the block
+// references replaced by the referenced text. References to blocks of
+// other pages are dropped.
+func (p Page) Text() string {
+	byID := map[string]string{}
+	for _, block := range p.Blocks {
+		if block.ID != "" {
+			byID[strings.ToLower(block.ID)] = block.Content
+		}
+	}
+
+	lines := lo.FilterMap(p.Blocks, func(block Block, _ int) (string, bool) {
+		text := blockRef.ReplaceAllStringFunc(block.Content, func(ref string) string {
+			return byID[strings.ToLower(blockRef.FindStringSubmatch(ref)[1])]
+		})
+		return text, strings.TrimSpace(text) != ""
+	})
+	return strings.Join(lines, "\n")
+}
+
+// Tags returns the tags:: of the page, e.g. "[[project]], #work" gives
+// project and work.
+func (p Page) Tags() []string {
+	return lo.FilterMap(strings.Split(p.Properties["tags"], ","), func(tag string, _ int) (string, bool) {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		tag = strings.TrimSuffix(strings.TrimPrefix(tag, "[["), "]]")
+		return tag, tag != ""
+	})
+}
@@ -0,0 +1,33 @@
+package notes
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Append adds text to the end of the note at path, on a line of its own.
+func Append(path, text string) error {
+	text = strings.TrimRight(text, "\r\n")
+	if strings.TrimSpace(text) == "" {
+		return errors.New("nothing to append")
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 && !strings.HasSuffix(string(body), "\n") {
+		text = "\n" + text
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(text + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
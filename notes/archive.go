@@ -0,0 +1,32 @@
+package notes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archive moves the note at path from the notes root into archiveDir,
+// keeping its path relative to the root, and returns the new path.
+func Archive(root, archiveDir, path string) (string, error) {
+	if strings.HasPrefix(path, archiveDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is already archived", filepath.Base(path))
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+
+	dest := filepath.Join(archiveDir, rel)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+
+	return dest, os.Rename(path, dest)
+}
@@ -0,0 +1,23 @@
+//go:build darwin || freebsd || netbsd
+
+package notes
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Birthtime returns when the file at path was created. ok is false when
+// the file can't be stat'ed.
+func Birthtime(path string) (created time.Time, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Birthtimespec.Sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Birthtimespec.Unix()), true
+}
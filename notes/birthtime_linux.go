@@ -0,0 +1,21 @@
+package notes
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Birthtime returns when the file at path was created. ok is false when
+// the file system doesn't record it, which statx reports on older kernels
+// and file systems.
+func Birthtime(path string) (created time.Time, ok bool) {
+	var stat unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stat); err != nil {
+		return time.Time{}, false
+	}
+	if stat.Mask&unix.STATX_BTIME == 0 || stat.Btime.Sec == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec)), true
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !windows
+
+package notes
+
+import "time"
+
+// Birthtime returns when the file at path was created, which isn't known
+// on this platform.
+func Birthtime(path string) (created time.Time, ok bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,21 @@
+package notes
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Birthtime returns when the file at path was created. ok is false when
+// the file can't be stat'ed.
+func Birthtime(path string) (created time.Time, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
@@ -0,0 +1,98 @@
+package notes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+)
+
+// Longest file name, in runes, taken from the first line of a new note.
+const maxNameLength = 60
+
+// NewNoteExtension returns the extension of new notes: the first of the
+// note extensions, see utils.Config.NoteExtensions, or .md.
+func NewNoteExtension(extensions []string) string {
+	if ext, ok := lo.Find(extensions, func(ext string) bool { return ext != "" }); ok {
+		return ext
+	}
+	return ".md"
+}
+
+// Create writes content as a new note with the extension ext in dir, named
+// after its first line, and returns its path. A number is appended when
+// the name is taken.
+func Create(dir, ext, content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", errors.New("nothing to create a note from")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	name := fileName(content)
+	path := filepath.Join(dir, name+ext)
+	for i := 2; ; i++ {
+		// O_EXCL, so an existing note is never overwritten.
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.WriteString(content); err != nil {
+			file.Close()
+			return "", err
+		}
+		return path, file.Close()
+	}
+}
+
+// fileName turns the first non-empty line of content, without heading
+// marks, into a lowercase file name of letters, digits and dashes.
+func fileName(content string) string {
+	title := ""
+	for _, line := range strings.Split(content, "\n") {
+		if title = strings.TrimSpace(strings.TrimLeft(line, "# ")); title != "" {
+			break
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	name := []rune(strings.Join(words, "-"))
+	if len(name) > maxNameLength {
+		name = []rune(strings.TrimRight(string(name[:maxNameLength]), "-"))
+	}
+	if len(name) == 0 {
+		return "untitled"
+	}
+	return string(name)
+}
+
+// CreateAt writes content as a new note at path, creating its folder.
+// It fails rather than overwrite an existing note.
+func CreateAt(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
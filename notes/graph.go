@@ -0,0 +1,131 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// LinkGraph is the links between the notes of a root, resolved to the
+// paths of the notes and attachments they point to.
+type LinkGraph struct {
+	Links     map[string][]string // paths each note links to
+	Backlinks map[string][]string // paths of the notes linking to each path
+	Broken    []BrokenLink        // links to nothing, by note then target
+	notes     []string            // the notes, attachments left out
+}
+
+// BrokenLink is a link whose target doesn't exist.
+type BrokenLink struct {
+	Source string // note the link is in
+	Target string // target as written in the link
+	Path   string // where a note for the target would be created
+}
+
+// NewLinkGraph reads the notes at paths, which are under root, and
+// resolves their links. Wiki links resolve by name, without extension and
+// case insensitively, or by path from the root; markdown links relative to
+// the note, then by name as well. Unreadable notes are left out.
+func NewLinkGraph(root string, paths []string) *LinkGraph {
+	g := &LinkGraph{Links: map[string][]string{}, Backlinks: map[string][]string{}}
+	byName := map[string][]string{}
+	known := map[string]bool{}
+	for _, path := range paths {
+		known[path] = true
+		name := strings.ToLower(filepath.Base(path))
+		byName[name] = append(byName[name], path)
+		if !IsAttachment(path) {
+			g.notes = append(g.notes, path)
+			base := strings.TrimSuffix(name, filepath.Ext(name))
+			byName[base] = append(byName[base], path)
+		}
+	}
+
+	// resolve returns the path target points to, looking it up at the
+	// candidate paths first and by name last.
+	resolve := func(target string, candidates ...string) (string, bool) {
+		for _, candidate := range candidates {
+			if known[candidate] {
+				return candidate, true
+			}
+		}
+		for _, candidate := range candidates {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, true
+			}
+		}
+		found := byName[strings.ToLower(filepath.Base(filepath.FromSlash(target)))]
+		if len(found) == 0 {
+			return "", false
+		}
+		return found[0], true
+	}
+
+	for _, source := range g.notes {
+		body, err := os.ReadFile(source)
+		if err != nil {
+			continue
+		}
+		markdown, wiki := splitLinks(string(body))
+		broken := func(target, path string) {
+			if filepath.Ext(path) == "" {
+				path += ".md"
+			}
+			g.Broken = append(g.Broken, BrokenLink{Source: source, Target: target, Path: filepath.Clean(path)})
+		}
+
+		for _, target := range markdown {
+			path := filepath.Join(filepath.Dir(source), filepath.FromSlash(target))
+			if strings.HasPrefix(target, "/") {
+				path = filepath.Join(root, filepath.FromSlash(target))
+			}
+			if resolved, ok := resolve(target, path, path+".md"); ok {
+				g.link(source, resolved)
+			} else {
+				broken(target, path)
+			}
+		}
+		for _, target := range wiki {
+			path := filepath.Join(root, filepath.FromSlash(target))
+			if resolved, ok := resolve(target, path, path+".md"); ok {
+				g.link(source, resolved)
+			} else {
+				broken(target, path)
+			}
+		}
+	}
+	return g
+}
+
+// link records that source links to target, links to itself left out.
+func (g *LinkGraph) link(source, target string) {
+	if source == target || lo.Contains(g.Links[source], target) {
+		return
+	}
+	g.Links[source] = append(g.Links[source], target)
+	g.Backlinks[target] = append(g.Backlinks[target], source)
+}
+
+// Orphans returns the notes that neither link to another note nor are
+// linked to, sorted by path.
+func (g *LinkGraph) Orphans() []string {
+	orphans := lo.Filter(g.notes, func(path string, _ int) bool {
+		return len(g.Links[path]) == 0 && len(g.Backlinks[path]) == 0
+	})
+	sort.Strings(orphans)
+	return orphans
+}
+
+// Merge adds the notes and links of other, the graph of another root.
+func (g *LinkGraph) Merge(other *LinkGraph) {
+	for source, targets := range other.Links {
+		for _, target := range targets {
+			g.link(source, target)
+		}
+	}
+	g.Broken = append(g.Broken, other.Broken...)
+	g.notes = lo.Uniq(append(g.notes, other.notes...))
+}
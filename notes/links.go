@@ -0,0 +1,65 @@
+package notes
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var (
+	// [text](target) and ![alt](target "title")
+	markdownLink = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// [[target]], ![[target|alias]] and [[target#heading]]
+	wikiLink = regexp.MustCompile(`\[\[([^\]|#]+)(?:[#|][^\]]*)?\]\]`)
+)
+
+// attachmentExtensions are the non-text files notes embed or link to.
+var attachmentExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".bmp",
+	".pdf", ".mp3", ".mp4", ".webm", ".mov", ".wav", ".ogg",
+	".zip", ".docx", ".xlsx", ".pptx",
+}
+
+// IsAttachment reports whether path is a non-text attachment such as an
+// image or a pdf.
+func IsAttachment(path string) bool {
+	return lo.Contains(attachmentExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// Links returns the targets of the markdown and wiki links in content,
+// without anchors, unescaped and with external URLs left out.
+func Links(content string) []string {
+	markdown, wiki := splitLinks(content)
+	return lo.Uniq(append(markdown, wiki...))
+}
+
+// splitLinks returns the targets of the markdown links and of the wiki
+// links in content, as Links does.
+func splitLinks(content string) (markdown, wiki []string) {
+	for _, match := range markdownLink.FindAllStringSubmatch(content, -1) {
+		target := match[1]
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") || strings.HasPrefix(target, "#") {
+			continue
+		}
+		target, _, _ = strings.Cut(target, "#")
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		markdown = append(markdown, target)
+	}
+	for _, match := range wikiLink.FindAllStringSubmatch(content, -1) {
+		wiki = append(wiki, strings.TrimSpace(match[1]))
+	}
+	return lo.Uniq(markdown), lo.Uniq(wiki)
+}
+
+// LinkedNames returns the lowercased file names the links of content point
+// to, e.g. "diagram.png" for ![](img/diagram.png).
+func LinkedNames(content string) []string {
+	return lo.Uniq(lo.Map(Links(content), func(target string, _ int) string {
+		return strings.ToLower(filepath.Base(filepath.FromSlash(target)))
+	}))
+}
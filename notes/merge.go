@@ -0,0 +1,42 @@
+package notes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/noelzubin/notes_search/frontmatter"
+)
+
+// Merge appends the notes at sources to the note at target, which is
+// created if it doesn't exist. Each source goes under a "## name" heading,
+// names holding one per source, without its frontmatter.
+func Merge(target string, sources, names []string) error {
+	if len(sources) == 0 {
+		return errors.New("nothing to merge")
+	}
+
+	body, err := os.ReadFile(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	merged := strings.TrimRight(string(body), "\r\n")
+
+	for i, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		_, text, _ := frontmatter.Split(string(content))
+		if merged != "" {
+			merged += "\n\n"
+		}
+		merged += "## " + names[i] + "\n\n" + strings.Trim(text, "\r\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, []byte(merged+"\n"), 0644)
+}
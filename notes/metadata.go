@@ -0,0 +1,21 @@
+package notes
+
+import (
+	"os"
+
+	"github.com/noelzubin/notes_search/frontmatter"
+)
+
+// SetFields sets the frontmatter fields of the note at path, see
+// frontmatter.SetFields.
+func SetFields(path string, fields []frontmatter.Field) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	content, err := frontmatter.SetFields(string(body), fields)
+	if err != nil {
+		return err
+	}
+	return writeNote(path, content)
+}
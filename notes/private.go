@@ -0,0 +1,36 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/noelzubin/notes_search/frontmatter"
+)
+
+// IsPrivate tells whether content is marked "private: true" in its
+// frontmatter. Private notes are only found by their title and their
+// content is shown once confirmed.
+func IsPrivate(content string) bool {
+	private, _ := strconv.ParseBool(frontmatter.Fields(content)["private"])
+	return private
+}
+
+// IsPrivateFile tells whether the note at path is private, see IsPrivate.
+func IsPrivateFile(path string) bool {
+	if IsAttachment(path) {
+		return false
+	}
+	body, err := os.ReadFile(path)
+	return err == nil && IsPrivate(string(body))
+}
+
+// PrivateTitle returns the title a private note is found by: its title
+// field, or its file name without the extension.
+func PrivateTitle(path, content string) string {
+	if title := strings.TrimSpace(frontmatter.Fields(content)["title"]); title != "" {
+		return title
+	}
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,77 @@
+package notes
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Occurrence is a line of a note matching the pattern.
+type Occurrence struct {
+	Line   int    // 1 based line number
+	Before string // the line as it is
+	After  string // the line once replaced
+}
+
+// Replacement is the planned edit of a single note.
+type Replacement struct {
+	Path        string
+	Occurrences []Occurrence
+	content     string // new content of the note
+}
+
+// PlanReplace finds the occurrences of pattern in the given notes and
+// works out the replaced content, without writing anything.
+// The pattern is taken literally unless regex is set, in which case
+// the replacement may refer to groups with $1.
+// Notes without a match are left out.
+func PlanReplace(paths []string, pattern, replacement string, regex bool) ([]Replacement, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	replace := func(line string) string {
+		if regex {
+			return re.ReplaceAllString(line, replacement)
+		}
+		return re.ReplaceAllLiteralString(line, replacement)
+	}
+
+	plans := []Replacement{}
+
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		lines := strings.Split(string(body), "\n")
+		occurrences := []Occurrence{}
+		for i, line := range lines {
+			if !re.MatchString(line) {
+				continue
+			}
+			lines[i] = replace(line)
+			occurrences = append(occurrences, Occurrence{Line: i + 1, Before: line, After: lines[i]})
+		}
+
+		if len(occurrences) > 0 {
+			plans = append(plans, Replacement{
+				Path:        path,
+				Occurrences: occurrences,
+				content:     strings.Join(lines, "\n"),
+			})
+		}
+	}
+
+	return plans, nil
+}
+
+// Apply writes the replaced content to the note.
+func (r Replacement) Apply() error {
+	return writeNote(r.Path, r.content)
+}
@@ -0,0 +1,165 @@
+package notes
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/noelzubin/notes_search/frontmatter"
+	"github.com/samber/lo"
+)
+
+// TagEdit is the planned tag change of a single note.
+type TagEdit struct {
+	Path    string
+	Before  []string // tags before the edit
+	After   []string // tags after the edit
+	Created bool     // the note had no frontmatter yet
+	content string   // new content of the note
+}
+
+// Changed reports whether the edit changes the tags at all.
+func (e TagEdit) Changed() bool {
+	return len(e.Before) != len(e.After) || len(lo.Intersect(e.Before, e.After)) != len(e.After)
+}
+
+// PlanTagEdit works out adding and removing tags in the frontmatter of
+// the given notes, without writing anything.
+func PlanTagEdit(paths []string, add, remove []string) ([]TagEdit, error) {
+	edits := []TagEdit{}
+
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		_, _, found := frontmatter.Split(string(body))
+		content, before, after, err := frontmatter.EditTags(string(body), add, remove)
+		if err != nil {
+			return nil, err
+		}
+
+		edits = append(edits, TagEdit{
+			Path:    path,
+			Before:  before,
+			After:   after,
+			Created: !found,
+			content: content,
+		})
+	}
+
+	return edits, nil
+}
+
+// Apply writes the edited frontmatter to the note.
+func (e TagEdit) Apply() error {
+	return writeNote(e.Path, e.content)
+}
+
+// writeNote replaces the content of the note, keeping its permissions.
+func writeNote(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), info.Mode())
+}
+
+// TagRename is the planned rename of a tag in a single note.
+type TagRename struct {
+	Path    string
+	Before  []string     // frontmatter tags before the rename
+	After   []string     // frontmatter tags after the rename
+	Inline  []Occurrence // lines whose inline #tags are renamed
+	content string       // new content of the note
+}
+
+// FrontmatterChanged reports whether the rename changes the frontmatter tags.
+func (r TagRename) FrontmatterChanged() bool {
+	return TagEdit{Before: r.Before, After: r.After}.Changed()
+}
+
+// inlineTag matches #tag, #tag/nested and the like.
+var inlineTag = regexp.MustCompile(`#[\p{L}\p{N}_/-]+`)
+
+// PlanTagRename works out renaming the tag old, and the tags nested below
+// it, to new in the frontmatter and the inline #tags of the given notes,
+// without writing anything. A note already tagged new ends up with the tag
+// once, so renaming also merges tags. Notes without the tag are left out.
+func PlanTagRename(paths []string, old, new string) ([]TagRename, error) {
+	old, new = strings.TrimPrefix(old, "#"), strings.TrimPrefix(new, "#")
+	if old == "" || new == "" || strings.ContainsAny(old+new, " \t") {
+		return nil, errors.New("tags can't be empty or contain spaces")
+	}
+
+	renames := []TagRename{}
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		content, before, after, err := frontmatter.RenameTag(string(body), old, new)
+		if err != nil {
+			return nil, err
+		}
+		rename := TagRename{Path: path, Before: before, After: after, Inline: []Occurrence{}}
+
+		// Inline tags are only looked for in the body, outside code blocks.
+		_, text, _ := frontmatter.Split(content)
+		front := content[:len(content)-len(text)]
+		lines := strings.Split(text, "\n")
+		inCode := false
+		for i, line := range lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inCode = !inCode
+			}
+			if inCode {
+				continue
+			}
+			if renamed := renameInlineTag(line, old, new); renamed != line {
+				lines[i] = renamed
+				rename.Inline = append(rename.Inline, Occurrence{
+					Line:   strings.Count(front, "\n") + i + 1,
+					Before: line,
+					After:  renamed,
+				})
+			}
+		}
+		rename.content = front + strings.Join(lines, "\n")
+
+		if rename.FrontmatterChanged() || len(rename.Inline) > 0 {
+			renames = append(renames, rename)
+		}
+	}
+
+	return renames, nil
+}
+
+// renameInlineTag renames the #old tags of line. A # within a word or a
+// URL doesn't start a tag.
+func renameInlineTag(line, old, new string) string {
+	matches := inlineTag.FindAllStringIndex(line, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][0], matches[i][1]
+		if start > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(line[:start])
+			if !unicode.IsSpace(prev) && !strings.ContainsRune("([{,;\"'", prev) {
+				continue
+			}
+		}
+		if tag := line[start+1 : end]; frontmatter.IsTagOrChild(tag, old) {
+			line = line[:start+1] + new + tag[len(old):] + line[end:]
+		}
+	}
+	return line
+}
+
+// Apply writes the renamed tags to the note.
+func (r TagRename) Apply() error {
+	return writeNote(r.Path, r.content)
+}
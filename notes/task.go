@@ -0,0 +1,65 @@
+package notes
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// taskItem matches the list items with a checkbox, e.g. "- [ ] buy milk"
+// or "1. [x] done", capturing the text before and in the box.
+var taskItem = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])\]`)
+
+// Tasks returns the indexes of the lines of content that are task list
+// items. Lines in code fences don't count.
+func Tasks(content string) []int {
+	tasks := []int{}
+	fenced := false
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if !fenced && taskItem.MatchString(line) {
+			tasks = append(tasks, i)
+		}
+	}
+	return tasks
+}
+
+// OpenTasks counts the unchecked task list items of content.
+func OpenTasks(content string) int {
+	lines := strings.Split(content, "\n")
+	return len(lo.Filter(Tasks(content), func(i int, _ int) bool {
+		return taskItem.FindStringSubmatch(lines[i])[2] == " "
+	}))
+}
+
+// ToggleTask checks or unchecks the n-th task list item of the note at
+// path, as counted by Tasks, and returns whether it's checked now.
+func ToggleTask(path string, n int) (bool, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	content := string(body)
+	tasks := Tasks(content)
+	if n < 0 || n >= len(tasks) {
+		return false, fmt.Errorf("no task %d in %s", n+1, path)
+	}
+
+	lines := strings.Split(content, "\n")
+	line := lines[tasks[n]]
+	match := taskItem.FindStringSubmatchIndex(line)
+	checked := line[match[4]:match[5]] == " "
+	box := " "
+	if checked {
+		box = "x"
+	}
+	lines[tasks[n]] = line[:match[4]] + box + line[match[5]:]
+	return checked, writeNote(path, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,66 @@
+package notes
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/samber/lo"
+)
+
+// IsNote tells whether the file at path, under root, has one of the
+// extensions of the notes. With "" among them the files without extension
+// count when they look like text, except in hidden folders such as .git.
+func IsNote(root, path string, extensions []string) bool {
+	ext := filepath.Ext(path)
+	// Extensions are case-insensitive on Windows and macOS.
+	if !lo.ContainsBy(extensions, func(e string) bool { return strings.EqualFold(e, ext) }) {
+		return false
+	}
+	if ext != "" {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	hidden := lo.SomeBy(strings.Split(filepath.ToSlash(rel), "/"), func(part string) bool {
+		return strings.HasPrefix(part, ".") && part != "." && part != ".."
+	})
+	return !hidden && IsText(path)
+}
+
+// Bytes read to tell text from binary files.
+const sniffSize = 512
+
+// IsText reports whether the file at path looks like text: its start is
+// UTF-8 without NUL bytes.
+func IsText(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	// The last rune may be cut off.
+	for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	return utf8.Valid(head)
+}
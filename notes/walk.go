@@ -0,0 +1,86 @@
+package notes
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// noIndexFile keeps the folder it's in, and its subfolders, out of the index.
+const noIndexFile = ".noindex"
+
+// Low I/O mode pauses after listing each folder.
+const lowIOWalkPause = 5 * time.Millisecond
+
+// Paths lists the notes of the config, whichever backend searches them.
+func Paths(config *utils.Config) []string {
+	paths, _ := Walk(config.RootPath, config.NoteExtensions(), config.ScratchpadPath(), config.LowIO())
+	return paths
+}
+
+// Walk lists the notes under root and the scratchpad if it exists, along
+// with the files and folders the walk isn't allowed to read. In low I/O
+// mode it pauses after listing each folder.
+func Walk(root string, extensions []string, scratchpad string, lowIO bool) (paths, denied []string) {
+	pause := time.Duration(0)
+	if lowIO {
+		pause = lowIOWalkPause
+	}
+	root = filepath.Clean(root)
+	paths, denied = glob(root, pause, func(path string) bool {
+		return IsNote(root, path, extensions)
+	})
+	if _, err := os.Stat(scratchpad); err == nil && !lo.Contains(paths, scratchpad) {
+		paths = append(paths, scratchpad)
+	}
+	return paths, denied
+}
+
+// Custom glob function because inbuild function doesn't support recursive globbing correctly
+// The files and folders it isn't allowed to read are returned as denied,
+// the folders holding a noIndexFile are skipped.
+func glob(root string, pause time.Duration, fn func(string) bool) (matches, denied []string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrPermission) {
+			denied = append(denied, path)
+			return nil
+		}
+		if d != nil && d.IsDir() && hasNoIndexFile(path) {
+			return filepath.SkipDir
+		}
+		if pause > 0 && d != nil && d.IsDir() {
+			time.Sleep(pause)
+		}
+		if fn(path) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, denied
+}
+
+// hasNoIndexFile tells whether the folder holds a noIndexFile.
+func hasNoIndexFile(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, noIndexFile))
+	return err == nil
+}
+
+// InNoIndexFolder tells whether path is in a folder under root that is
+// kept out of the index by a noIndexFile, in it or in one of its parents.
+// The walk skips these folders, single changed files are checked by it.
+func InNoIndexFolder(root, path string) bool {
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if hasNoIndexFile(dir) {
+			return true
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
@@ -1,23 +1,34 @@
 package bleve_indexer
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"io/ioutil"
-	"log"
+	"log/slog"
+	"math/rand"
 	"os"
-	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/blevesearch/bleve/v2"
+	"github.com/noelzubin/notes_search/frontmatter"
+	"github.com/noelzubin/notes_search/logseq"
+	"github.com/noelzubin/notes_search/notes"
 	"github.com/noelzubin/notes_search/search"
 	"github.com/noelzubin/notes_search/utils"
 	"github.com/samber/lo"
 
 	_ "github.com/blevesearch/bleve/v2/config"
 	bleveSearch "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
 )
 
 // bleveIndexer is the implmentation of the SearchIndexer
@@ -25,47 +36,93 @@ import (
 type bleveIndexer struct {
 	notesRoot  string
 	extensions []string
+	types      map[string][]string // extension groups, see utils.Config.Types
 	index      bleve.Index
 	indexPath  string
-}
+	archiveDir string
+	scratchpad string // indexed even when outside the notes root
+
+	minPrefix     int // shortest last word searched as a prefix
+	maxExpansions int // most terms a prefix may expand to
+	fuzziness     int // edits a word of a fuzzy query may be away
+
+	boosts    map[string]float64 // score multiplier by lowercased folder prefix
+	stopwords []string           // see utils.Config.Stopwords
 
-// returns where index and metadata will be stored on disk.
-func getDataPath() string {
-	dir, _ := os.UserCacheDir()
-	return path.Join(dir, "/notes_search")
+	encrypted *encryptedStore // nil unless the index is encrypted at rest
+	dataDir   string          // where the index and its metadata are stored
+	status    *rootStatus     // state of the reindexes, see IndexStatus
+	lowIO     bool            // go easy on the root, see utils.Config.LowIO
+	batchSize int             // notes indexed at once by IndexNotes
+	stale     time.Duration   // notes unchanged for longer are listed by is:stale
+	indexing  *sync.Mutex     // serializes IndexNotes and IndexFile
 }
 
-// Get path to the index
-func getIndexPath() string {
-	return path.Join(getDataPath(), "/index.bleve")
+// Get path to the index in the data dir
+func getIndexPath(dir string) string {
+	return filepath.Join(dir, "index.bleve")
 }
 
-// Get path to the fileinfos.json file
-func getFileInfosPath() string {
-	return path.Join(getDataPath(), "/fileinfos.json")
+// Get path to the fileinfos.json file in the data dir
+func getFileInfosPath(dir string) string {
+	return filepath.Join(dir, "fileinfos.json")
 }
 
 // NewBleveIndexer returns a new SearchIndexer
 func NewBleveIndexer(config *utils.Config) (bleveIndexer, error) {
-	if err := os.MkdirAll(getDataPath(), 0700); err != nil {
+	dataDir := config.IndexDir()
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
 		return bleveIndexer{}, err
 	}
-
-	index_path := getIndexPath()
-	index, err := GetIndex(index_path)
-	if err != nil {
+	if err := resetOutdatedIndex(dataDir, config.Stopwords); err != nil {
 		return bleveIndexer{}, err
 	}
 
-	return bleveIndexer{config.RootPath, config.Extensions, index, index_path}, nil
+	index_path := getIndexPath(dataDir)
+	var index bleve.Index
+	var encrypted *encryptedStore
+	if config.EncryptIndex {
+		passphrase, err := config.IndexPassphrase()
+		if err != nil {
+			return bleveIndexer{}, err
+		}
+		encrypted, index, err = openEncrypted(dataDir, passphrase, config.Stopwords)
+		if err != nil {
+			return bleveIndexer{}, err
+		}
+	} else {
+		var err error
+		if index, err = GetIndex(index_path, config.Stopwords); err != nil {
+			return bleveIndexer{}, err
+		}
+	}
+
+	return bleveIndexer{config.RootPath, config.NoteExtensions(), config.Types, index, index_path, config.ArchiveDir(), config.ScratchpadPath(), config.MinPrefixLength, config.MaxPrefixExpansions, config.EditDistance(), config.FolderBoosts(), config.Stopwords, encrypted, dataDir, &rootStatus{status: search.IndexStatus{Root: config.RootPath}}, config.LowIO(), config.IndexBatchSize, config.StalePeriod(), &sync.Mutex{}}, nil
 }
 
+// OpenIndex and CloseIndex hand the index over to other processes.
+// An encrypted index only lives in this process, so it stays open.
 func (s *bleveIndexer) OpenIndex() {
-	s.index, _ = GetIndex(s.indexPath)
+	if s.encrypted == nil {
+		s.index, _ = GetIndex(s.indexPath, s.stopwords)
+	}
 }
 
+// CloseIndex waits for a running reindex, e.g. one cancelled on quit, to
+// stop first, and writes the encrypted snapshot if a save is pending.
 func (s *bleveIndexer) CloseIndex() {
-	s.index.Close()
+	s.indexing.Lock()
+	defer s.indexing.Unlock()
+	if s.encrypted == nil {
+		s.index.Close()
+	} else if err := s.encrypted.flush(); err != nil {
+		slog.Error("saving the encrypted index failed", "err", err)
+	}
+}
+
+// DocCount returns the number of indexed notes.
+func (s *bleveIndexer) DocCount() (uint64, error) {
+	return s.index.DocCount()
 }
 
 // Reindex all the notes.
@@ -73,52 +130,566 @@ func (s *bleveIndexer) CloseIndex() {
 // It compares all the file in the rootPath with the ones in the metadata file.
 // If the file is new or modified, it is indexed. If the file is deleted,
 // it is removed from the index.
-func (s *bleveIndexer) IndexNotes() {
-	old, err := readFileInfos(getFileInfosPath())
-	if err == fs.ErrNotExist {
-		old = make([]FileInfo, 0)
+//
+// Once ctx is done no more notes are read. The batch read so far is still
+// indexed, but the file infos are left as they were, so the next reindex
+// goes over the same changes again.
+func (s *bleveIndexer) IndexNotes(ctx context.Context) {
+	s.indexing.Lock()
+	defer s.indexing.Unlock()
+
+	start := time.Now()
+	s.status.start()
+
+	// An unreachable root, e.g. an unmounted network share, would look
+	// like all its notes were deleted.
+	if err := reachable(s.notesRoot, rootTimeout); err != nil {
+		slog.Error("skipping unreachable notes root", "root", s.notesRoot, "err", err)
+		s.status.finish(0, 0, time.Since(start), err)
+		return
 	}
 
-	currentPaths, _ := getListOfNotes(s.notesRoot, s.extensions)
+	old := s.readFileInfos()
 
-	current := lo.Map(currentPaths, func(path string, _ int) FileInfo {
+	paths, denied := s.walkNotes()
+	current := withCreated(lo.Map(paths, func(path string, _ int) FileInfo {
 		fileInfo, _ := getFileInfoForFile(path)
 		return fileInfo
-	})
+	}), old)
 
 	deleted, modified, created := compareFileInfos(old, current)
 	toIndex := append(modified, created...)
+	s.status.toIndex(len(current), len(toIndex))
 
-	var wg sync.WaitGroup
+	// Notes that couldn't be read or indexed keep their old file info, or
+	// are left out of the file infos if they're new, so they're tried
+	// again next time.
+	var failed []string
+	var failedMu sync.Mutex
 
-	wg.Add(len(deleted) + len(toIndex))
+	batch := s.index.NewBatch()
+	var batched []string // paths of the notes in the batch
+	// flush indexes the batch once it holds batchSize notes, or whatever
+	// it holds when forced.
+	flush := func(force bool) {
+		if batch.Size() == 0 || (!force && batch.Size() < s.batchSize) {
+			return
+		}
+		if err := s.index.Batch(batch); err != nil {
+			slog.Error("indexing a batch failed", "notes", batch.Size(), "err", err)
+			s.status.failed(batch.Size())
+			failedMu.Lock()
+			failed = append(failed, batched...)
+			failedMu.Unlock()
+		}
+		batch.Reset()
+		batched = nil
+	}
 
 	for _, fi := range deleted {
-		go func(fi FileInfo) {
-			defer wg.Done()
-			s.index.Delete(fi.Path)
-		}(fi)
+		if s.encrypted != nil {
+			s.encrypted.remove(fi.Path)
+		}
+		batch.Delete(fi.Path)
+		batched = append(batched, fi.Path)
+		flush(false)
+		s.status.deleted()
 	}
 
-	for _, fi := range toIndex {
-		go func(fi FileInfo) {
+	// The notes are read and parsed by a few workers, and indexed in
+	// batches as they come. In low I/O mode only a few are read at a time.
+	queue := make(chan FileInfo)
+	read := make(chan Note)
+	var wg sync.WaitGroup
+	var deniedMu sync.Mutex
+	for i := 0; i < lo.Ternary(s.lowIO, lowIOReads, runtime.NumCPU()); i++ {
+		wg.Add(1)
+		go func() {
 			defer wg.Done()
-			body, _ := os.ReadFile(fi.Path)
-			s.index.Index(fi.Path, Note{Path: fi.Path, Body: string(body), ModTime: fi.ModTime})
-		}(fi)
+			for fi := range queue {
+				body, err := os.ReadFile(fi.Path)
+				if errors.Is(err, fs.ErrPermission) {
+					deniedMu.Lock()
+					denied = append(denied, fi.Path)
+					deniedMu.Unlock()
+					continue
+				}
+				if err != nil {
+					slog.Error("reading a note failed", "path", fi.Path, "err", err)
+					failedMu.Lock()
+					failed = append(failed, fi.Path)
+					failedMu.Unlock()
+					s.status.failed(1)
+					continue
+				}
+				read <- s.newNote(fi, body)
+			}
+		}()
+	}
+	go func() {
+	feed:
+		for _, fi := range toIndex {
+			select {
+			case queue <- fi:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(queue)
+		wg.Wait()
+		close(read)
+	}()
+
+	for note := range read {
+		// The workers are drained once cancelled.
+		if ctx.Err() != nil {
+			continue
+		}
+		if note.ignored {
+			if s.encrypted != nil {
+				s.encrypted.remove(note.Path)
+			}
+			batch.Delete(note.Path)
+			batched = append(batched, note.Path)
+			flush(false)
+			s.status.indexed()
+			continue
+		}
+		if s.encrypted != nil {
+			s.encrypted.put(note)
+		}
+		if err := batch.Index(note.Path, note); err != nil {
+			slog.Error("indexing failed", "path", note.Path, "err", err)
+			s.status.failed(1)
+			failedMu.Lock()
+			failed = append(failed, note.Path)
+			failedMu.Unlock()
+		} else {
+			batched = append(batched, note.Path)
+		}
+		flush(false)
+		s.status.indexed()
+	}
+	flush(true)
+
+	if err := ctx.Err(); err != nil {
+		slog.Info("reindex cancelled", "root", s.notesRoot, "ms", time.Since(start).Milliseconds())
+		s.status.finish(0, 0, time.Since(start), err)
+		return
 	}
 
-	wg.Wait()
+	// A note that can no longer be read keeps its old file info too, along
+	// with what was indexed of it.
+	retry := append(denied, failed...)
+	current = lo.Filter(current, func(fi FileInfo, _ int) bool {
+		return !lo.Contains(retry, fi.Path)
+	})
+	current = append(current, lo.Filter(old, func(fi FileInfo, _ int) bool {
+		return lo.Contains(retry, fi.Path)
+	})...)
+	if err := s.storeFileInfos(current); err != nil {
+		slog.Error("storing the file infos failed", "err", err)
+	}
+	if len(denied) > 0 {
+		slog.Warn("skipped files and folders without read permission", "root", s.notesRoot, "count", len(denied),
+			"paths", denied[:lo.Min([]int{len(denied), 20})])
+	}
+	slog.Info("indexed notes", "root", s.notesRoot, "added", len(created), "updated", len(modified),
+		"deleted", len(deleted), "skipped", len(denied), "ms", time.Since(start).Milliseconds())
+	s.status.finish(len(current), len(denied), time.Since(start), nil)
+}
+
+// IndexFile indexes the note at path alone, or removes it from the index
+// if it's gone. Paths that aren't notes of the root are ignored.
+func (s *bleveIndexer) IndexFile(path string) error {
+	if !s.isNote(path) {
+		return nil
+	}
+
+	s.indexing.Lock()
+	defer s.indexing.Unlock()
+
+	old := s.readFileInfos()
+	fileInfos := lo.Filter(old, func(fi FileInfo, _ int) bool { return fi.Path != path })
+	fi, err := getFileInfoForFile(path)
+	if err == nil {
+		fi = withCreated([]FileInfo{fi}, old)[0]
+	}
+	switch {
+	case os.IsNotExist(err):
+		if err := s.deleteNote(path); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := s.indexNote(s.newNote(fi, body)); err != nil {
+			return err
+		}
+		fileInfos = append(fileInfos, fi)
+	}
+	return s.storeFileInfos(fileInfos)
+}
+
+// isNote tells whether path is one of the notes of the root, whether or
+// not it exists. Notes in a folder kept out of the index aren't.
+func (s *bleveIndexer) isNote(path string) bool {
+	if path == s.scratchpad {
+		return true
+	}
+	rel, err := filepath.Rel(s.notesRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return notes.IsNote(s.notesRoot, path, s.extensions) && !notes.InNoIndexFolder(s.notesRoot, path)
+}
+
+// IndexStatus returns the state of the reindex of the root.
+func (s *bleveIndexer) IndexStatus() []search.IndexStatus {
+	s.status.mu.Lock()
+	defer s.status.mu.Unlock()
+	return []search.IndexStatus{s.status.status}
+}
+
+// Notes read at once in low I/O mode.
+const lowIOReads = 2
+
+// How long the notes root has to answer before a reindex skips it.
+const rootTimeout = 10 * time.Second
+
+// Reachable checks that the notes root can be read, e.g. that the drive
+// it's on is mounted.
+func Reachable(root string) error {
+	return reachable(root, rootTimeout)
+}
+
+// reachable checks that root can be read within timeout. A hung network
+// mount blocks the check's goroutine rather than the reindex.
+func reachable(root string, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := os.ReadDir(root)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("%s didn't answer within %s", root, timeout)
+	}
+}
+
+// rootStatus tracks the reindexes of the root, shared by the copies of
+// the indexer.
+type rootStatus struct {
+	mu     sync.Mutex
+	status search.IndexStatus
+}
+
+func (r *rootStatus) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Indexing = true
+	r.status.Deleted, r.status.Errors = 0, 0
+}
+
+// toIndex records how many notes the running reindex found once it walked
+// the root, and how many of them it indexes.
+func (r *rootStatus) toIndex(scanned, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Scanned, r.status.Indexed, r.status.ToIndex = scanned, 0, n
+}
+
+// indexed counts a note the running reindex has indexed.
+func (r *rootStatus) indexed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Indexed++
+}
 
-	err = StoreFileInfos(getFileInfosPath(), current)
+// deleted counts a note the running reindex has removed from the index.
+func (r *rootStatus) deleted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Deleted++
+}
+
+// failed counts n notes the running reindex failed to read or index.
+func (r *rootStatus) failed(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Errors += n
+}
+
+func (r *rootStatus) finish(notes, skipped int, took time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.Indexing, r.status.Took, r.status.Err, r.status.Skipped = false, took, "", skipped
+	r.status.Scanned, r.status.Indexed, r.status.ToIndex, r.status.Deleted = 0, 0, 0, 0
+	if err != nil {
+		r.status.Err = err.Error()
+		return
+	}
+	r.status.Notes = notes
+}
+
+// readFileInfos returns the metadata of the notes as last indexed.
+func (s *bleveIndexer) readFileInfos() []FileInfo {
+	if s.encrypted != nil {
+		return s.encrypted.readFileInfos()
+	}
+	fi, err := readFileInfos(getFileInfosPath(s.dataDir))
+	if err != nil {
+		return make([]FileInfo, 0)
+	}
+	return fi
+}
+
+// storeFileInfos records the metadata of the indexed notes. An encrypted
+// index is saved along with it.
+func (s *bleveIndexer) storeFileInfos(fi []FileInfo) error {
+	if s.encrypted != nil {
+		return s.encrypted.save(fi)
+	}
+	return StoreFileInfos(getFileInfosPath(s.dataDir), fi)
+}
+
+// indexNote indexes the note, or deletes it from the index if it's ignored.
+func (s *bleveIndexer) indexNote(note Note) error {
+	if note.ignored {
+		return s.deleteNote(note.Path)
+	}
+	if s.encrypted != nil {
+		s.encrypted.put(note)
+	}
+	return s.index.Index(note.Path, note)
+}
+
+func (s *bleveIndexer) deleteNote(path string) error {
+	if s.encrypted != nil {
+		s.encrypted.remove(path)
+	}
+	return s.index.Delete(path)
+}
+
+// IndexSize returns the bytes the index of the config takes on disk,
+// along with its metadata.
+func IndexSize(config *utils.Config) int64 {
+	dir := config.IndexDir()
+	size := int64(0)
+	for _, path := range indexFiles(dir) {
+		filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				if info, err := d.Info(); err == nil {
+					size += info.Size()
+				}
+			}
+			return nil
+		})
+	}
+	return size
+}
+
+// notePaths lists the notes to index: the notes under the root and the
+// scratchpad if it exists.
+func (s *bleveIndexer) notePaths() []string {
+	paths, _ := s.walkNotes()
+	return paths
+}
+
+// walkNotes lists the notes like notePaths, along with the files and
+// folders the walk isn't allowed to read.
+func (s *bleveIndexer) walkNotes() (paths, denied []string) {
+	slog.Debug("listing notes", "root", s.notesRoot, "extensions", s.extensions)
+	return notes.Walk(s.notesRoot, s.extensions, s.scratchpad, s.lowIO)
+}
+
+// Random picks a random note that isn't archived.
+func (s *bleveIndexer) Random() search.SearchResult {
+	all := withArchiveFilter(bleve.NewMatchAllQuery(), false)
+
+	count, err := s.index.Search(bleve.NewSearchRequestOptions(all, 0, 0, false))
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+	if count.Total == 0 {
+		return search.SearchResult{Hits: []search.DocumentMatch{}}
+	}
+
+	offset := rand.New(rand.NewSource(time.Now().UnixNano())).Intn(int(count.Total))
+	pick, err := s.index.Search(bleve.NewSearchRequestOptions(all, 1, offset, false))
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+
+	return search.SearchResult{
+		Hits: lo.Map(pick.Hits, func(hit *bleveSearch.DocumentMatch, _ int) search.DocumentMatch {
+			return search.DocumentMatch{Path: hit.ID, Content: "..."}
+		}),
+	}
+}
+
+// newNote builds the document indexed for the file.
+func (s *bleveIndexer) newNote(fi FileInfo, body []byte) Note {
+	relPath, err := filepath.Rel(s.notesRoot, fi.Path)
+	if err != nil {
+		relPath = fi.Path
+	}
+
+	// An ignored note is deleted from the index instead of indexed.
+	if isIgnored(fi.Path, body) {
+		return Note{Path: fi.Path, ignored: true}
+	}
+
+	// Private notes are only found by their title, the rest of them
+	// stays out of the index.
+	if !notes.IsAttachment(fi.Path) && notes.IsPrivate(string(body)) {
+		return Note{
+			Path:     fi.Path,
+			RelPath:  filepath.ToSlash(relPath),
+			Title:    notes.PrivateTitle(fi.Path, string(body)),
+			Hash:     hashOf(body),
+			Size:     len(body),
+			Type:     utils.TypeOf(s.types, fi.Path),
+			Created:  fi.Created,
+			ModTime:  fi.ModTime,
+			Archived: s.isArchived(fi.Path),
+			Private:  true,
+		}
+	}
+
+	// Logseq pages are indexed by block, without the bullets and the
+	// property lines, and with the block references resolved.
+	text, title, properties := string(body), "", map[string]string{}
+	meta := frontmatter.Parse(text)
+	// Attachments are found by their name, their content is noise.
+	if notes.IsAttachment(fi.Path) {
+		text = filepath.Base(fi.Path)
+	} else if strings.EqualFold(filepath.Ext(fi.Path), ".md") && logseq.IsOutline(text) {
+		page := logseq.Parse(text)
+		text, title, properties = page.Text(), page.Title, page.Properties
+		meta.Tags = page.Tags()
+	} else {
+		title, properties = meta.Title, frontmatter.Fields(text)
+	}
+	var date *time.Time
+	if !meta.Date.IsZero() {
+		date = &meta.Date
+	}
+
+	return Note{
+		Path:       fi.Path,
+		RelPath:    filepath.ToSlash(relPath),
+		Title:      title,
+		Properties: properties,
+		Body:       text,
+		Hash:       hashOf(body),
+		Size:       len(body),
+		Words:      len(strings.Fields(string(body))),
+		Lang:       detectLang(string(body)),
+		Type:       utils.TypeOf(s.types, fi.Path),
+		Tags:       meta.Tags,
+		Date:       date,
+		Aliases:    meta.Aliases,
+		Links:      notes.LinkedNames(string(body)),
+		Created:    fi.Created,
+		ModTime:    fi.ModTime,
+		Archived:   s.isArchived(fi.Path),
+		Dates:      propertyDates(properties),
+	}
+}
+
+// isIgnored tells whether the note asks to stay out of the index with
+// "notes_search: ignore" in its frontmatter.
+func isIgnored(path string, body []byte) bool {
+	return !notes.IsAttachment(path) && strings.EqualFold(frontmatter.Fields(string(body))["notes_search"], "ignore")
+}
+
+// propertyDates returns the properties whose value is a date, see
+// frontmatter.DateLayouts.
+func propertyDates(properties map[string]string) map[string]time.Time {
+	dates := map[string]time.Time{}
+	for key, value := range properties {
+		if date, ok := frontmatter.ParseDate(value); ok {
+			dates[key] = date
+		}
+	}
+	return dates
+}
+
+// isArchived reports whether the note lives in the archive folder.
+func (s *bleveIndexer) isArchived(path string) bool {
+	return strings.HasPrefix(path, s.archiveDir+string(filepath.Separator))
 }
 
 // Search searches the index for the given query.
 // If the length of the query is less than 3, it returns all the notes.
-func (s *bleveIndexer) Search(query string) search.SearchResult {
-	queryLen := len(query)
-	if queryLen > 0 && query[queryLen-1] != ' ' {
-		query = query + "*"
+// Archived notes are only returned for is:archived queries.
+func (s *bleveIndexer) Search(ctx context.Context, input string) search.SearchResult {
+	return s.SearchPage(ctx, input, 0, search.DefaultSize)
+}
+
+// SearchPage returns size hits of the query starting at from.
+func (s *bleveIndexer) SearchPage(ctx context.Context, input string, from, size int) search.SearchResult {
+	start := time.Now()
+	searchRequest, query := s.newSearchRequest(ctx, input)
+	searchRequest.From = from
+	searchRequest.Size = size
+	searchRequest.IncludeLocations = true
+	searchRequest.Fields = hitFields
+
+	// Hits of the boosted page in order, nil without boosts.
+	var order []string
+	if len(s.boosts) > 0 && len(query) >= 3 {
+		searchRequest.Query, order = s.boostedPage(ctx, searchRequest.Query, from, size)
+		searchRequest.From = 0
+	}
+
+	searchResult, err := s.index.SearchInContext(ctx, searchRequest)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		s.logQuery(input, from, 0, time.Since(start), err)
+		return search.SearchResult{
+			Hits: []search.DocumentMatch{},
+			Err:  err,
+		}
+	}
+
+	if order != nil {
+		sort.SliceStable(searchResult.Hits, func(i, j int) bool {
+			return lo.IndexOf(order, searchResult.Hits[i].ID) < lo.IndexOf(order, searchResult.Hits[j].ID)
+		})
+	}
+
+	result := s.withReferences(ctx, toSearchResult(searchResult))
+	s.logQuery(input, from, len(result.Hits), time.Since(start), nil)
+	return result
+}
+
+// newSearchRequest builds the request of the query with its filters,
+// along with the query string searched.
+func (s *bleveIndexer) newSearchRequest(ctx context.Context, input string) (*bleve.SearchRequest, string) {
+	parsed := search.ParseQuery(input)
+	// Rank and highlight by the words of a lone proximity phrase.
+	if strings.TrimSpace(parsed.Text) == "" && len(parsed.Proximity) > 0 {
+		phrases := lo.Map(parsed.Proximity, func(p search.Proximity, _ int) string { return p.Phrase })
+		parsed.Text = strings.Join(phrases, " ") + " "
+	}
+	query := s.withPrefix(parsed.Text)
+	switch {
+	case len(query) < 3:
+	case parsed.Fuzzy:
+		query = withFuzziness(parsed.Text, s.fuzziness, nil)
+	case len(parsed.FuzzyWords) > 0:
+		query = withFuzziness(query, s.fuzziness, parsed.FuzzyWords)
 	}
 	bleveQuery := bleve.NewQueryStringQuery(query)
 	searchRequest := bleve.NewSearchRequest(bleveQuery)
@@ -126,19 +697,183 @@ func (s *bleveIndexer) Search(query string) search.SearchResult {
 
 	if len(query) < 3 {
 		searchRequest = bleve.NewSearchRequest(bleve.NewMatchAllQuery())
-		searchRequest.SortBy([]string{"-ModTime"})
+		// Stale notes are reviewed from the oldest.
+		searchRequest.SortBy([]string{lo.Ternary(parsed.Stale, "ModTime", "-ModTime")})
+	}
+
+	if len(query) >= 3 {
+		searchRequest.Query = withAliasBoost(searchRequest.Query, parsed.Text)
+	}
+	searchRequest.Query = withExclusions(searchRequest.Query, parsed.Exclude)
+	searchRequest.Query = withPathFilter(searchRequest.Query, parsed.Paths)
+	searchRequest.Query = withRanges(searchRequest.Query, parsed.Ranges)
+	searchRequest.Query = withLangFilter(searchRequest.Query, parsed.Langs)
+	searchRequest.Query = withTypeFilter(searchRequest.Query, parsed.Types)
+	searchRequest.Query = withExtFilter(searchRequest.Query, parsed.Exts)
+	searchRequest.Query = withTagFilter(searchRequest.Query, parsed.Tags)
+	if parsed.Stale {
+		searchRequest.Query = withStaleFilter(searchRequest.Query, time.Now().Add(-s.stale))
 	}
+	searchRequest.Query = withModifiedFilter(searchRequest.Query, parsed.After, parsed.Before)
+	searchRequest.Query = withCreatedFilter(searchRequest.Query, parsed.CreatedAfter, parsed.CreatedBefore)
+	searchRequest.Query = s.withProximity(ctx, searchRequest.Query, parsed.Proximity)
+	searchRequest.Query = withArchiveFilter(searchRequest.Query, parsed.Archived)
+	return searchRequest, query
+}
+
+// Activity counts the notes by the local days they were created and last
+// modified on, archived ones included. A note created and modified on the
+// same day counts once.
+func (s *bleveIndexer) Activity(ctx context.Context, since time.Time) (map[string]int, error) {
+	const page = 1000
+	modified := bleve.NewDateRangeQuery(since, time.Time{})
+	modified.SetField("ModTime")
+	created := bleve.NewDateRangeQuery(since, time.Time{})
+	created.SetField("Created")
+	touched := bleve.NewDisjunctionQuery(modified, created)
 
-	searchRequest.Size = 100
-	searchResult, err := s.index.Search(searchRequest)
+	counts := map[string]int{}
+	for from := 0; ; from += page {
+		request := bleve.NewSearchRequestOptions(touched, page, from, false)
+		// Scores tie across pages, the order of the ids doesn't.
+		request.SortBy([]string{"_id"})
+		request.Fields = []string{"ModTime", "Created"}
+		result, err := s.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range result.Hits {
+			days := map[string]bool{}
+			for _, field := range request.Fields {
+				value, _ := hit.Fields[field].(string)
+				if at, err := time.Parse(time.RFC3339, value); err == nil && !at.Before(since) {
+					days[at.Local().Format("2006-01-02")] = true
+				}
+			}
+			for day := range days {
+				counts[day]++
+			}
+		}
+		if len(result.Hits) < page {
+			return counts, nil
+		}
+	}
+}
 
+// Most tags counted by Tags.
+const maxTags = 1000
+
+// Tags counts the tags of the notes matching the query, most used first.
+// Tags are lowercased, the way they're matched.
+func (s *bleveIndexer) Tags(ctx context.Context, input string) ([]search.TagCount, error) {
+	request, _ := s.newSearchRequest(ctx, input)
+	request.Size = 0
+	request.Highlight = nil
+	request.AddFacet("tags", bleve.NewFacetRequest("Tags", maxTags))
+
+	result, err := s.index.SearchInContext(ctx, request)
 	if err != nil {
-		return search.SearchResult{
-			Hits: []search.DocumentMatch{},
-			Err:  err,
+		return nil, err
+	}
+	tags := []search.TagCount{}
+	if facet := result.Facets["tags"]; facet != nil && facet.Terms != nil {
+		for _, term := range facet.Terms.Terms() {
+			tags = append(tags, search.TagCount{Tag: term.Term, Count: term.Count})
 		}
 	}
+	return tags, nil
+}
+
+// Queries taking longer are logged as warnings.
+const slowQuery = 250 * time.Millisecond
+
+// logQuery logs the latency of a query, at debug level unless it's slow
+// or failed. The query itself is left out of the log of an encrypted index.
+func (s *bleveIndexer) logQuery(query string, from, hits int, took time.Duration, err error) {
+	if s.encrypted != nil {
+		query = "(encrypted)"
+	}
+	level := slog.LevelDebug
+	if took >= slowQuery {
+		level = slog.LevelWarn
+	}
+	attrs := []any{"query", query, "from", from, "hits", hits, "ms", took.Milliseconds()}
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, "err", err)
+	}
+	slog.Log(context.Background(), level, "search", attrs...)
+}
+
+// boostWindow is how many of the best hits are ranked again with the
+// folder boosts. Hits past it keep their place.
+const boostWindow = 500
+
+// boostedPage restricts q to the hits on the page from, size once the
+// folder boosts are applied, and returns them in order. The ranking is
+// done on scores alone, so the highlighting is only computed for the page.
+func (s *bleveIndexer) boostedPage(ctx context.Context, q query.Query, from, size int) (query.Query, []string) {
+	ranking := bleve.NewSearchRequestOptions(q, lo.Max([]int{boostWindow, from + size}), 0, false)
+	ranked, err := s.index.SearchInContext(ctx, ranking)
+	if err != nil {
+		return q, nil
+	}
 
+	s.applyBoosts(ranked.Hits)
+	page := ranked.Hits[lo.Min([]int{from, len(ranked.Hits)}):lo.Min([]int{from + size, len(ranked.Hits)})]
+	ids := lo.Map(page, func(hit *bleveSearch.DocumentMatch, _ int) string { return hit.ID })
+	return bleve.NewConjunctionQuery(q, bleve.NewDocIDQuery(ids)), ids
+}
+
+// applyBoosts scales the score of the hits by the boost of their folder
+// and sorts them again. The longest matching folder wins.
+func (s *bleveIndexer) applyBoosts(hits bleveSearch.DocumentMatchCollection) {
+	for _, hit := range hits {
+		rel, err := filepath.Rel(s.notesRoot, hit.ID)
+		if err != nil {
+			continue
+		}
+		rel = strings.ToLower(filepath.ToSlash(rel))
+
+		prefix := ""
+		for folder := range s.boosts {
+			if strings.HasPrefix(rel, folder) && len(folder) > len(prefix) {
+				prefix = folder
+			}
+		}
+		if prefix != "" {
+			hit.Score *= s.boosts[prefix]
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+}
+
+// withReferences lists the notes linking to the attachments among the hits.
+func (s *bleveIndexer) withReferences(ctx context.Context, result search.SearchResult) search.SearchResult {
+	for i, hit := range result.Hits {
+		if !notes.IsAttachment(hit.Path) {
+			continue
+		}
+
+		linked := bleve.NewTermQuery(strings.ToLower(filepath.Base(hit.Path)))
+		linked.SetField("Links")
+		referencing, err := s.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(withArchiveFilter(linked, false), 100, 0, false))
+		if err != nil {
+			continue
+		}
+		result.Hits[i].ReferencedBy = lo.Map(referencing.Hits, func(hit *bleveSearch.DocumentMatch, _ int) string {
+			return hit.ID
+		})
+	}
+	return result
+}
+
+// hitFields are the stored fields toSearchResult needs.
+var hitFields = []string{"Title", "Hash", "Size", "Private"}
+
+// toSearchResult converts the hits of bleve, with the first highlighted
+// fragment of the body as the content.
+func toSearchResult(searchResult *bleve.SearchResult) search.SearchResult {
 	var getFragment = func(hit *bleveSearch.DocumentMatch) string {
 		content := "..."
 		body := hit.Fragments["Body"]
@@ -150,9 +885,22 @@ func (s *bleveIndexer) Search(query string) search.SearchResult {
 
 	result := search.SearchResult{
 		Hits: lo.Map(searchResult.Hits, func(hit *bleveSearch.DocumentMatch, _ int) search.DocumentMatch {
+			title, _ := hit.Fields["Title"].(string)
+			hash, _ := hit.Fields["Hash"].(string)
+			// Empty notes are all alike, but not copies of each other.
+			if size, _ := hit.Fields["Size"].(float64); size == 0 {
+				hash = ""
+			}
+			if private, _ := hit.Fields["Private"].(bool); private {
+				return search.DocumentMatch{Path: hit.ID, Title: title, Score: hit.Score, Hash: hash, Private: true}
+			}
 			return search.DocumentMatch{
 				Path:    hit.ID,
 				Content: getFragment(hit),
+				Matches: countMatches(hit),
+				Title:   title,
+				Score:   hit.Score,
+				Hash:    hash,
 			}
 		}),
 		Err: nil,
@@ -161,36 +909,278 @@ func (s *bleveIndexer) Search(query string) search.SearchResult {
 	return result
 }
 
-// GetIndex returns the index if it exists or creates a new one if it doesn't.
-func GetIndex(path string) (bleve.Index, error) {
-	index, err := bleve.Open(path)
+// countMatches returns how often the query terms occur in the body.
+func countMatches(hit *bleveSearch.DocumentMatch) int {
+	count := 0
+	for _, locations := range hit.Locations["Body"] {
+		count += len(locations)
+	}
+	return count
+}
 
-	if err == bleve.ErrorIndexPathDoesNotExist {
-		mapping := bleve.NewIndexMapping()
-		index, err = bleve.New(path, mapping)
+// withPrefix makes the last word of the query a prefix search while it is
+// being typed, i.e. not followed by a space. Prefixes shorter than
+// minPrefix or matching more than maxExpansions terms are searched as
+// whole words instead, as expanding them is slow on big indexes.
+func (s *bleveIndexer) withPrefix(query string) string {
+	if query == "" || strings.HasSuffix(query, " ") {
+		return query
 	}
 
-	if err == nil {
-		return index, nil
+	words := strings.Fields(query)
+	last := words[len(words)-1]
+	// Drop the operators and the field of "+field:word".
+	if _, value, found := strings.Cut(last, ":"); found {
+		last = value
+	}
+	last = strings.ToLower(strings.TrimLeft(last, "+-"))
+
+	if utf8.RuneCountInString(last) < s.minPrefix || strings.ContainsAny(last, "*?\"~^/()") {
+		return query
+	}
+	if s.maxExpansions > 0 && s.prefixExpansions(last, s.maxExpansions+1) > s.maxExpansions {
+		return query
+	}
+	return query + "*"
+}
+
+// withFuzziness lets the plain words of the query match the words at most
+// distance edits away, e.g. kuberntes~1 for kuberntes, all of them unless
+// only lists some. Words with a field or syntax of their own are left as
+// they are.
+func withFuzziness(query string, distance int, only []string) string {
+	words := strings.Fields(query)
+	for i, word := range words {
+		if strings.Trim(word, "+-") == "" || strings.ContainsAny(word, "*?\"~^/():\\") {
+			continue
+		}
+		if len(only) > 0 && !lo.Contains(only, strings.TrimLeft(word, "+")) {
+			continue
+		}
+		words[i] = fmt.Sprintf("%s~%d", word, distance)
+	}
+	return strings.Join(words, " ")
+}
+
+// prefixExpansions counts the indexed terms starting with prefix,
+// stopping at limit.
+func (s *bleveIndexer) prefixExpansions(prefix string, limit int) int {
+	dict, err := s.index.FieldDictPrefix("_all", []byte(prefix))
+	if err != nil {
+		return 0
 	}
+	defer dict.Close()
 
-	mapping := bleve.NewIndexMapping()
-	index, err = bleve.New(path, mapping)
-	return index, err
+	count := 0
+	for count < limit {
+		entry, err := dict.Next()
+		if err != nil || entry == nil {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// withAliasBoost ranks the notes having the query as an alias first,
+// so an alias resolves to its note.
+func withAliasBoost(q query.Query, text string) query.Query {
+	alias := bleve.NewMatchPhraseQuery(strings.TrimSpace(text))
+	alias.SetField("Aliases")
+	alias.SetBoost(10)
+
+	boosted := bleve.NewBooleanQuery()
+	boosted.AddMust(q)
+	boosted.AddShould(alias)
+	return boosted
+}
+
+// withExclusions leaves out the notes matching any of the terms.
+func withExclusions(q query.Query, terms []string) query.Query {
+	if len(terms) == 0 {
+		return q
+	}
+
+	filtered := bleve.NewBooleanQuery()
+	filtered.AddMust(q)
+	for _, term := range terms {
+		filtered.AddMustNot(bleve.NewMatchQuery(term))
+	}
+	return filtered
 }
 
-// getListOfNotes returns a list of all the notes in the given directory
-func getListOfNotes(src string, extensions []string) (paths []string, err error) {
-	return glob(src, func(path string) bool {
-		ext := filepath.Ext(path)
+// withPathFilter restricts q to notes whose relative path starts with
+// every one of the prefixes, e.g. projects/ for a subtree, case
+// insensitively. A prefix with * or ? is a glob the whole path must match.
+func withPathFilter(q query.Query, paths []string) query.Query {
+	if len(paths) == 0 {
+		return q
+	}
 
-		log.Println("exetnsions to filter by ")
-		for _, e := range extensions {
-			log.Println(e)
+	conjuncts := []query.Query{q}
+	for _, p := range paths {
+		p = strings.TrimPrefix(strings.ToLower(filepath.ToSlash(p)), "/")
+		if strings.ContainsAny(p, "*?") {
+			wildcard := bleve.NewWildcardQuery(p)
+			wildcard.SetField("RelPath")
+			conjuncts = append(conjuncts, wildcard)
+			continue
 		}
-		log.Println("-------")
-		return lo.Contains(extensions, ext)
-	}), nil
+		prefix := bleve.NewPrefixQuery(p)
+		prefix.SetField("RelPath")
+		conjuncts = append(conjuncts, prefix)
+	}
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+// withLangFilter restricts q to notes written in any of the languages.
+func withLangFilter(q query.Query, langs []string) query.Query {
+	if len(langs) == 0 {
+		return q
+	}
+
+	disjuncts := lo.Map(langs, func(lang string, _ int) query.Query {
+		term := bleve.NewTermQuery(lang)
+		term.SetField("Lang")
+		return term
+	})
+	return bleve.NewConjunctionQuery(q, bleve.NewDisjunctionQuery(disjuncts...))
+}
+
+// withTypeFilter restricts q to notes of any of the extension groups.
+func withTypeFilter(q query.Query, types []string) query.Query {
+	if len(types) == 0 {
+		return q
+	}
+
+	disjuncts := lo.Map(types, func(name string, _ int) query.Query {
+		term := bleve.NewTermQuery(name)
+		term.SetField("Type")
+		return term
+	})
+	return bleve.NewConjunctionQuery(q, bleve.NewDisjunctionQuery(disjuncts...))
+}
+
+// withExtFilter restricts q to notes with any of the extensions.
+func withExtFilter(q query.Query, exts []string) query.Query {
+	if len(exts) == 0 {
+		return q
+	}
+
+	disjuncts := lo.Map(exts, func(ext string, _ int) query.Query {
+		wildcard := bleve.NewWildcardQuery("*." + ext)
+		wildcard.SetField("RelPath")
+		return wildcard
+	})
+	return bleve.NewConjunctionQuery(q, bleve.NewDisjunctionQuery(disjuncts...))
+}
+
+// withTagFilter restricts q to notes tagged with all of the tags. Each
+// tag is matched whole and case insensitively against the Tags field the
+// tag facet counts, so project/alpha doesn't match [project, alpha].
+func withTagFilter(q query.Query, tags []string) query.Query {
+	if len(tags) == 0 {
+		return q
+	}
+
+	conjuncts := []query.Query{q}
+	for _, tag := range tags {
+		term := bleve.NewTermQuery(strings.ToLower(tag))
+		term.SetField("Tags")
+		conjuncts = append(conjuncts, term)
+	}
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+// withStaleFilter restricts q to notes last modified before cutoff.
+func withStaleFilter(q query.Query, cutoff time.Time) query.Query {
+	stale := bleve.NewDateRangeQuery(time.Time{}, cutoff)
+	stale.SetField("ModTime")
+	return bleve.NewConjunctionQuery(q, stale)
+}
+
+// withModifiedFilter restricts q to notes last modified from after on and
+// before before, leaving out either bound when zero.
+func withModifiedFilter(q query.Query, after, before time.Time) query.Query {
+	if after.IsZero() && before.IsZero() {
+		return q
+	}
+	modified := bleve.NewDateRangeQuery(after, before)
+	modified.SetField("ModTime")
+	return bleve.NewConjunctionQuery(q, modified)
+}
+
+// withCreatedFilter restricts q to notes created from after on and before
+// before, leaving out either bound when zero.
+func withCreatedFilter(q query.Query, after, before time.Time) query.Query {
+	if after.IsZero() && before.IsZero() {
+		return q
+	}
+	created := bleve.NewDateRangeQuery(after, before)
+	created.SetField("Created")
+	return bleve.NewConjunctionQuery(q, created)
+}
+
+// rangeFields maps the fields of search.Range to the Note fields.
+var rangeFields = map[string]string{"words": "Words", "size": "Size"}
+
+// withRanges restricts q to notes whose numeric fields are in the ranges.
+func withRanges(q query.Query, ranges []search.Range) query.Query {
+	if len(ranges) == 0 {
+		return q
+	}
+
+	conjuncts := []query.Query{q}
+	for _, r := range ranges {
+		var min, max *float64
+		value := r.Value
+		inclusive := strings.Contains(r.Op, "=")
+		if r.Op != "<" && r.Op != "<=" {
+			min = &value
+		}
+		if r.Op != ">" && r.Op != ">=" {
+			max = &value
+		}
+
+		numeric := bleve.NewNumericRangeInclusiveQuery(min, max, &inclusive, &inclusive)
+		numeric.SetField(rangeFields[r.Field])
+		conjuncts = append(conjuncts, numeric)
+	}
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+// withArchiveFilter restricts q to archived or to regular notes.
+// Notes indexed before archiving existed have no Archived field,
+// so regular notes are matched by excluding archived ones.
+func withArchiveFilter(q query.Query, archived bool) query.Query {
+	archivedQuery := bleve.NewBoolFieldQuery(true)
+	archivedQuery.SetField("Archived")
+
+	filtered := bleve.NewBooleanQuery()
+	if archived {
+		filtered.AddMust(q, archivedQuery)
+		return filtered
+	}
+
+	filtered.AddMust(q)
+	filtered.AddMustNot(archivedQuery)
+	return filtered
+}
+
+// GetIndex returns the index if it exists or creates a new one if it doesn't.
+// stopwords only apply to a new index.
+func GetIndex(path string, stopwords []string) (bleve.Index, error) {
+	index, err := bleve.Open(path)
+
+	if err == nil {
+		return index, nil
+	}
+
+	mapping, err := newIndexMapping(stopwords)
+	if err != nil {
+		return nil, err
+	}
+	return bleve.New(path, mapping)
 }
 
 // FileInfo contains the path and the last modified time of a file
@@ -198,6 +1188,7 @@ func getListOfNotes(src string, extensions []string) (paths []string, err error)
 type FileInfo struct {
 	Path    string    // Path to the file
 	ModTime time.Time // Last modified time
+	Created time.Time // Birth time, else when first indexed, see withCreated
 }
 
 // GetFileInfoForFile returns the FileInfo for the given file
@@ -206,7 +1197,28 @@ func getFileInfoForFile(path string) (fi FileInfo, err error) {
 	if err != nil {
 		return FileInfo{}, err
 	}
-	return FileInfo{Path: path, ModTime: info.ModTime()}, nil
+	created, _ := notes.Birthtime(path)
+	return FileInfo{Path: path, ModTime: info.ModTime(), Created: created}, nil
+}
+
+// withCreated fills in the creation time of the files whose file system
+// doesn't record it: the one recorded in old when they were first indexed,
+// else their modification time, as a file is no younger than that.
+func withCreated(current, old []FileInfo) []FileInfo {
+	firstSeen := map[string]time.Time{}
+	for _, fi := range old {
+		firstSeen[fi.Path] = fi.Created
+	}
+	for i, fi := range current {
+		if !fi.Created.IsZero() {
+			continue
+		}
+		current[i].Created = fi.ModTime
+		if created, ok := firstSeen[fi.Path]; ok && !created.IsZero() && created.Before(fi.ModTime) {
+			current[i].Created = created
+		}
+	}
+	return current
 }
 
 // storeFileInfos stores the given FileInfos in the given path
@@ -252,6 +1264,7 @@ func readFileInfos(path string) (fi []FileInfo, err error) {
 }
 
 // compareFileInfos compares the old and current FileInfos and returns the deleted, modified and created FileInfos
+// Modified files are returned with their current FileInfo, so they're indexed with their new time.
 func compareFileInfos(old, current []FileInfo) (deleted, modified, created []FileInfo) {
 
 	deleted = make([]FileInfo, 0)
@@ -263,8 +1276,8 @@ func compareFileInfos(old, current []FileInfo) (deleted, modified, created []Fil
 		for _, f2 := range current {
 			if f1.Path == f2.Path {
 				found = true
-				if f1.ModTime != f2.ModTime {
-					modified = append(modified, f1)
+				if !f1.ModTime.Equal(f2.ModTime) {
+					modified = append(modified, f2)
 				}
 			}
 		}
@@ -290,19 +1303,29 @@ func compareFileInfos(old, current []FileInfo) (deleted, modified, created []Fil
 
 // Note is the struct that is indexed
 type Note struct {
-	Path    string
-	Body    string
-	ModTime time.Time
-}
+	Path       string
+	RelPath    string            // path relative to the notes root, with forward slashes
+	Title      string            // frontmatter title, title:: of Logseq pages, the title of private notes
+	Properties map[string]string // frontmatter fields, key:: value properties of Logseq pages
+	Body       string
+	Hash       string // sha256 of the file, to verify the index against the disk
+	Size       int    // in bytes
+	Words      int
+	Lang       string     // ISO 639-1 code, empty when unknown
+	Type       string     // lowercased extension group, see utils.Config.Types
+	Tags       []string   // tags of the frontmatter, tags:: of Logseq pages
+	Date       *time.Time // date of the frontmatter, nil without one
+	Aliases    []string   // other names of the note, from the frontmatter
+	Links      []string   // names of the files the note links to
+	Created    time.Time  // birth time of the file, else when first indexed
+	ModTime    time.Time
+	Archived   bool // lives in the archive folder
+	Private    bool // only its title is indexed, see notes.IsPrivate
 
-// Custom glob function because inbuild function doesn't support recursive globbing correctly
-func glob(root string, fn func(string) bool) []string {
-	var matches []string
-	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if fn(path) {
-			matches = append(matches, path)
-		}
-		return nil
-	})
-	return matches
+	// Properties holding a date, so they can be compared in ranges.
+	Dates map[string]time.Time
+
+	// Asked to stay out of the index, see isIgnored. Not a field of the
+	// document, the note is deleted from the index instead.
+	ignored bool
 }
@@ -0,0 +1,205 @@
+package bleve_indexer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+func TestCompareFileInfos(t *testing.T) {
+	before := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	after := before.Add(time.Hour)
+	old := []FileInfo{{Path: "kept.md", ModTime: before}, {Path: "edited.md", ModTime: before}, {Path: "gone.md", ModTime: before}}
+	current := []FileInfo{{Path: "kept.md", ModTime: before}, {Path: "edited.md", ModTime: after}, {Path: "new.md", ModTime: after}}
+
+	deleted, modified, created := compareFileInfos(old, current)
+	if want := []FileInfo{{Path: "gone.md", ModTime: before}}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted = %v, want %v", deleted, want)
+	}
+	// Modified files come with their new time, so it's stored on reindex.
+	if want := []FileInfo{{Path: "edited.md", ModTime: after}}; !reflect.DeepEqual(modified, want) {
+		t.Errorf("modified = %v, want %v", modified, want)
+	}
+	if want := []FileInfo{{Path: "new.md", ModTime: after}}; !reflect.DeepEqual(created, want) {
+		t.Errorf("created = %v, want %v", created, want)
+	}
+}
+
+// newTestIndexer returns an indexer of the notes under root, with its
+// config and index in a temporary home. It's closed by the caller.
+func newTestIndexer(t *testing.T, root string) bleveIndexer {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if err := os.MkdirAll(utils.ConfigDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(utils.ConfigPath(), []byte("root_path: "+root+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewBleveIndexer(utils.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestIndexNotesReindexesModified(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "note.md")
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write := func(body string, at time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# Note\nfirst draft", written)
+
+	s := newTestIndexer(t, root)
+	defer s.CloseIndex()
+	ctx := context.Background()
+	s.IndexNotes(ctx)
+
+	edited := written.Add(30 * time.Minute)
+	write("# Note\nsecond draft", edited)
+	s.IndexNotes(ctx)
+
+	stored, ok := lo.Find(s.readFileInfos(), func(fi FileInfo) bool { return fi.Path == path })
+	if !ok || !stored.ModTime.Equal(edited) {
+		t.Errorf("stored ModTime = %v (found %v), want %v", stored.ModTime, ok, edited)
+	}
+	request := bleve.NewSearchRequest(bleve.NewDocIDQuery([]string{path}))
+	request.Fields = []string{"ModTime"}
+	result, err := s.index.Search(request)
+	if err != nil || len(result.Hits) != 1 {
+		t.Fatalf("indexed note: %v, %v", result, err)
+	}
+	if indexed, _ := time.Parse(time.RFC3339, result.Hits[0].Fields["ModTime"].(string)); !indexed.Equal(edited) {
+		t.Errorf("indexed ModTime = %v, want %v", indexed, edited)
+	}
+	if hits := s.Search(ctx, "second ").Hits; len(hits) != 1 {
+		t.Errorf("search for the new content found %d notes, want 1", len(hits))
+	}
+	if hits := s.Search(ctx, "first ").Hits; len(hits) != 0 {
+		t.Errorf("search for the old content found %d notes, want 0", len(hits))
+	}
+
+	// With the new time stored, the next run has nothing to reindex.
+	current, err := getFileInfoForFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, modified, _ := compareFileInfos(s.readFileInfos(), []FileInfo{current}); len(modified) != 0 {
+		t.Errorf("modified after reindexing = %v, want none", modified)
+	}
+}
+
+func TestIgnoredNotesAreNotIndexed(t *testing.T) {
+	root := t.TempDir()
+	kept := filepath.Join(root, "kept.md")
+	ignored := filepath.Join(root, "ignored.md")
+	hidden := filepath.Join(root, "private", "deep", "hidden.md")
+	if err := os.MkdirAll(filepath.Dir(hidden), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, body := range map[string]string{
+		kept:    "budget",
+		ignored: "---\nnotes_search: ignore\n---\nbudget",
+		hidden:  "budget",
+		filepath.Join(root, "private", ".noindex"): "",
+	} {
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestIndexer(t, root)
+	defer s.CloseIndex()
+	ctx := context.Background()
+	s.IndexNotes(ctx)
+	if err := s.IndexFile(hidden); err != nil {
+		t.Fatal(err)
+	}
+	if hits := s.Search(ctx, "budget ").Hits; len(hits) != 1 || hits[0].Path != kept {
+		t.Fatalf("found %v, want only %s", hits, kept)
+	}
+
+	// A note that asks to be ignored once indexed is deleted.
+	if err := os.WriteFile(kept, []byte("---\nnotes_search: ignore\n---\nbudget"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.IndexFile(kept); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := s.DocCount(); count != 0 {
+		t.Errorf("%d notes indexed, want none", count)
+	}
+}
+
+func TestSnapshotRestoresToAnotherRoot(t *testing.T) {
+	root, moved := t.TempDir(), t.TempDir()
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, dir := range []string{root, moved} {
+		path := filepath.Join(dir, "plans", "budget.md")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("---\ntags: [money]\n---\nquarterly budget"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, written, written); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestIndexer(t, root)
+	s.IndexNotes(context.Background())
+	s.CloseIndex()
+	var snapshot bytes.Buffer
+	if err := CreateSnapshot([]*utils.Config{utils.NewConfig()}, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// The notes moved, as they do to another machine.
+	if err := os.WriteFile(utils.ConfigPath(), []byte("root_path: "+moved+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := utils.NewConfig()
+	if _, err := RestoreSnapshot([]*utils.Config{config}, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := NewBleveIndexer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.CloseIndex()
+
+	path := filepath.Join(moved, "plans", "budget.md")
+	ctx := context.Background()
+	for _, query := range []string{"quarterly ", "tag:money", "path:plans/"} {
+		if hits := restored.Search(ctx, query).Hits; len(hits) != 1 || hits[0].Path != path {
+			t.Errorf("search for %q found %v, want %s", query, hits, path)
+		}
+	}
+	current, err := getFileInfoForFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, modified, created := compareFileInfos(restored.readFileInfos(), []FileInfo{current})
+	if len(deleted)+len(modified)+len(created) != 0 {
+		t.Errorf("after restoring deleted = %v, modified = %v, created = %v, want none", deleted, modified, created)
+	}
+}
@@ -0,0 +1,241 @@
+package bleve_indexer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/samber/lo"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Layout of the encrypted file: salt, nonce, then the sealed snapshot.
+const (
+	saltSize  = 16
+	nonceSize = 12
+)
+
+// How often the snapshot is written at most. The saves in between, e.g.
+// one per note the watcher reports, are written together once it passed.
+const saveEvery = 2 * time.Second
+
+// Get path to the encrypted index
+func getEncryptedPath(dir string) string {
+	return filepath.Join(dir, "index.enc")
+}
+
+// encryptedStore persists the indexed notes and their metadata encrypted,
+// for when the cache dir isn't. The bleve index then only lives in memory
+// and is rebuilt from the snapshot on startup.
+type encryptedStore struct {
+	path    string
+	version string      // see mappingVersion
+	salt    []byte      // salt the key was derived with, kept for the session
+	aead    cipher.AEAD // derived once, scrypt is slow on purpose
+
+	mu        sync.Mutex
+	notes     map[string]Note // indexed notes by path
+	fileInfos []FileInfo
+	written   time.Time   // when the snapshot was last written
+	pending   *time.Timer // writes the saves since, nil if none
+}
+
+// snapshot is what gets encrypted.
+type snapshot struct {
+	Version   string
+	FileInfos []FileInfo
+	Notes     []Note
+}
+
+// openEncrypted decrypts the snapshot in the data dir into an in-memory index.
+// A missing snapshot, or one built with another mapping, starts empty.
+// Plaintext leftovers of an unencrypted index are removed.
+func openEncrypted(dir, passphrase string, stopwords []string) (*encryptedStore, bleve.Index, error) {
+	if err := os.RemoveAll(getIndexPath(dir)); err != nil {
+		return nil, nil, err
+	}
+	if err := os.Remove(getFileInfosPath(dir)); err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	mapping, err := newIndexMapping(stopwords)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := bleve.NewMemOnly(mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store := &encryptedStore{
+		path:      getEncryptedPath(dir),
+		version:   mappingVersion(stopwords),
+		notes:     map[string]Note{},
+		fileInfos: []FileInfo{},
+	}
+	snap, err := store.load(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	if snap.Version != store.version {
+		return store, index, nil
+	}
+
+	batch := index.NewBatch()
+	for _, note := range snap.Notes {
+		store.notes[note.Path] = note
+		if err := batch.Index(note.Path, note); err != nil {
+			return nil, nil, err
+		}
+	}
+	store.fileInfos = snap.FileInfos
+	return store, index, index.Batch(batch)
+}
+
+func (e *encryptedStore) put(note Note) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notes[note.Path] = note
+}
+
+func (e *encryptedStore) remove(path string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.notes, path)
+}
+
+func (e *encryptedStore) readFileInfos() []FileInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fileInfos
+}
+
+// save records fileInfos and writes them encrypted along with the notes,
+// right away unless the snapshot was written within saveEvery, then once
+// it passed.
+func (e *encryptedStore) save(fileInfos []FileInfo) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.fileInfos = fileInfos
+	wait := saveEvery - time.Since(e.written)
+	if wait <= 0 {
+		return e.write()
+	}
+	if e.pending == nil {
+		e.pending = time.AfterFunc(wait, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.pending = nil
+			if err := e.write(); err != nil {
+				slog.Error("saving the encrypted index failed", "err", err)
+			}
+		})
+	}
+	return nil
+}
+
+// flush writes the saves waiting for saveEvery to pass right away.
+func (e *encryptedStore) flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending == nil {
+		return nil
+	}
+	e.pending.Stop()
+	e.pending = nil
+	return e.write()
+}
+
+// write encrypts the notes along with the file infos to disk, e.mu held.
+func (e *encryptedStore) write() error {
+	snap := snapshot{Version: e.version, FileInfos: e.fileInfos, Notes: lo.Values(e.notes)}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(snap); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := append(append(append([]byte{}, e.salt...), nonce...), e.aead.Seal(nil, nonce, plain.Bytes(), nil)...)
+	// Written aside and renamed, so a crash never leaves half a snapshot.
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, e.path); err != nil {
+		return err
+	}
+	e.written = time.Now()
+	return nil
+}
+
+// load derives the key of the session from the passphrase and the salt
+// of the snapshot on disk, or a new salt without one, and decrypts the
+// snapshot. A missing snapshot loads empty.
+func (e *encryptedStore) load(passphrase string) (snapshot, error) {
+	sealed, err := os.ReadFile(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		e.salt = make([]byte, saltSize)
+		if _, err := rand.Read(e.salt); err != nil {
+			return snapshot{}, err
+		}
+		e.aead, err = deriveCipher(passphrase, e.salt)
+		return snapshot{Version: e.version, FileInfos: []FileInfo{}}, err
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+	if len(sealed) < saltSize+nonceSize {
+		return snapshot{}, errors.New("encrypted index is truncated")
+	}
+
+	salt, nonce, data := sealed[:saltSize], sealed[saltSize:saltSize+nonceSize], sealed[saltSize+nonceSize:]
+	e.salt = append([]byte{}, salt...)
+	if e.aead, err = deriveCipher(passphrase, e.salt); err != nil {
+		return snapshot{}, err
+	}
+	plain, err := e.aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return snapshot{}, errors.New("can't decrypt the index, wrong passphrase?")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return snapshot{}, err
+	}
+	var snap snapshot
+	if err := json.NewDecoder(zr).Decode(&snap); err != nil {
+		return snapshot{}, err
+	}
+	return snap, nil
+}
+
+// deriveCipher derives the key from the passphrase and salt.
+func deriveCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
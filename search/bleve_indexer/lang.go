@@ -0,0 +1,20 @@
+package bleve_indexer
+
+import (
+	"github.com/abadojack/whatlanggo"
+	"github.com/noelzubin/notes_search/frontmatter"
+)
+
+// detectLang returns the ISO 639-1 code of the language the note is
+// written in, or "" when the detection isn't reliable.
+func detectLang(content string) string {
+	if _, body, found := frontmatter.Split(content); found {
+		content = body
+	}
+
+	info := whatlanggo.Detect(content)
+	if !info.IsReliable() {
+		return ""
+	}
+	return info.Lang.Iso6391()
+}
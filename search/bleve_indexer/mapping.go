@@ -0,0 +1,152 @@
+package bleve_indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/stop"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/analysis/tokenmap"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/samber/lo"
+)
+
+// indexVersion is bumped whenever the mapping changes.
+// An index built by another version is thrown away and rebuilt.
+const indexVersion = 12
+
+// Get path to the file holding the version of the index
+func getVersionPath(dir string) string {
+	return filepath.Join(dir, "version")
+}
+
+// newIndexMapping returns the mapping of the Note documents.
+// Fields without an explicit mapping are mapped dynamically and analyzed
+// like the standard analyzer, but with the configured stopwords.
+func newIndexMapping(stopwords []string) (mapping.IndexMapping, error) {
+	indexMapping := bleve.NewIndexMapping()
+
+	filters := []string{lowercase.Name}
+	switch {
+	case stopwords == nil:
+		filters = append(filters, en.StopName)
+	case len(stopwords) == 1 && stopwords[0] == "none":
+	default:
+		err := indexMapping.AddCustomTokenMap("notes_stop", map[string]interface{}{
+			"type":   tokenmap.Name,
+			"tokens": lo.ToAnySlice(lo.Map(stopwords, func(word string, _ int) string { return strings.ToLower(word) })),
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = indexMapping.AddCustomTokenFilter("notes_stop", map[string]interface{}{
+			"type":           stop.Name,
+			"stop_token_map": "notes_stop",
+		})
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, "notes_stop")
+	}
+	err := indexMapping.AddCustomAnalyzer("notes", map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	indexMapping.DefaultAnalyzer = "notes"
+
+	// The whole path as one lowercased token, for path: filters.
+	err = indexMapping.AddCustomAnalyzer("path", map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	relPath := bleve.NewKeywordFieldMapping()
+	relPath.Analyzer = "path"
+	relPath.IncludeInAll = false
+
+	// Only compared against in words: and size: ranges.
+	number := bleve.NewNumericFieldMapping()
+	number.IncludeInAll = false
+
+	// ISO 639-1 code, matched exactly by lang: filters.
+	lang := bleve.NewKeywordFieldMapping()
+	lang.IncludeInAll = false
+
+	// Lowercased names of the files the note links to, see notes.LinkedNames.
+	links := bleve.NewKeywordFieldMapping()
+	links.IncludeInAll = false
+
+	hash := bleve.NewKeywordFieldMapping()
+	hash.IncludeInAll = false
+
+	// Lowercased name of the extension group, matched by type: filters.
+	noteType := bleve.NewKeywordFieldMapping()
+	noteType.IncludeInAll = false
+
+	// Frontmatter tags, each one lowercased token matched whole, e.g.
+	// Tags:project/alpha. They're in _all through Properties already.
+	tags := bleve.NewKeywordFieldMapping()
+	tags.Analyzer = "path"
+	tags.IncludeInAll = false
+
+	// Frontmatter date, compared against in Date:>"2024-01-01" ranges.
+	date := bleve.NewDateTimeFieldMapping()
+	date.IncludeInAll = false
+
+	note := bleve.NewDocumentMapping()
+	note.AddFieldMappingsAt("RelPath", relPath)
+	note.AddFieldMappingsAt("Size", number)
+	note.AddFieldMappingsAt("Words", number)
+	note.AddFieldMappingsAt("Lang", lang)
+	note.AddFieldMappingsAt("Links", links)
+	note.AddFieldMappingsAt("Hash", hash)
+	note.AddFieldMappingsAt("Type", noteType)
+	note.AddFieldMappingsAt("Tags", tags)
+	note.AddFieldMappingsAt("Date", date)
+	indexMapping.DefaultMapping = note
+
+	return indexMapping, nil
+}
+
+// mappingVersion identifies the mapping an index was built with.
+func mappingVersion(stopwords []string) string {
+	version := strconv.Itoa(indexVersion)
+	if stopwords != nil {
+		version += "\nstopwords: " + strings.Join(stopwords, " ")
+	}
+	return version
+}
+
+// resetOutdatedIndex removes the index and its metadata in dir when they
+// were built by another version or with other stopwords, so the next
+// IndexNotes rebuilds them.
+func resetOutdatedIndex(dir string, stopwords []string) error {
+	version := mappingVersion(stopwords)
+	data, _ := os.ReadFile(getVersionPath(dir))
+	if strings.TrimSpace(string(data)) == version {
+		return nil
+	}
+
+	if err := os.RemoveAll(getIndexPath(dir)); err != nil {
+		return err
+	}
+	if err := os.Remove(getFileInfosPath(dir)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(getVersionPath(dir), []byte(version), 0600)
+}
@@ -0,0 +1,66 @@
+package bleve_indexer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// Change is a file the next IndexNotes adds, updates or deletes.
+type Change struct {
+	Path   string
+	Action string // "add", "update" or "delete"
+	Reason string // "new", "modtime changed", "missing" or "index outdated"
+}
+
+// PlanIndex returns the changes the next IndexNotes makes with the config,
+// without opening or changing the index.
+func PlanIndex(config *utils.Config) ([]Change, error) {
+	dir := config.IndexDir()
+	version := mappingVersion(config.Stopwords)
+
+	old, outdated := []FileInfo{}, false
+	if config.EncryptIndex {
+		passphrase, err := config.IndexPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		store := &encryptedStore{path: getEncryptedPath(dir), version: version}
+		snap, err := store.load(passphrase)
+		switch {
+		case err != nil:
+			return nil, err
+		case snap.Version != version:
+			outdated = true
+		default:
+			old = snap.FileInfos
+		}
+	} else {
+		data, _ := os.ReadFile(getVersionPath(dir))
+		outdated = strings.TrimSpace(string(data)) != version
+		if fi, err := readFileInfos(getFileInfosPath(dir)); err == nil && !outdated {
+			old = fi
+		}
+	}
+
+	current := lo.Map(notes.Paths(config), func(path string, _ int) FileInfo {
+		fi, _ := getFileInfoForFile(path)
+		return fi
+	})
+	deleted, modified, created := compareFileInfos(old, current)
+
+	changes := []Change{}
+	for _, fi := range created {
+		changes = append(changes, Change{fi.Path, "add", lo.Ternary(outdated, "index outdated", "new")})
+	}
+	for _, fi := range modified {
+		changes = append(changes, Change{fi.Path, "update", "modtime changed"})
+	}
+	for _, fi := range deleted {
+		changes = append(changes, Change{fi.Path, "delete", "missing"})
+	}
+	return changes, nil
+}
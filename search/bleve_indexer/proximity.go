@@ -0,0 +1,98 @@
+package bleve_indexer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	bleveSearch "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/samber/lo"
+)
+
+// proximityWindow is the most notes containing all the words of a
+// proximity phrase that are checked for their distance.
+const proximityWindow = 10000
+
+// bodyTerms analyzes text like the body of the notes is indexed.
+func (s *bleveIndexer) bodyTerms(text string) []string {
+	mapping := s.index.Mapping()
+	analyzer := mapping.AnalyzerNamed(mapping.AnalyzerNameForPath("Body"))
+	return lo.Map(analyzer.Analyze([]byte(text)), func(token *analysis.Token, _ int) string {
+		return string(token.Term)
+	})
+}
+
+// withProximity restricts q to the notes matching every proximity phrase.
+// bleve has no sloppy phrases, so the notes containing all the words are
+// fetched with their positions and checked here.
+func (s *bleveIndexer) withProximity(ctx context.Context, q query.Query, proximities []search.Proximity) query.Query {
+	for _, p := range proximities {
+		terms := lo.Uniq(s.bodyTerms(p.Phrase))
+		if len(terms) == 0 {
+			continue
+		}
+
+		all := bleve.NewConjunctionQuery(lo.Map(terms, func(term string, _ int) query.Query {
+			t := bleve.NewTermQuery(term)
+			t.SetField("Body")
+			return t
+		})...)
+		request := bleve.NewSearchRequestOptions(all, proximityWindow, 0, false)
+		request.IncludeLocations = true
+		candidates, err := s.index.SearchInContext(ctx, request)
+		if err != nil {
+			continue
+		}
+
+		near := lo.FilterMap(candidates.Hits, func(hit *bleveSearch.DocumentMatch, _ int) (string, bool) {
+			return hit.ID, isNear(hit.Locations["Body"], terms, p.Distance)
+		})
+		q = bleve.NewConjunctionQuery(q, bleve.NewDocIDQuery(near))
+	}
+	return q
+}
+
+// isNear reports whether all the terms occur within a window having at
+// most distance other words, in any order.
+func isNear(locations bleveSearch.TermLocationMap, terms []string, distance int) bool {
+	type occurrence struct {
+		pos  uint64
+		term int
+	}
+
+	occurrences := []occurrence{}
+	for i, term := range terms {
+		for _, location := range locations[term] {
+			occurrences = append(occurrences, occurrence{location.Pos, i})
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].pos < occurrences[j].pos })
+
+	// Slide a window over the occurrences, shrinking it from the left
+	// while it still holds every term.
+	seen := make([]int, len(terms))
+	covered := 0
+	left := 0
+	for _, o := range occurrences {
+		if seen[o.term] == 0 {
+			covered++
+		}
+		seen[o.term]++
+
+		for covered == len(terms) {
+			span := int(o.pos - occurrences[left].pos)
+			if span-(len(terms)-1) <= distance {
+				return true
+			}
+			seen[occurrences[left].term]--
+			if seen[occurrences[left].term] == 0 {
+				covered--
+			}
+			left++
+		}
+	}
+	return false
+}
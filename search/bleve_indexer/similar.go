@@ -0,0 +1,114 @@
+package bleve_indexer
+
+import (
+	"math"
+	"os"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/samber/lo"
+)
+
+// similarTerms is how many significant terms of a note make up the
+// query for similar notes.
+const similarTerms = 12
+
+// Similar lists the notes sharing the most significant terms with the
+// note at path, i.e. the terms frequent in it but rare in the others.
+func (s *bleveIndexer) Similar(path string) search.SearchResult {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+
+	terms, err := s.significantTerms(body)
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+	if len(terms) == 0 {
+		return search.SearchResult{Hits: []search.DocumentMatch{}}
+	}
+
+	like := bleve.NewDisjunctionQuery(lo.Map(terms, func(t weightedTerm, _ int) query.Query {
+		term := bleve.NewTermQuery(t.term)
+		term.SetField("Body")
+		term.SetBoost(t.weight)
+		return term
+	})...)
+
+	similar := bleve.NewBooleanQuery()
+	similar.AddMust(like)
+	similar.AddMustNot(bleve.NewDocIDQuery([]string{path}))
+
+	searchRequest := bleve.NewSearchRequest(withArchiveFilter(similar, false))
+	searchRequest.Highlight = bleve.NewHighlight()
+	searchRequest.Size = 100
+	searchRequest.IncludeLocations = true
+	searchRequest.Fields = hitFields
+	searchResult, err := s.index.Search(searchRequest)
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+	return toSearchResult(searchResult)
+}
+
+type weightedTerm struct {
+	term   string
+	weight float64
+}
+
+// significantTerms analyzes body like the index does and weighs its
+// terms by tf-idf, returning the heaviest ones.
+func (s *bleveIndexer) significantTerms(body []byte) ([]weightedTerm, error) {
+	tf := map[string]int{}
+	for _, term := range s.bodyTerms(string(body)) {
+		if len(term) > 2 {
+			tf[term]++
+		}
+	}
+
+	total, err := s.index.DocCount()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := []weightedTerm{}
+	for term, count := range tf {
+		df, err := s.docFrequency(term)
+		if err != nil {
+			return nil, err
+		}
+		// Terms only this note has can't match any other.
+		if df < 2 {
+			continue
+		}
+		idf := math.Log(float64(total) / float64(df))
+		if idf <= 0 {
+			continue
+		}
+		terms = append(terms, weightedTerm{term, float64(count) * idf})
+	}
+
+	sort.Slice(terms, func(i, j int) bool { return terms[i].weight > terms[j].weight })
+	if len(terms) > similarTerms {
+		terms = terms[:similarTerms]
+	}
+	return terms, nil
+}
+
+// docFrequency returns the number of notes whose body has the term.
+func (s *bleveIndexer) docFrequency(term string) (uint64, error) {
+	dict, err := s.index.FieldDictRange("Body", []byte(term), []byte(term))
+	if err != nil {
+		return 0, err
+	}
+	defer dict.Close()
+
+	entry, err := dict.Next()
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return entry.Count, nil
+}
@@ -0,0 +1,375 @@
+package bleve_indexer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	bleveIndex "github.com/blevesearch/bleve_index_api"
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// snapshotRootFile is the entry of a vault in a snapshot holding its notes
+// root, against which the paths of its file infos are relative.
+const snapshotRootFile = "root"
+
+// indexFiles are the files and directories making up the index in dir.
+func indexFiles(dir string) []string {
+	return []string{getIndexPath(dir), getFileInfosPath(dir), getVersionPath(dir), getEncryptedPath(dir)}
+}
+
+// snapshotDir is where the index of the config goes in a snapshot: its
+// index dir relative to the data dir, "." for RootPath.
+func snapshotDir(config *utils.Config) string {
+	rel, err := filepath.Rel(utils.DataDir(), config.IndexDir())
+	if err != nil {
+		return config.IndexDir()
+	}
+	return filepath.ToSlash(rel)
+}
+
+// CreateSnapshot writes the indexes of configs and their metadata to w as
+// a gzipped tarball. The paths of the file infos are stored relative to
+// the notes root, so the snapshot can be restored on another machine.
+// The index shouldn't be written to meanwhile.
+func CreateSnapshot(configs []*utils.Config, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, config := range configs {
+		dir := config.IndexDir()
+		if _, err := os.Stat(getVersionPath(dir)); err != nil {
+			return fmt.Errorf("no index for %s, run `notes_search index` first", config.RootPath)
+		}
+		for _, root := range indexFiles(dir) {
+			err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+				if errors.Is(err, fs.ErrNotExist) && file == root {
+					return nil
+				}
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					return err
+				}
+				name := path.Join(snapshotDir(config), filepath.ToSlash(rel))
+				if file == getFileInfosPath(dir) {
+					return addFileInfosToTar(tw, file, name, config.RootPath)
+				}
+				return addToTar(tw, file, name)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		root := []byte(filepath.Clean(config.RootPath))
+		if err := addBytesToTar(tw, path.Join(snapshotDir(config), snapshotRootFile), root); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addToTar adds the file to tw under name.
+func addToTar(tw *tar.Writer, file, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// addFileInfosToTar adds the file infos in file to tw under name, with
+// the paths of the notes under root relative to it.
+func addFileInfosToTar(tw *tar.Writer, file, name, root string) error {
+	fileInfos, err := readFileInfos(file)
+	if err != nil {
+		return err
+	}
+	for i, fi := range fileInfos {
+		if rel, err := filepath.Rel(root, fi.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			fileInfos[i].Path = filepath.ToSlash(rel)
+		}
+	}
+	data, err := json.Marshal(fileInfos)
+	if err != nil {
+		return err
+	}
+	return addBytesToTar(tw, name, data)
+}
+
+// addBytesToTar adds data to tw as a file named name.
+func addBytesToTar(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// RestoreSnapshot replaces the indexes of configs with the ones in the
+// snapshot read from r, and returns the roots of the restored vaults.
+// Vaults missing from the snapshot are left alone, entries of unknown
+// vaults are skipped. Nothing is replaced if the snapshot is unreadable.
+// A vault whose notes root moved gets its notes re-keyed under the new
+// root, unless its index is encrypted.
+func RestoreSnapshot(configs []*utils.Config, r io.Reader) ([]string, error) {
+	byDir := map[string]*utils.Config{}
+	for _, config := range configs {
+		byDir[snapshotDir(config)] = config
+	}
+
+	// Extract next to the indexes first, so they are only replaced once
+	// the whole snapshot was read.
+	if err := os.MkdirAll(utils.DataDir(), 0700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(utils.DataDir(), "restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	found := map[string]bool{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		dir, ok := vaultOf(header.Name, byDir)
+		if !ok {
+			continue
+		}
+		if err := extract(tr, filepath.Join(tmp, filepath.FromSlash(header.Name))); err != nil {
+			return nil, err
+		}
+		found[dir] = true
+	}
+
+	restored := []string{}
+	for dir := range found {
+		config := byDir[dir]
+		from := filepath.Join(tmp, filepath.FromSlash(dir))
+		if _, err := os.Stat(getVersionPath(from)); err != nil {
+			return restored, fmt.Errorf("snapshot of %s has no version, not restoring it", config.RootPath)
+		}
+		if err := moveRoot(from, config); err != nil {
+			return restored, err
+		}
+		if err := replaceIndex(from, config.IndexDir()); err != nil {
+			return restored, err
+		}
+		restored = append(restored, config.RootPath)
+	}
+	return restored, nil
+}
+
+// vaultOf returns the snapshot dir of the vault the entry name belongs to,
+// false for entries outside the index files of the known vaults.
+func vaultOf(name string, byDir map[string]*utils.Config) (string, bool) {
+	clean := path.Clean(name)
+	if clean != name || path.IsAbs(clean) || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+	for dir := range byDir {
+		rel := clean
+		if dir != "." {
+			if !strings.HasPrefix(clean, dir+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(clean, dir+"/")
+		}
+		for _, file := range append(indexFiles(""), snapshotRootFile) {
+			if rel == file || strings.HasPrefix(rel, file+"/") {
+				return dir, true
+			}
+		}
+	}
+	return "", false
+}
+
+// extract writes the current entry of tr to file.
+func extract(tr *tar.Reader, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, tr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replaceIndex moves the index files extracted in from to dir, removing
+// the ones there.
+func replaceIndex(from, dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	old, extracted := indexFiles(dir), indexFiles(from)
+	for i := range old {
+		if err := os.RemoveAll(old[i]); err != nil {
+			return err
+		}
+		if _, err := os.Stat(extracted[i]); err != nil {
+			continue
+		}
+		if err := os.Rename(extracted[i], old[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveRoot resolves the paths of the file infos extracted in from against
+// the notes root of config, and re-keys the notes indexed under the root
+// of the snapshot when it differs. Snapshots taken before the root was
+// recorded keep their absolute paths.
+func moveRoot(from string, config *utils.Config) error {
+	data, err := os.ReadFile(filepath.Join(from, snapshotRootFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	oldRoot, root := string(data), filepath.Clean(config.RootPath)
+
+	if _, err := os.Stat(getFileInfosPath(from)); err == nil {
+		fileInfos, err := readFileInfos(getFileInfosPath(from))
+		if err != nil {
+			return err
+		}
+		for i, fi := range fileInfos {
+			if !filepath.IsAbs(fi.Path) {
+				fileInfos[i].Path = filepath.Join(root, filepath.FromSlash(fi.Path))
+			}
+		}
+		if err := StoreFileInfos(getFileInfosPath(from), fileInfos); err != nil {
+			return err
+		}
+	}
+	if oldRoot == root {
+		return nil
+	}
+
+	if _, err := os.Stat(getEncryptedPath(from)); err == nil {
+		return fmt.Errorf("the encrypted index of %s can't be restored to %s", oldRoot, root)
+	}
+	index, err := bleve.Open(getIndexPath(from))
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+	return rekeyNotes(index, oldRoot, root)
+}
+
+// rekeyNotes moves the notes indexed under oldRoot to the same paths under
+// root, rebuilding them from their stored fields.
+func rekeyNotes(index bleve.Index, oldRoot, root string) error {
+	count, err := index.DocCount()
+	if err != nil {
+		return err
+	}
+	request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), int(count), 0, false)
+	result, err := index.Search(request)
+	if err != nil {
+		return err
+	}
+
+	batch := index.NewBatch()
+	for _, hit := range result.Hits {
+		rel, err := filepath.Rel(oldRoot, hit.ID)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		doc, err := index.Document(hit.ID)
+		if err != nil || doc == nil {
+			return fmt.Errorf("reading %s from the snapshot: %v", hit.ID, err)
+		}
+		note := storedFields(doc)
+		path := filepath.Join(root, rel)
+		note["Path"] = path
+		batch.Delete(hit.ID)
+		if err := batch.Index(path, note); err != nil {
+			return err
+		}
+	}
+	return index.Batch(batch)
+}
+
+// storedFields returns the stored fields of doc by name, with the values
+// of a field given several times in a list.
+func storedFields(doc bleveIndex.Document) map[string]interface{} {
+	fields := map[string]interface{}{}
+	doc.VisitFields(func(field bleveIndex.Field) {
+		var value interface{}
+		switch field := field.(type) {
+		case bleveIndex.TextField:
+			value = field.Text()
+		case bleveIndex.NumericField:
+			value, _ = field.Number()
+		case bleveIndex.DateTimeField:
+			value, _, _ = field.DateTime()
+		case bleveIndex.BooleanField:
+			value, _ = field.Boolean()
+		default:
+			return
+		}
+		switch previous := fields[field.Name()].(type) {
+		case nil:
+			fields[field.Name()] = value
+		case []interface{}:
+			fields[field.Name()] = append(previous, value)
+		default:
+			fields[field.Name()] = []interface{}{previous, value}
+		}
+	})
+	return fields
+}
@@ -0,0 +1,37 @@
+package bleve_indexer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/noelzubin/notes_search/search"
+	"github.com/samber/lo"
+)
+
+// Syntax describes the bleve query string syntax, with the fields of the
+// notes currently in the index.
+func (s *bleveIndexer) Syntax() []search.SyntaxEntry {
+	entries := []search.SyntaxEntry{
+		{Example: "budget review", Help: "notes with either word, both rank higher"},
+		{Example: "+budget +review", Help: "notes with both words"},
+		{Example: "-draft", Help: "notes without the word"},
+		{Example: `"budget review"`, Help: "the exact phrase"},
+		{Example: "budg*  b?dget", Help: "wildcards, the last word is a prefix while typing"},
+		{Example: "budgte~1", Help: "fuzzy, up to 1 edit away"},
+		{Example: "/budg[ae]t/", Help: "regular expression"},
+		{Example: "budget^3 review", Help: "boost a word"},
+		{Example: "Title:budget", Help: "search a single field"},
+		{Example: "Tags:project/alpha", Help: "a frontmatter tag, matched whole"},
+		{Example: "Words:>100  Date:>\"2024-01-01\"", Help: "numeric and date ranges on a field"},
+	}
+
+	fields, err := s.index.Fields()
+	if err == nil {
+		fields = lo.Filter(fields, func(field string, _ int) bool {
+			return !strings.HasPrefix(field, "_")
+		})
+		sort.Strings(fields)
+		entries = append(entries, search.SyntaxEntry{Example: "fields", Help: strings.Join(fields, ", ")})
+	}
+	return entries
+}
@@ -0,0 +1,114 @@
+package bleve_indexer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/samber/lo"
+)
+
+// Drift is how the index differs from the notes on disk.
+type Drift struct {
+	Missing []string // notes on disk that aren't indexed
+	Stale   []string // indexed notes that no longer exist
+	Changed []string // notes whose content differs from the indexed one
+}
+
+// Empty reports whether the index matches the disk.
+func (d Drift) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Stale) == 0 && len(d.Changed) == 0
+}
+
+// hashOf returns the hex encoded sha256 of content.
+func hashOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify cross-checks the indexed documents against the notes on disk by
+// existence and content hash. With repair, the drift is fixed by removing
+// the stale documents and indexing the missing and changed notes.
+func (s *bleveIndexer) Verify(repair bool) (Drift, error) {
+	indexed, err := s.indexedHashes()
+	if err != nil {
+		return Drift{}, err
+	}
+
+	drift := Drift{Missing: []string{}, Stale: []string{}, Changed: []string{}}
+	paths := s.notePaths()
+	for _, path := range paths {
+		hash, ok := indexed[path]
+		if !ok {
+			// Ignored notes aren't indexed.
+			if body, err := os.ReadFile(path); err == nil && isIgnored(path, body) {
+				continue
+			}
+			drift.Missing = append(drift.Missing, path)
+			continue
+		}
+		if body, err := os.ReadFile(path); err == nil && hashOf(body) != hash {
+			drift.Changed = append(drift.Changed, path)
+		}
+	}
+	for path := range indexed {
+		if !lo.Contains(paths, path) {
+			drift.Stale = append(drift.Stale, path)
+		}
+	}
+
+	if !repair || drift.Empty() {
+		return drift, nil
+	}
+
+	for _, path := range drift.Stale {
+		if err := s.deleteNote(path); err != nil {
+			return drift, err
+		}
+	}
+	current := withCreated(lo.Map(paths, func(path string, _ int) FileInfo {
+		fi, _ := getFileInfoForFile(path)
+		return fi
+	}), s.readFileInfos())
+	for _, path := range append(drift.Missing, drift.Changed...) {
+		fi, err := getFileInfoForFile(path)
+		if err != nil {
+			return drift, err
+		}
+		fi = withCreated([]FileInfo{fi}, current)[0]
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return drift, err
+		}
+		if err := s.indexNote(s.newNote(fi, body)); err != nil {
+			return drift, err
+		}
+	}
+
+	// The next IndexNotes starts from the repaired state.
+	return drift, s.storeFileInfos(current)
+}
+
+// indexedHashes returns the hash of every indexed document by path.
+// Documents indexed without a hash map to "".
+func (s *bleveIndexer) indexedHashes() (map[string]string, error) {
+	const page = 1000
+	hashes := map[string]string{}
+
+	for from := 0; ; from += page {
+		request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), page, from, false)
+		request.Fields = []string{"Hash"}
+		result, err := s.index.Search(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range result.Hits {
+			hash, _ := hit.Fields["Hash"].(string)
+			hashes[hit.ID] = hash
+		}
+		if len(result.Hits) < page {
+			return hashes, nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// cacheKey identifies a page of results.
+type cacheKey struct {
+	query      string
+	from, size int
+}
+
+type cacheEntry struct {
+	key    cacheKey
+	result SearchResult
+}
+
+// cachedIndexer keeps the results of the recent queries, so going back to
+// a query, e.g. by backspacing, doesn't hit the index again. The cache is
+// dropped whenever the notes are reindexed.
+type cachedIndexer struct {
+	NotesIndexer
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[cacheKey]*list.Element
+}
+
+// NewCachedIndexer wraps indexer with an LRU cache of size queries.
+func NewCachedIndexer(indexer NotesIndexer, size int) NotesIndexer {
+	return &cachedIndexer{
+		NotesIndexer: indexer,
+		size:         size,
+		order:        list.New(),
+		entries:      map[cacheKey]*list.Element{},
+	}
+}
+
+func (c *cachedIndexer) Search(ctx context.Context, query string) SearchResult {
+	return c.cached(cacheKey{query, 0, DefaultSize}, func() SearchResult {
+		return c.NotesIndexer.Search(ctx, query)
+	})
+}
+
+func (c *cachedIndexer) SearchPage(ctx context.Context, query string, from, size int) SearchResult {
+	return c.cached(cacheKey{query, from, size}, func() SearchResult {
+		return c.NotesIndexer.SearchPage(ctx, query, from, size)
+	})
+}
+
+// IndexNotes reindexes and invalidates the cache.
+func (c *cachedIndexer) IndexNotes(ctx context.Context) {
+	c.NotesIndexer.IndexNotes(ctx)
+	c.Invalidate()
+}
+
+// IndexFile indexes the note and invalidates the cache.
+func (c *cachedIndexer) IndexFile(path string) error {
+	defer c.Invalidate()
+	return c.NotesIndexer.IndexFile(path)
+}
+
+// Invalidate drops all the cached results.
+func (c *cachedIndexer) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = map[cacheKey]*list.Element{}
+}
+
+// cached returns the cached result for key, running search on a miss.
+// Failed and cancelled searches aren't cached.
+func (c *cachedIndexer) cached(key cacheKey, search func() SearchResult) SearchResult {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*cacheEntry).result
+	}
+	c.mu.Unlock()
+
+	result := search()
+	if result.Err != nil {
+		return result
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&cacheEntry{key, result})
+	}
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.order.Remove(oldest)
+	}
+	return result
+}
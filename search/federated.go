@@ -0,0 +1,228 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Vault is a collection of notes with its own index.
+type Vault struct {
+	Name    string
+	Indexer NotesIndexer
+}
+
+// federatedIndexer searches several vaults at once. The hits are labeled
+// with their vault and merged by score; vault:name limits the search to
+// some of the vaults.
+type federatedIndexer struct {
+	vaults []Vault
+}
+
+// NewFederatedIndexer searches all of vaults.
+func NewFederatedIndexer(vaults []Vault) NotesIndexer {
+	return &federatedIndexer{vaults: vaults}
+}
+
+// each runs fn on every vault concurrently.
+func (f *federatedIndexer) each(vaults []Vault, fn func(i int, v Vault)) {
+	var wg sync.WaitGroup
+	wg.Add(len(vaults))
+	for i, v := range vaults {
+		go func(i int, v Vault) {
+			defer wg.Done()
+			fn(i, v)
+		}(i, v)
+	}
+	wg.Wait()
+}
+
+// IndexNotes indexes the vaults in parallel. Each reports its own status,
+// so a slow or unreachable vault doesn't hold back the others.
+func (f *federatedIndexer) IndexNotes(ctx context.Context) {
+	f.each(f.vaults, func(_ int, v Vault) { v.Indexer.IndexNotes(ctx) })
+}
+
+// IndexFile indexes the note in the vault it belongs to, the others
+// ignore it.
+func (f *federatedIndexer) IndexFile(path string) error {
+	for _, v := range f.vaults {
+		if err := v.Indexer.IndexFile(path); err != nil {
+			return fmt.Errorf("vault %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f *federatedIndexer) OpenIndex() {
+	f.each(f.vaults, func(_ int, v Vault) { v.Indexer.OpenIndex() })
+}
+
+func (f *federatedIndexer) CloseIndex() {
+	f.each(f.vaults, func(_ int, v Vault) { v.Indexer.CloseIndex() })
+}
+
+func (f *federatedIndexer) Search(ctx context.Context, query string) SearchResult {
+	return f.SearchPage(ctx, query, 0, DefaultSize)
+}
+
+// SearchPage fetches the first from+size hits of every vault, since any of
+// them may rank in the page, and merges them.
+func (f *federatedIndexer) SearchPage(ctx context.Context, query string, from, size int) SearchResult {
+	vaults := f.selected(ParseQuery(query).Vaults)
+	return f.merge(vaults, from, size, func(v Vault) SearchResult {
+		return v.Indexer.SearchPage(ctx, query, 0, from+size)
+	})
+}
+
+func (f *federatedIndexer) Similar(path string) SearchResult {
+	return f.merge(f.vaults, 0, DefaultSize, func(v Vault) SearchResult {
+		return v.Indexer.Similar(path)
+	})
+}
+
+// Random picks a random note of a random vault.
+func (f *federatedIndexer) Random() SearchResult {
+	if len(f.vaults) == 0 {
+		return SearchResult{Hits: []DocumentMatch{}}
+	}
+	v := f.vaults[rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(f.vaults))]
+	return labeled(v, v.Indexer.Random())
+}
+
+// Syntax is the syntax of the first vault's backend along with vault:.
+func (f *federatedIndexer) Syntax() []SyntaxEntry {
+	syntax := []SyntaxEntry{}
+	if len(f.vaults) > 0 {
+		syntax = f.vaults[0].Indexer.Syntax()
+	}
+	names := lo.Map(f.vaults, func(v Vault, _ int) string { return v.Name })
+	return append(syntax, SyntaxEntry{Example: "vault:" + strings.Join(names, " vault:"), Help: "only search the named vaults"})
+}
+
+// DocCount sums the notes indexed in every vault.
+func (f *federatedIndexer) DocCount() (uint64, error) {
+	total := uint64(0)
+	for _, v := range f.vaults {
+		count, err := v.Indexer.DocCount()
+		if err != nil {
+			return 0, fmt.Errorf("vault %s: %w", v.Name, err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Tags adds up the tags of the vaults named in the query, all of them if
+// none is.
+func (f *federatedIndexer) Tags(ctx context.Context, query string) ([]TagCount, error) {
+	vaults := f.selected(ParseQuery(query).Vaults)
+	counts := make([][]TagCount, len(vaults))
+	errs := make([]error, len(vaults))
+	f.each(vaults, func(i int, v Vault) {
+		counts[i], errs[i] = v.Indexer.Tags(ctx, query)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("vault %s: %w", vaults[i].Name, err)
+		}
+	}
+
+	totals := map[string]int{}
+	for _, vaultCounts := range counts {
+		for _, count := range vaultCounts {
+			totals[count.Tag] += count.Count
+		}
+	}
+	tags := lo.MapToSlice(totals, func(tag string, count int) TagCount { return TagCount{Tag: tag, Count: count} })
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// Activity adds up the notes created or modified each day in every vault.
+func (f *federatedIndexer) Activity(ctx context.Context, since time.Time) (map[string]int, error) {
+	counts := make([]map[string]int, len(f.vaults))
+	errs := make([]error, len(f.vaults))
+	f.each(f.vaults, func(i int, v Vault) {
+		counts[i], errs[i] = v.Indexer.Activity(ctx, since)
+	})
+	totals := map[string]int{}
+	for i, vaultCounts := range counts {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("vault %s: %w", f.vaults[i].Name, errs[i])
+		}
+		for day, count := range vaultCounts {
+			totals[day] += count
+		}
+	}
+	return totals, nil
+}
+
+// IndexStatus lists the roots of every vault, labeled with the vault.
+func (f *federatedIndexer) IndexStatus() []IndexStatus {
+	statuses := []IndexStatus{}
+	for _, v := range f.vaults {
+		for _, status := range v.Indexer.IndexStatus() {
+			status.Vault = v.Name
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// selected returns the vaults named in the query, all of them if none is.
+// Names are case-insensitive.
+func (f *federatedIndexer) selected(names []string) []Vault {
+	if len(names) == 0 {
+		return f.vaults
+	}
+	return lo.Filter(f.vaults, func(v Vault, _ int) bool {
+		return lo.ContainsBy(names, func(name string) bool { return strings.EqualFold(name, v.Name) })
+	})
+}
+
+// merge searches the vaults and returns size hits from from, ordered by
+// score. The first error of any vault fails the search.
+func (f *federatedIndexer) merge(vaults []Vault, from, size int, search func(v Vault) SearchResult) SearchResult {
+	results := make([]SearchResult, len(vaults))
+	f.each(vaults, func(i int, v Vault) {
+		results[i] = labeled(v, search(v))
+	})
+
+	hits := []DocumentMatch{}
+	for _, result := range results {
+		if result.Err != nil {
+			return SearchResult{Hits: []DocumentMatch{}, Err: result.Err}
+		}
+		hits = append(hits, result.Hits...)
+	}
+	// Stable, so equal scores keep the order of the vaults.
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	if from > len(hits) {
+		from = len(hits)
+	}
+	return SearchResult{Hits: hits[from:lo.Min([]int{from + size, len(hits)})]}
+}
+
+// labeled sets the vault of the hits.
+func labeled(v Vault, result SearchResult) SearchResult {
+	for i := range result.Hits {
+		result.Hits[i].Vault = v.Name
+	}
+	if result.Err != nil {
+		result.Err = fmt.Errorf("vault %s: %w", v.Name, result.Err)
+	}
+	return result
+}
@@ -0,0 +1,521 @@
+package grep_indexer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/noelzubin/notes_search/frontmatter"
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/samber/lo"
+)
+
+// grepIndexer is the implementation of the NotesIndexer interface which
+// keeps no index: every search reads the notes on disk, so the results
+// are never stale. ripgrep narrows down the notes to read when it's
+// installed. Suits small vaults, big ones search faster with bleve.
+type grepIndexer struct {
+	config *utils.Config
+	rg     string // path of ripgrep, "" to read every note
+
+	mu      sync.Mutex
+	status  search.IndexStatus // notes found by the last IndexNotes
+	scanned scan               // the last scan, its next pages are read from it
+}
+
+// scan is the sorted hits of a query, as read at a time.
+type scan struct {
+	query string
+	hits  []grepHit
+	at    time.Time
+}
+
+// Characters of the note shown around the first match.
+const snippetContext = 80
+
+// How long the hits of a query serve its next pages, the TUI fetching
+// them right after the first one. Later searches read the notes again.
+const scanReuse = 5 * time.Second
+
+// NewGrepIndexer returns the grep backend of the notes root of config.
+func NewGrepIndexer(config *utils.Config) *grepIndexer {
+	rg, err := exec.LookPath("rg")
+	if err != nil {
+		slog.Info("ripgrep not found, searching the notes in Go", "err", err)
+		rg = ""
+	}
+	return &grepIndexer{config: config, rg: rg, status: search.IndexStatus{Root: config.RootPath}}
+}
+
+// IndexNotes only counts the notes, there's no index to update.
+func (g *grepIndexer) IndexNotes(ctx context.Context) {
+	start := time.Now()
+	count := len(g.notePaths())
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.Notes, g.status.Took = count, time.Since(start)
+	g.scanned = scan{}
+}
+
+// IndexFile only forgets the last scan, the note is read at the next
+// search.
+func (g *grepIndexer) IndexFile(path string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.scanned = scan{}
+	return nil
+}
+
+func (g *grepIndexer) OpenIndex()  {}
+func (g *grepIndexer) CloseIndex() {}
+
+// DocCount counts the notes on disk.
+func (g *grepIndexer) DocCount() (uint64, error) {
+	return uint64(len(g.notePaths())), nil
+}
+
+func (g *grepIndexer) IndexStatus() []search.IndexStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return []search.IndexStatus{g.status}
+}
+
+// Syntax describes the regular expressions the words of a query are.
+func (g *grepIndexer) Syntax() []search.SyntaxEntry {
+	return []search.SyntaxEntry{
+		{Example: "budget review", Help: "notes with both words, each a regular expression"},
+		{Example: `budg[ae]t\s+review`, Help: "a regular expression, \\s for a space"},
+		{Example: "Budget", Help: "case sensitive once a word has an uppercase letter"},
+		{Example: "-draft", Help: "notes without the word"},
+	}
+}
+
+// Similar needs the term statistics of an index.
+func (g *grepIndexer) Similar(path string) search.SearchResult {
+	return search.SearchResult{Hits: []search.DocumentMatch{}, Err: errors.New("similar notes need the bleve backend")}
+}
+
+// Random picks a random note that isn't archived.
+func (g *grepIndexer) Random() search.SearchResult {
+	paths := lo.Filter(g.notePaths(), func(path string, _ int) bool { return !g.isArchived(path) })
+	if len(paths) == 0 {
+		return search.SearchResult{Hits: []search.DocumentMatch{}}
+	}
+	pick := paths[rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(paths))]
+	return search.SearchResult{Hits: []search.DocumentMatch{{Path: pick, Content: "..."}}}
+}
+
+func (g *grepIndexer) Search(ctx context.Context, query string) search.SearchResult {
+	return g.SearchPage(ctx, query, 0, search.DefaultSize)
+}
+
+// grepHit is a note matching the query, with what it's sorted by.
+type grepHit struct {
+	search.DocumentMatch
+	modTime time.Time
+}
+
+// SearchPage returns size of the notes matching every word of the query,
+// most matches first, then the recently modified. Without words every
+// note matches, the recently modified first. The notes are read once per
+// query, the pages fetched right after the first come from that scan.
+func (g *grepIndexer) SearchPage(ctx context.Context, input string, from, size int) search.SearchResult {
+	hits, err := g.scan(ctx, input)
+	if err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+	hits = hits[lo.Min([]int{from, len(hits)}):lo.Min([]int{from + size, len(hits)})]
+	return search.SearchResult{Hits: lo.Map(hits, func(hit grepHit, _ int) search.DocumentMatch { return hit.DocumentMatch })}
+}
+
+// scan returns the sorted hits of the query, reading the notes unless
+// they were read for it within scanReuse.
+func (g *grepIndexer) scan(ctx context.Context, input string) ([]grepHit, error) {
+	g.mu.Lock()
+	last := g.scanned
+	g.mu.Unlock()
+	if last.query == input && last.hits != nil && time.Since(last.at) < scanReuse {
+		return last.hits, nil
+	}
+
+	parsed := search.ParseQuery(input)
+	if err := unsupported(parsed); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	hits, err := g.search(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Matches != hits[j].Matches {
+			return hits[i].Matches > hits[j].Matches
+		}
+		// Stale notes are reviewed from the oldest.
+		if parsed.Stale {
+			return hits[i].modTime.Before(hits[j].modTime)
+		}
+		return hits[i].modTime.After(hits[j].modTime)
+	})
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.scanned = scan{query: input, hits: hits, at: start}
+	return hits, nil
+}
+
+// unsupported reports the operators that need an index.
+func unsupported(q search.Query) error {
+	switch {
+	case len(q.Langs) > 0:
+		return errors.New("lang: needs the bleve backend")
+	case len(q.Proximity) > 0:
+		return errors.New(`"words"~N needs the bleve backend`)
+	case q.Fuzzy || len(q.FuzzyWords) > 0:
+		return errors.New("fuzzy search needs the bleve backend")
+	}
+	return nil
+}
+
+// search returns the notes matching the parsed query, unsorted.
+func (g *grepIndexer) search(ctx context.Context, q search.Query) ([]grepHit, error) {
+	terms, err := compile(strings.Fields(q.Text))
+	if err != nil {
+		return nil, err
+	}
+	excluded, err := compile(q.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []grepHit{}
+	for _, path := range g.candidates(ctx, terms) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if g.isArchived(path) != q.Archived || !g.inFilters(path, q) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !inDates(info.ModTime(), created(path, info.ModTime()), q, g.config.StalePeriod()) {
+			continue
+		}
+		body, err := os.ReadFile(path)
+		if err != nil || !inRanges(body, q.Ranges) || !hasTags(body, q.Tags) {
+			continue
+		}
+		if hit, ok := g.match(path, body, terms, excluded); ok {
+			hits = append(hits, grepHit{DocumentMatch: hit, modTime: info.ModTime()})
+		}
+	}
+	return hits, nil
+}
+
+// match checks the note at path against the words of the query and
+// builds its hit. Attachments are matched by their path, private notes
+// by their title, and the rest by their path and content.
+func (g *grepIndexer) match(path string, body []byte, terms, excluded []*regexp.Regexp) (search.DocumentMatch, bool) {
+	text := string(body)
+	rel := g.relPath(path)
+	if strings.EqualFold(frontmatter.Fields(text)["notes_search"], "ignore") {
+		return search.DocumentMatch{}, false
+	}
+
+	private := !notes.IsAttachment(path) && notes.IsPrivate(text)
+	searched := rel + "\n" + text
+	switch {
+	case notes.IsAttachment(path):
+		searched = rel
+	case private:
+		searched = notes.PrivateTitle(path, text)
+	}
+
+	matches := 0
+	for _, term := range terms {
+		found := len(term.FindAllStringIndex(searched, -1))
+		if found == 0 {
+			return search.DocumentMatch{}, false
+		}
+		matches += found
+	}
+	for _, term := range excluded {
+		if term.MatchString(searched) {
+			return search.DocumentMatch{}, false
+		}
+	}
+
+	if private {
+		return search.DocumentMatch{Path: path, Title: notes.PrivateTitle(path, text), Private: true}, true
+	}
+	title := ""
+	if !notes.IsAttachment(path) {
+		title = frontmatter.Parse(text).Title
+	}
+	return search.DocumentMatch{Path: path, Content: snippet(text, terms), Matches: matches, Title: title}, true
+}
+
+// candidates lists the notes that may match: those ripgrep finds the
+// first word in, plus the ones whose path has it and the attachments,
+// or every note without ripgrep or words.
+func (g *grepIndexer) candidates(ctx context.Context, terms []*regexp.Regexp) []string {
+	paths := g.notePaths()
+	if g.rg == "" || len(terms) == 0 {
+		return paths
+	}
+
+	// The scratchpad may be outside of the root.
+	roots := append([]string{g.config.RootPath}, lo.Filter(paths, func(path string, _ int) bool {
+		return !strings.HasPrefix(path, g.config.RootPath+string(filepath.Separator))
+	})...)
+	args := append([]string{"--files-with-matches", "--no-messages", "--no-ignore", "--hidden", "-e", terms[0].String(), "--"}, roots...)
+	out, err := exec.CommandContext(ctx, g.rg, args...).Output()
+	var exit *exec.ExitError
+	if err != nil && !(errors.As(err, &exit) && exit.ExitCode() == 1) {
+		// ripgrep may not take the expression, read every note instead.
+		slog.Warn("ripgrep failed, searching the notes in Go", "err", err)
+		return paths
+	}
+
+	found := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		found[filepath.Clean(scanner.Text())] = true
+	}
+	return lo.Filter(paths, func(path string, _ int) bool {
+		return found[path] || notes.IsAttachment(path) || terms[0].MatchString(g.relPath(path))
+	})
+}
+
+// compile turns the words of a query into regular expressions, case
+// insensitive unless a word has an uppercase letter.
+func compile(words []string) ([]*regexp.Regexp, error) {
+	terms := []*regexp.Regexp{}
+	for _, word := range words {
+		pattern := word
+		if !strings.ContainsFunc(word, unicode.IsUpper) {
+			pattern = "(?i)" + word
+		}
+		term, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad regular expression %s: %w", word, err)
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// snippet returns the line around the first match of the terms with the
+// matches marked like bleve's highlights.
+func snippet(text string, terms []*regexp.Regexp) string {
+	_, body, _ := frontmatter.Split(text)
+	start := -1
+	for _, term := range terms {
+		if loc := term.FindStringIndex(body); loc != nil && (start < 0 || loc[0] < start) {
+			start = loc[0]
+		}
+	}
+	if start < 0 {
+		return "..."
+	}
+
+	from := lo.Max([]int{strings.LastIndex(body[:start], "\n") + 1, start - snippetContext})
+	to := len(body)
+	if end := strings.Index(body[start:], "\n"); end >= 0 {
+		to = start + end
+	}
+	to = lo.Min([]int{to, start + 2*snippetContext})
+	for from > 0 && !utf8RuneStart(body[from]) {
+		from--
+	}
+	for to < len(body) && !utf8RuneStart(body[to]) {
+		to++
+	}
+
+	line := body[from:to]
+	for _, term := range terms {
+		line = term.ReplaceAllStringFunc(line, func(match string) string { return "<mark>" + match + "</mark>" })
+	}
+	return line
+}
+
+// utf8RuneStart reports whether b starts a character.
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// inFilters reports whether the note at path passes the path:, ext: and
+// type: filters.
+func (g *grepIndexer) inFilters(path string, q search.Query) bool {
+	rel := strings.ToLower(g.relPath(path))
+	for _, prefix := range q.Paths {
+		prefix = strings.TrimPrefix(strings.ToLower(filepath.ToSlash(prefix)), "/")
+		if strings.ContainsAny(prefix, "*?") {
+			if !matchGlob(prefix, rel) {
+				return false
+			}
+		} else if !strings.HasPrefix(rel, prefix) {
+			return false
+		}
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if len(q.Exts) > 0 && !lo.Contains(q.Exts, ext) {
+		return false
+	}
+	return len(q.Types) == 0 || lo.Contains(q.Types, utils.TypeOf(g.config.Types, path))
+}
+
+// matchGlob matches rel against a path: glob, whose * also matches
+// slashes like bleve's wildcards.
+func matchGlob(glob, rel string) bool {
+	pattern := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(glob)) + "$"
+	return regexp.MustCompile(pattern).MatchString(rel)
+}
+
+// created returns when the note at path was created. Without an index
+// to remember when it was first seen, a note whose file system doesn't
+// record it counts as created when last modified.
+func created(path string, modTime time.Time) time.Time {
+	if birth, ok := notes.Birthtime(path); ok {
+		return birth
+	}
+	return modTime
+}
+
+// inDates reports whether a note modified at modTime and created at
+// createdAt passes the after:, before:, modified:, created: and is:stale
+// filters.
+func inDates(modTime, createdAt time.Time, q search.Query, stale time.Duration) bool {
+	switch {
+	case !q.CreatedAfter.IsZero() && createdAt.Before(q.CreatedAfter):
+		return false
+	case !q.CreatedBefore.IsZero() && !createdAt.Before(q.CreatedBefore):
+		return false
+	case !q.After.IsZero() && modTime.Before(q.After):
+		return false
+	case !q.Before.IsZero() && !modTime.Before(q.Before):
+		return false
+	case q.Stale && modTime.After(time.Now().Add(-stale)):
+		return false
+	}
+	return true
+}
+
+// inRanges reports whether the note is in the words: and size: ranges.
+func inRanges(body []byte, ranges []search.Range) bool {
+	for _, r := range ranges {
+		value := float64(len(body))
+		if r.Field == "words" {
+			value = float64(len(strings.Fields(string(body))))
+		}
+		ok := map[string]bool{
+			"<": value < r.Value, "<=": value <= r.Value,
+			">": value > r.Value, ">=": value >= r.Value,
+			"=": value == r.Value,
+		}[r.Op]
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTags reports whether the frontmatter of the note has every tag,
+// case insensitively.
+func hasTags(body []byte, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	have := lo.Map(frontmatter.Parse(string(body)).Tags, func(tag string, _ int) string { return strings.ToLower(tag) })
+	return lo.EveryBy(tags, func(tag string) bool { return lo.Contains(have, strings.ToLower(tag)) })
+}
+
+// Tags counts the frontmatter tags of the notes matching the query, most
+// used first.
+func (g *grepIndexer) Tags(ctx context.Context, input string) ([]search.TagCount, error) {
+	parsed := search.ParseQuery(input)
+	if err := unsupported(parsed); err != nil {
+		return nil, err
+	}
+	hits, err := g.search(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, hit := range hits {
+		body, err := os.ReadFile(hit.Path)
+		if err != nil || notes.IsAttachment(hit.Path) {
+			continue
+		}
+		for _, tag := range lo.Uniq(lo.Map(frontmatter.Parse(string(body)).Tags, func(tag string, _ int) string { return strings.ToLower(tag) })) {
+			counts[tag]++
+		}
+	}
+	tags := lo.MapToSlice(counts, func(tag string, count int) search.TagCount { return search.TagCount{Tag: tag, Count: count} })
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+// Activity counts the notes by the local days they were created and last
+// modified on, a note created and modified the same day once.
+func (g *grepIndexer) Activity(ctx context.Context, since time.Time) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, path := range g.notePaths() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		days := map[string]bool{}
+		for _, at := range []time.Time{info.ModTime(), created(path, info.ModTime())} {
+			if !at.Before(since) {
+				days[at.Local().Format("2006-01-02")] = true
+			}
+		}
+		for day := range days {
+			counts[day]++
+		}
+	}
+	return counts, nil
+}
+
+// notePaths lists the notes under the root and the scratchpad.
+func (g *grepIndexer) notePaths() []string {
+	return notes.Paths(g.config)
+}
+
+// isArchived tells whether the note is in the archive folder.
+func (g *grepIndexer) isArchived(path string) bool {
+	return strings.HasPrefix(path, g.config.ArchiveDir()+string(filepath.Separator))
+}
+
+// relPath returns the path of the note below the root with slashes.
+func (g *grepIndexer) relPath(path string) string {
+	rel, err := filepath.Rel(g.config.RootPath, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
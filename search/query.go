@@ -0,0 +1,363 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a search query split into the free text handed to the backend
+// and the operators understood by notes_search itself.
+type Query struct {
+	Text     string   // Free text of the query
+	Archived bool     // is:archived, search the archived notes instead
+	Stale    bool     // is:stale, notes unchanged for longer than the stale period, oldest first
+	Fuzzy    bool     // a lone ~, the words match words a few edits away as well
+	Exclude  []string // -term, notes containing these are left out
+	Paths    []string // path:sub/, notes whose path starts with all of these or matches the globs
+	Ranges   []Range  // words:>2000, size:<1kb
+	Langs    []string // lang:de, notes written in any of these languages
+	Types    []string // type:code, notes of any of these extension groups
+	Exts     []string // ext:md, notes with any of these extensions, without the dot
+	Tags     []string // tag:work, notes tagged with all of these
+	Vaults   []string // vault:work, only search these vaults
+
+	// "project deadline"~5, notes with the words near each other
+	Proximity []Proximity
+
+	// word~, only these words match words a few edits away as well. They
+	// are kept in Text without the ~.
+	FuzzyWords []string
+
+	// after:7d and before:2024-01-01, notes last modified from After on and
+	// before Before, zero when unbounded. modified:2024-01 and
+	// modified:>last-week narrow them too.
+	After  time.Time
+	Before time.Time
+
+	// created:2023 and created:<7d, notes created from CreatedAfter on and
+	// before CreatedBefore, zero when unbounded
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// Proximity matches notes where the words of Phrase appear, in any order,
+// with at most Distance other words between them.
+type Proximity struct {
+	Phrase   string
+	Distance int
+}
+
+// proximity matches "some words"~N
+var proximity = regexp.MustCompile(`"([^"]+)"~(\d+)`)
+
+// Range compares a numeric field of the notes against a value.
+type Range struct {
+	Field string  // "words" or "size" (in bytes)
+	Op    string  // one of < <= > >= =
+	Value float64 // the value compared against
+}
+
+// sizeUnits are the suffixes accepted by size: ranges.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"kb", 1 << 10},
+	{"mb", 1 << 20},
+	{"gb", 1 << 30},
+	{"b", 1},
+}
+
+// ParseQuery pulls the known operators out of the query.
+// Anything else, including backend specific syntax, is kept in Text.
+func ParseQuery(input string) Query {
+	q := Query{Exclude: []string{}, Paths: []string{}, Ranges: []Range{}, Langs: []string{}, Types: []string{}, Exts: []string{}, Tags: []string{}, Vaults: []string{}, Proximity: []Proximity{}, FuzzyWords: []string{}}
+	text := []string{}
+
+	// Phrases hold spaces, so they are taken out before splitting.
+	input = proximity.ReplaceAllStringFunc(input, func(match string) string {
+		groups := proximity.FindStringSubmatch(match)
+		distance, _ := strconv.Atoi(groups[2])
+		q.Proximity = append(q.Proximity, Proximity{Phrase: groups[1], Distance: distance})
+		return " "
+	})
+
+	// Operators whose value doesn't parse are searched as text.
+	now := time.Now()
+	for _, token := range strings.Fields(input) {
+		switch {
+		case strings.EqualFold(token, "is:archived"):
+			q.Archived = true
+		case strings.EqualFold(token, "is:stale"):
+			q.Stale = true
+		case token == "~":
+			q.Fuzzy = true
+		case isFuzzyWord(token):
+			word := strings.TrimSuffix(token, "~")
+			q.FuzzyWords = append(q.FuzzyWords, word)
+			text = append(text, word)
+		case hasOperator(token, "words:") || hasOperator(token, "size:"):
+			if r := parseRange(token); r != nil {
+				q.Ranges = append(q.Ranges, *r)
+			} else {
+				text = append(text, token)
+			}
+		case hasOperator(token, "lang:"):
+			q.Langs = append(q.Langs, strings.ToLower(token[len("lang:"):]))
+		case hasOperator(token, "type:"):
+			q.Types = append(q.Types, strings.ToLower(token[len("type:"):]))
+		case hasOperator(token, "ext:"):
+			q.Exts = append(q.Exts, strings.TrimPrefix(strings.ToLower(token[len("ext:"):]), "."))
+		case hasOperator(token, "tag:"):
+			q.Tags = append(q.Tags, strings.TrimPrefix(token[len("tag:"):], "#"))
+		case hasOperator(token, "vault:"):
+			q.Vaults = append(q.Vaults, token[len("vault:"):])
+		case hasOperator(token, "after:"):
+			after, ok := parseDate(token[len("after:"):], now)
+			switch {
+			case !ok:
+				text = append(text, token)
+			case after.After(q.After):
+				q.After = after
+			}
+		case hasOperator(token, "before:"):
+			before, ok := parseDate(token[len("before:"):], now)
+			switch {
+			case !ok:
+				text = append(text, token)
+			case q.Before.IsZero() || before.Before(q.Before):
+				q.Before = before
+			}
+		case hasOperator(token, "created:"):
+			if from, until, ok := dateBounds(token[len("created:"):], now); ok {
+				q.CreatedAfter, q.CreatedBefore = narrow(q.CreatedAfter, q.CreatedBefore, from, until)
+			} else {
+				text = append(text, token)
+			}
+		case hasOperator(token, "modified:"):
+			if from, until, ok := dateBounds(token[len("modified:"):], now); ok {
+				q.After, q.Before = narrow(q.After, q.Before, from, until)
+			} else {
+				text = append(text, token)
+			}
+		case hasOperator(token, "path:"):
+			q.Paths = append(q.Paths, token[len("path:"):])
+		case isExclusion(token):
+			q.Exclude = append(q.Exclude, token[1:])
+		default:
+			text = append(text, token)
+		}
+	}
+
+	q.Text = strings.Join(text, " ")
+	// A trailing space or ~ means the last word is complete, keep it.
+	if len(text) > 0 && (strings.HasSuffix(input, " ") || strings.HasSuffix(input, "~")) {
+		q.Text += " "
+	}
+
+	return q
+}
+
+// isExclusion reports whether the token is a plain -term.
+// Backend syntax such as -Field:value is left to the backend.
+func isExclusion(token string) bool {
+	return len(token) > 1 && token[0] == '-' && !strings.ContainsAny(token, ":\"")
+}
+
+// isFuzzyWord reports whether the token is a plain word~. Words with a
+// syntax of their own, and bleve's word~2, are left to the backend.
+func isFuzzyWord(token string) bool {
+	word, found := strings.CutSuffix(token, "~")
+	return found && strings.Trim(word, "+") != "" && word[0] != '-' && !strings.ContainsAny(word, "*?\"~^/():\\")
+}
+
+// hasOperator reports whether the token is operator followed by a value,
+// e.g. ext:md for "ext:".
+func hasOperator(token, operator string) bool {
+	return len(token) > len(operator) && strings.EqualFold(token[:len(operator)], operator)
+}
+
+// parseRange parses words:>2000 or size:<1kb, nil for anything else.
+// A value without an operator means =.
+func parseRange(token string) *Range {
+	field, value, found := strings.Cut(strings.ToLower(token), ":")
+	if !found || (field != "words" && field != "size") {
+		return nil
+	}
+
+	op := "="
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+
+	multiplier := 1.0
+	if field == "size" {
+		for _, unit := range sizeUnits {
+			if strings.HasSuffix(value, unit.suffix) {
+				multiplier = unit.multiplier
+				value = strings.TrimSuffix(value, unit.suffix)
+				break
+			}
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return nil
+	}
+	return &Range{Field: field, Op: op, Value: number * multiplier}
+}
+
+// dateLayouts are the dates accepted by after:, before:, created: and
+// modified:, from the most precise, with the length of the period each
+// names.
+var dateLayouts = []struct {
+	layout              string
+	years, months, days int
+}{
+	{"2006-01-02", 0, 0, 1},
+	{"2006-01", 0, 1, 0},
+	{"2006", 1, 0, 0},
+}
+
+// relativeDate matches the durations back from now of the date operators,
+// e.g. 12h, 7d, 2w, 3m or 1y.
+var relativeDate = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// parseDate reads the value of after: and before:, the start of a period
+// parsePeriod understands. ok is false for anything else.
+func parseDate(value string, now time.Time) (date time.Time, ok bool) {
+	date, _, ok = parsePeriod(value, now)
+	return date, ok
+}
+
+// parsePeriod reads a local date such as 2024-01-31, 2024-01 or 2024,
+// today, yesterday, this-week, last-week, this-month, last-month,
+// this-year or last-year into the period from start to end, weeks
+// starting on Monday. A duration back from now such as 7d is an instant,
+// returned with a zero end. ok is false for anything else.
+func parsePeriod(value string, now time.Time) (start, end time.Time, ok bool) {
+	value = strings.ToLower(value)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := midnight.AddDate(0, 0, -(int(midnight.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	switch value {
+	case "today":
+		return midnight, midnight.AddDate(0, 0, 1), true
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), midnight, true
+	case "this-week":
+		return monday, monday.AddDate(0, 0, 7), true
+	case "last-week":
+		return monday.AddDate(0, 0, -7), monday, true
+	case "this-month":
+		return month, month.AddDate(0, 1, 0), true
+	case "last-month":
+		return month.AddDate(0, -1, 0), month, true
+	case "this-year":
+		return year, year.AddDate(1, 0, 0), true
+	case "last-year":
+		return year.AddDate(-1, 0, 0), year, true
+	}
+
+	if match := relativeDate.FindStringSubmatch(value); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), time.Time{}, true
+		case "d":
+			return now.AddDate(0, 0, -n), time.Time{}, true
+		case "w":
+			return now.AddDate(0, 0, -7*n), time.Time{}, true
+		case "m":
+			return now.AddDate(0, -n, 0), time.Time{}, true
+		default:
+			return now.AddDate(-n, 0, 0), time.Time{}, true
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout.layout, value, now.Location()); err == nil {
+			return date, date.AddDate(layout.years, layout.months, layout.days), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// dateBounds reads the value of created: and modified:, a period
+// parsePeriod understands after an optional < <= > or >=, into the dates
+// it matches, from on and before until, zero when unbounded. A bare
+// period matches the dates in it, a bare duration the dates since; >7d
+// is newer than 7 days and <2024 older than 2024.
+func dateBounds(value string, now time.Time) (from, until time.Time, ok bool) {
+	op := ""
+	for _, candidate := range []string{"<=", ">=", "<", ">"} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+
+	start, end, ok := parsePeriod(value, now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	instant := end.IsZero()
+	if instant {
+		end = start
+	}
+	switch {
+	case op == ">":
+		return end, time.Time{}, true
+	case op == ">=" || (op == "" && instant):
+		return start, time.Time{}, true
+	case op == "<":
+		return time.Time{}, start, true
+	case op == "<=":
+		return time.Time{}, end, true
+	}
+	return start, end, true
+}
+
+// narrow intersects the dates from after on and before before with the
+// dates from from on and before until, zero being unbounded.
+func narrow(after, before, from, until time.Time) (time.Time, time.Time) {
+	if from.After(after) {
+		after = from
+	}
+	if !until.IsZero() && (before.IsZero() || until.Before(before)) {
+		before = until
+	}
+	return after, before
+}
+
+// SyntaxEntry documents one piece of query syntax for the cheat sheet.
+type SyntaxEntry struct {
+	Example string
+	Help    string
+}
+
+// Operators are the operators handled by ParseQuery, for any backend.
+var Operators = []SyntaxEntry{
+	{"-term", "leave out notes containing term"},
+	{"is:archived", "search the archived notes instead"},
+	{"is:stale", "notes unchanged for longer than stale_after days, oldest first"},
+	{"kuberntes ~", "fuzzy search, words up to fuzziness edits away match too (alt+~ toggles it)"},
+	{"path:work/", "notes in the work folder, path:*draft* for a glob on the whole path"},
+	{"words:>2000  size:<1kb", "word count and file size ranges (< <= > >= =, b kb mb gb)"},
+	{"after:7d  before:2024-01", "notes modified since or before a date (2024-01-31, 2024-01, 2024, today, yesterday) or h d w m y ago"},
+	{"created:2023  modified:>last-week", "notes created or modified in, after (>) or before (<) a date, this- or last-week, -month, -year, or newer than 7d"},
+	{"lang:de", "notes detected as written in the language"},
+	{"type:code", "notes whose extension is in the group, see types in the config"},
+	{"ext:md", "notes with the extension"},
+	{"tag:work", "notes tagged work in their frontmatter"},
+	{`"project deadline"~5`, "words at most 5 words apart, in any order"},
+}
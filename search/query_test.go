@@ -0,0 +1,163 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(q Query) any
+		want  any
+	}{
+		{"plain text", "kubernetes deploy", func(q Query) any { return q.Text }, "kubernetes deploy"},
+		{"trailing space kept", "deploy ", func(q Query) any { return q.Text }, "deploy "},
+		{"archived", "IS:ARCHIVED budget", func(q Query) any { return []any{q.Archived, q.Text} }, []any{true, "budget"}},
+		{"stale", "is:stale", func(q Query) any { return q.Stale }, true},
+		{"lone ~", "kuberntes ~", func(q Query) any { return []any{q.Fuzzy, q.Text} }, []any{true, "kuberntes "}},
+		{"word~", "kuberntes~ deploy", func(q Query) any { return []any{q.Fuzzy, q.FuzzyWords, q.Text} }, []any{false, []string{"kuberntes"}, "kuberntes deploy"}},
+		{"trailing word~ is complete", "deploy kuberntes~", func(q Query) any { return q.Text }, "deploy kuberntes "},
+		{"word~2 left to the backend", "kuberntes~2", func(q Query) any { return []any{q.FuzzyWords, q.Text} }, []any{[]string{}, "kuberntes~2"}},
+		{"exclusion", "go -java", func(q Query) any { return []any{q.Exclude, q.Text} }, []any{[]string{"java"}, "go"}},
+		{"field exclusion left to the backend", "-Title:draft", func(q Query) any { return []any{q.Exclude, q.Text} }, []any{[]string{}, "-Title:draft"}},
+		{"paths", "path:work/ PATH:Projects", func(q Query) any { return q.Paths }, []string{"work/", "Projects"}},
+		{"empty path", "path:", func(q Query) any { return []any{q.Paths, q.Text} }, []any{[]string{}, "path:"}},
+		{"globs", "path:*.md path:work/**/todo.md", func(q Query) any { return q.Paths }, []string{"*.md", "work/**/todo.md"}},
+		{"langs lowercased", "lang:DE lang:en", func(q Query) any { return q.Langs }, []string{"de", "en"}},
+		{"types lowercased", "type:Code", func(q Query) any { return q.Types }, []string{"code"}},
+		{"exts without the dot", "ext:.MD ext:org", func(q Query) any { return q.Exts }, []string{"md", "org"}},
+		{"tags without the #", "tag:#work tag:c++", func(q Query) any { return q.Tags }, []string{"work", "c++"}},
+		{"vaults", "vault:work", func(q Query) any { return q.Vaults }, []string{"work"}},
+		{"empty operators are text", "lang: type: ext: tag: vault:", func(q Query) any { return q.Text }, "lang: type: ext: tag: vault:"},
+		{"ranges", "words:>2000 size:<=1kb size:3", func(q Query) any { return q.Ranges }, []Range{
+			{Field: "words", Op: ">", Value: 2000},
+			{Field: "size", Op: "<=", Value: 1024},
+			{Field: "size", Op: "=", Value: 3},
+		}},
+		{"bad ranges are text", "words:many size:-1", func(q Query) any { return []any{q.Ranges, q.Text} }, []any{[]Range{}, "words:many size:-1"}},
+		{"proximity", `"project deadline"~5 budget`, func(q Query) any { return []any{q.Proximity, q.Text} }, []any{[]Proximity{{Phrase: "project deadline", Distance: 5}}, "budget"}},
+		{"bad dates are text", "after:soon before:never", func(q Query) any {
+			return []any{q.After.IsZero(), q.Before.IsZero(), q.Text}
+		}, []any{true, true, "after:soon before:never"}},
+		{"bad periods are text", "created:x modified:y", func(q Query) any {
+			return []any{q.After.IsZero(), q.Before.IsZero(), q.CreatedAfter.IsZero(), q.CreatedBefore.IsZero(), q.Text}
+		}, []any{true, true, true, true, "created:x modified:y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(ParseQuery(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryDates(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return d
+	}
+	tests := []struct {
+		input                                      string
+		after, before, createdAfter, createdBefore time.Time
+	}{
+		{"after:2024-01-01", date("2024-01-01"), time.Time{}, time.Time{}, time.Time{}},
+		{"before:2024-01", time.Time{}, date("2024-01-01"), time.Time{}, time.Time{}},
+		// The latest after: and the earliest before: win.
+		{"after:2023 after:2024-02 before:2025 before:2024-06", date("2024-02-01"), date("2024-06-01"), time.Time{}, time.Time{}},
+		{"modified:2024-03", date("2024-03-01"), date("2024-04-01"), time.Time{}, time.Time{}},
+		{"created:2023", time.Time{}, time.Time{}, date("2023-01-01"), date("2024-01-01")},
+		{"created:<2023 created:>=2020", time.Time{}, time.Time{}, date("2020-01-01"), date("2023-01-01")},
+		{"after:2024-01-01 modified:2023", date("2024-01-01"), date("2024-01-01"), time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q := ParseQuery(tt.input)
+			if !q.After.Equal(tt.after) || !q.Before.Equal(tt.before) {
+				t.Errorf("modified = %v..%v, want %v..%v", q.After, q.Before, tt.after, tt.before)
+			}
+			if !q.CreatedAfter.Equal(tt.createdAfter) || !q.CreatedBefore.Equal(tt.createdBefore) {
+				t.Errorf("created = %v..%v, want %v..%v", q.CreatedAfter, q.CreatedBefore, tt.createdAfter, tt.createdBefore)
+			}
+		})
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2024, 5, 15, 13, 30, 0, 0, time.Local)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		value      string
+		start, end time.Time
+		ok         bool
+	}{
+		{"today", day(2024, 5, 15), day(2024, 5, 16), true},
+		{"Yesterday", day(2024, 5, 14), day(2024, 5, 15), true},
+		{"this-week", day(2024, 5, 13), day(2024, 5, 20), true},
+		{"last-week", day(2024, 5, 6), day(2024, 5, 13), true},
+		{"this-month", day(2024, 5, 1), day(2024, 6, 1), true},
+		{"last-month", day(2024, 4, 1), day(2024, 5, 1), true},
+		{"this-year", day(2024, 1, 1), day(2025, 1, 1), true},
+		{"last-year", day(2023, 1, 1), day(2024, 1, 1), true},
+		{"2024-02-29", day(2024, 2, 29), day(2024, 3, 1), true},
+		{"2023-12", day(2023, 12, 1), day(2024, 1, 1), true},
+		{"2020", day(2020, 1, 1), day(2021, 1, 1), true},
+		{"12h", now.Add(-12 * time.Hour), time.Time{}, true},
+		{"7d", day(2024, 5, 8).Add(13*time.Hour + 30*time.Minute), time.Time{}, true},
+		{"2w", day(2024, 5, 1).Add(13*time.Hour + 30*time.Minute), time.Time{}, true},
+		{"3m", day(2024, 2, 15).Add(13*time.Hour + 30*time.Minute), time.Time{}, true},
+		{"1y", day(2023, 5, 15).Add(13*time.Hour + 30*time.Minute), time.Time{}, true},
+		{"soon", time.Time{}, time.Time{}, false},
+		{"7x", time.Time{}, time.Time{}, false},
+		{"2024-13", time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, end, ok := parsePeriod(tt.value, now)
+			if ok != tt.ok || !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("parsePeriod(%q) = %v, %v, %v, want %v, %v, %v", tt.value, start, end, ok, tt.start, tt.end, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDateBounds(t *testing.T) {
+	now := time.Date(2024, 5, 15, 13, 30, 0, 0, time.Local)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	weekAgo := now.AddDate(0, 0, -7)
+	tests := []struct {
+		value       string
+		from, until time.Time
+		ok          bool
+	}{
+		{"2024", day(2024, 1, 1), day(2025, 1, 1), true},
+		{">2024", day(2025, 1, 1), time.Time{}, true},
+		{">=2024", day(2024, 1, 1), time.Time{}, true},
+		{"<2024", time.Time{}, day(2024, 1, 1), true},
+		{"<=2024", time.Time{}, day(2025, 1, 1), true},
+		{"last-week", day(2024, 5, 6), day(2024, 5, 13), true},
+		// A bare duration is the dates since, like >=.
+		{"7d", weekAgo, time.Time{}, true},
+		{">7d", weekAgo, time.Time{}, true},
+		{"<7d", time.Time{}, weekAgo, true},
+		{"<=7d", time.Time{}, weekAgo, true},
+		{"<", time.Time{}, time.Time{}, false},
+		{">soon", time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			from, until, ok := dateBounds(tt.value, now)
+			if ok != tt.ok || !from.Equal(tt.from) || !until.Equal(tt.until) {
+				t.Errorf("dateBounds(%q) = %v, %v, %v, want %v, %v, %v", tt.value, from, until, ok, tt.from, tt.until, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// remoteIndexer is the implementation of the NotesIndexer interface
+// which forwards everything to a notes_search daemon.
+type remoteIndexer struct {
+	baseURL string
+	client  *http.Client
+	conf    utils.ServerConfig
+}
+
+// NewRemoteIndexer returns a NotesIndexer talking to the daemon at addr.
+// When a TLS certificate is configured it is trusted as the daemon's
+// certificate, which covers the usual self-signed setup.
+func NewRemoteIndexer(addr string, conf utils.ServerConfig) (*remoteIndexer, error) {
+	network, address := splitAddr(addr)
+	scheme := "http"
+	transport := &http.Transport{}
+
+	if conf.TLSCert != "" {
+		pem, err := os.ReadFile(conf.TLSCert)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(pem)
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		scheme = "https"
+	}
+
+	baseURL := scheme + "://" + address
+	if network == "unix" {
+		// The host is ignored, every request goes over the socket.
+		baseURL = scheme + "://localhost"
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", address)
+		}
+	}
+
+	return &remoteIndexer{baseURL: baseURL, client: &http.Client{Transport: transport}, conf: conf}, nil
+}
+
+// do sends the request with the configured credentials, until ctx is done.
+func (s *remoteIndexer) do(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.conf.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.conf.Token)
+	} else if s.conf.Username != "" {
+		req.SetBasicAuth(s.conf.Username, s.conf.Password)
+	}
+
+	return s.client.Do(req)
+}
+
+// The index lives on the daemon, there is nothing to open or close locally.
+func (s *remoteIndexer) OpenIndex()  {}
+func (s *remoteIndexer) CloseIndex() {}
+
+// IndexNotes asks the daemon to reindex its notes. Cancelling only stops
+// waiting for the daemon, which finishes the reindex.
+func (s *remoteIndexer) IndexNotes(ctx context.Context) {
+	resp, err := s.do(ctx, http.MethodPost, "/index")
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// IndexFile asks the daemon to reindex the note at path.
+func (s *remoteIndexer) IndexFile(path string) error {
+	resp, err := s.do(context.Background(), http.MethodPost, "/index?path="+url.QueryEscape(path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("daemon: %s", resp.Status)
+	}
+	return nil
+}
+
+// Search runs the query on the daemon.
+func (s *remoteIndexer) Search(ctx context.Context, query string) search.SearchResult {
+	return s.get(ctx, "/search?q="+url.QueryEscape(query))
+}
+
+// SearchPage runs the query on the daemon, returning one page of hits.
+func (s *remoteIndexer) SearchPage(ctx context.Context, query string, from, size int) search.SearchResult {
+	return s.get(ctx, fmt.Sprintf("/search?q=%s&from=%d&size=%d", url.QueryEscape(query), from, size))
+}
+
+// Random asks the daemon for a random note.
+func (s *remoteIndexer) Random() search.SearchResult {
+	return s.get(context.Background(), "/random")
+}
+
+// Similar asks the daemon for notes related to the note at path.
+func (s *remoteIndexer) Similar(path string) search.SearchResult {
+	return s.get(context.Background(), "/similar?path="+url.QueryEscape(path))
+}
+
+// Syntax asks the daemon for the query syntax of its backend.
+func (s *remoteIndexer) Syntax() []search.SyntaxEntry {
+	entries := []search.SyntaxEntry{}
+	resp, err := s.do(context.Background(), http.MethodGet, "/syntax")
+	if err != nil {
+		return entries
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(&entries)
+	return entries
+}
+
+// DocCount asks the daemon how many notes it has indexed.
+func (s *remoteIndexer) DocCount() (uint64, error) {
+	resp, err := s.do(context.Background(), http.MethodGet, "/count")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("daemon: %s", resp.Status)
+	}
+
+	var body struct{ Count uint64 }
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	return body.Count, err
+}
+
+// IndexStatus asks the daemon for the state of its reindexes.
+func (s *remoteIndexer) IndexStatus() []search.IndexStatus {
+	statuses := []search.IndexStatus{}
+	resp, err := s.do(context.Background(), http.MethodGet, "/status")
+	if err != nil {
+		return []search.IndexStatus{{Root: s.baseURL, Err: err.Error()}}
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(&statuses)
+	return statuses
+}
+
+// Tags asks the daemon for the tags of the notes matching the query.
+func (s *remoteIndexer) Tags(ctx context.Context, query string) ([]search.TagCount, error) {
+	resp, err := s.do(ctx, http.MethodGet, "/tags?q="+url.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon: %s", resp.Status)
+	}
+
+	tags := []search.TagCount{}
+	err = json.NewDecoder(resp.Body).Decode(&tags)
+	return tags, err
+}
+
+// Activity asks the daemon for the notes created or modified each day
+// since since.
+func (s *remoteIndexer) Activity(ctx context.Context, since time.Time) (map[string]int, error) {
+	resp, err := s.do(ctx, http.MethodGet, "/activity?since="+url.QueryEscape(since.Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon: %s", resp.Status)
+	}
+
+	counts := map[string]int{}
+	err = json.NewDecoder(resp.Body).Decode(&counts)
+	return counts, err
+}
+
+// get fetches a search result from the daemon.
+func (s *remoteIndexer) get(ctx context.Context, path string) search.SearchResult {
+	resp, err := s.do(ctx, http.MethodGet, path)
+	if err != nil {
+		// Cancelled searches report ctx.Err() rather than the URL error.
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: fmt.Errorf("daemon: %s", resp.Status)}
+	}
+
+	var body searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return search.SearchResult{Hits: []search.DocumentMatch{}, Err: err}
+	}
+
+	result := search.SearchResult{Hits: body.Hits}
+	if body.Error != "" {
+		result.Err = errors.New(body.Error)
+	}
+	return result
+}
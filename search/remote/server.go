@@ -0,0 +1,294 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noelzubin/notes_search/notes"
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+	"github.com/noelzubin/notes_search/watcher"
+	"github.com/samber/lo"
+)
+
+// searchResponse is the wire format of search.SearchResult.
+// errors don't survive JSON so the message is sent as a string.
+type searchResponse struct {
+	Hits  []search.DocumentMatch
+	Error string
+}
+
+// newHandler exposes the given indexer over HTTP.
+//
+//	GET  /search?q=<query>  search the index, &from=<n>&size=<n> for a page
+//	GET  /random            a random note
+//	GET  /similar?path=<p>  notes related to the note at p, a note of a vault
+//	GET  /syntax            query syntax of the backend
+//	POST /index             reindex all the notes
+//	GET  /ws                websocket for live search, see wsRequest
+func newHandler(indexer search.NotesIndexer, hub *hub, config *utils.Config) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/ws", hub.serveWS)
+
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if !params.Has("from") && !params.Has("size") {
+			writeResult(w, indexer.Search(r.Context(), params.Get("q")))
+			return
+		}
+
+		from, _ := strconv.Atoi(params.Get("from"))
+		size, err := strconv.Atoi(params.Get("size"))
+		if err != nil || from < 0 || size <= 0 {
+			http.Error(w, "invalid from or size", http.StatusBadRequest)
+			return
+		}
+		writeResult(w, indexer.SearchPage(r.Context(), params.Get("q"), from, size))
+	})
+
+	mux.HandleFunc("/random", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, indexer.Random())
+	})
+
+	mux.HandleFunc("/similar", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		// Similar reads the file, anything but a note stays private.
+		if !isVaultNote(config, path) {
+			http.NotFound(w, r)
+			return
+		}
+		writeResult(w, indexer.Similar(path))
+	})
+
+	mux.HandleFunc("/syntax", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(indexer.Syntax())
+	})
+
+	mux.HandleFunc("/count", func(w http.ResponseWriter, r *http.Request) {
+		count, err := indexer.DocCount()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct{ Count uint64 }{count})
+	})
+
+	mux.HandleFunc("/tags", func(w http.ResponseWriter, r *http.Request) {
+		tags, err := indexer.Tags(r.Context(), r.URL.Query().Get("q"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	})
+
+	mux.HandleFunc("/activity", func(w http.ResponseWriter, r *http.Request) {
+		since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+		if err != nil {
+			http.Error(w, "bad since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		counts, err := indexer.Activity(r.Context(), since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(counts)
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(indexer.IndexStatus())
+	})
+
+	mux.HandleFunc("/index", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// ?path= reindexes a single note.
+		if path := r.URL.Query().Get("path"); path != "" {
+			if err := indexer.IndexFile(path); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else {
+			// The index is shared, a client going away doesn't stop it.
+			indexer.IndexNotes(context.Background())
+		}
+		w.WriteHeader(http.StatusNoContent)
+		go hub.notifyIndexed()
+	})
+
+	return mux
+}
+
+// isVaultNote reports whether path is a note of one of the vaults of
+// config, symlinks resolved so they can't point out of the vault.
+func isVaultNote(config *utils.Config, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	return lo.SomeBy(config.VaultConfigs(), func(vault *utils.Config) bool {
+		root, err := filepath.EvalSymlinks(vault.RootPath)
+		if err != nil {
+			return false
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		return notes.IsNote(root, resolved, vault.NoteExtensions())
+	})
+}
+
+// writeResult sends the search result as JSON.
+func writeResult(w http.ResponseWriter, result search.SearchResult) {
+	resp := searchResponse{Hits: result.Hits}
+	if result.Err != nil {
+		resp.Error = result.Err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Listen opens a listener for addr.
+// Addresses starting with "unix:" or containing a path separator are
+// treated as unix sockets, anything else as a TCP host:port.
+func Listen(addr string) (net.Listener, error) {
+	network, address := splitAddr(addr)
+	if network == "unix" {
+		// Remove a stale socket left behind by a previous run.
+		os.Remove(address)
+	}
+	return net.Listen(network, address)
+}
+
+// Serve serves the indexer on addr until the listener fails.
+// Requests are authenticated and served over TLS as configured.
+func Serve(addr string, indexer search.NotesIndexer, config *utils.Config) error {
+	if err := config.Server.Validate(); err != nil {
+		return err
+	}
+	l, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+
+	conf := config.Server
+	hub := newHub(indexer)
+	handler := withAuth(newHandler(indexer, hub, config), conf)
+	slog.Info("serving notes", "addr", addr)
+
+	if interval := config.ReindexEvery(); interval > 0 {
+		go reindexEvery(interval, indexer, hub)
+	}
+	if roots := config.WatchedRoots(); len(roots) > 0 {
+		if w, err := watcher.New(roots); err != nil {
+			slog.Warn("can't watch the notes, changes are picked up by reindexing", "err", err)
+		} else {
+			go indexChanges(w, indexer, hub)
+		}
+	}
+
+	if conf.TLSCert != "" {
+		return http.ServeTLS(l, handler, conf.TLSCert, conf.TLSKey)
+	}
+	return http.Serve(l, handler)
+}
+
+// reindexEvery reindexes the notes on a fixed interval, as a fallback for
+// filesystems where changes can't be watched reliably.
+func reindexEvery(interval time.Duration, indexer search.NotesIndexer, hub *hub) {
+	for range time.Tick(interval) {
+		indexer.IndexNotes(context.Background())
+		hub.notifyIndexed()
+	}
+}
+
+// indexChanges indexes the notes as the watcher reports their changes,
+// every note when folders came or went.
+func indexChanges(w *watcher.Watcher, indexer search.NotesIndexer, hub *hub) {
+	for changes := range w.Changes() {
+		if changes.Rescan {
+			indexer.IndexNotes(context.Background())
+		} else {
+			for _, path := range changes.Paths {
+				if err := indexer.IndexFile(path); err != nil {
+					slog.Error("indexing the changed note failed", "path", path, "err", err)
+				}
+			}
+		}
+		hub.notifyIndexed()
+	}
+}
+
+// withAuth rejects requests without the configured bearer token or
+// basic auth credentials. Without either configured every request passes.
+func withAuth(next http.Handler, conf utils.ServerConfig) http.Handler {
+	if conf.Token == "" && conf.Username == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, conf) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="notes_search"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the request against the configured credentials.
+func authorized(r *http.Request, conf utils.ServerConfig) bool {
+	if conf.Token != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if secureEqual(token, conf.Token) {
+			return true
+		}
+	}
+
+	if conf.Username != "" {
+		user, pass, ok := r.BasicAuth()
+		if ok && secureEqual(user, conf.Username) && secureEqual(pass, conf.Password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// secureEqual compares secrets in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// splitAddr returns the network and address to dial or listen on.
+func splitAddr(addr string) (network, address string) {
+	if strings.HasPrefix(addr, "unix:") {
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	}
+	if strings.ContainsRune(addr, '/') {
+		return "unix", addr
+	}
+	return "tcp", addr
+}
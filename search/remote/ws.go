@@ -0,0 +1,122 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/noelzubin/notes_search/search"
+)
+
+// wsRequest is sent by the client whenever its query changes.
+type wsRequest struct {
+	Query string
+}
+
+// wsMessage is pushed to the client.
+// Type is "results" for a query's hits or "indexed" after a reindex.
+type wsMessage struct {
+	Type  string
+	Query string                 `json:",omitempty"`
+	Hits  []search.DocumentMatch `json:",omitempty"`
+	Error string                 `json:",omitempty"`
+}
+
+// wsClient is a single websocket connection and its current query.
+type wsClient struct {
+	conn  *websocket.Conn
+	mu    sync.Mutex // guards writes to conn and query
+	query string
+}
+
+// send writes a message to the client.
+func (c *wsClient) send(msg wsMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(msg)
+}
+
+// hub keeps track of the live websocket clients so they can be told
+// about index changes.
+type hub struct {
+	indexer search.NotesIndexer
+	mu      sync.Mutex
+	clients map[*wsClient]bool
+}
+
+func newHub(indexer search.NotesIndexer) *hub {
+	return &hub{indexer: indexer, clients: make(map[*wsClient]bool)}
+}
+
+// upgrader keeps gorilla's default origin check: browsers send the Basic
+// credentials withAuth checks to any site, so a page served from another
+// host mustn't open a websocket with them. Clients other than browsers
+// send no Origin and are let through.
+var upgrader = websocket.Upgrader{}
+
+// serveWS upgrades the connection and answers every query update with
+// fresh results until the client goes away.
+func (h *hub) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := &wsClient{conn: conn}
+	h.mu.Lock()
+	h.clients[client] = true
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, client)
+		h.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+
+		client.mu.Lock()
+		client.query = req.Query
+		client.mu.Unlock()
+
+		if err := client.send(h.results(req.Query)); err != nil {
+			return
+		}
+	}
+}
+
+// results runs the query and wraps it in a message.
+func (h *hub) results(query string) wsMessage {
+	result := h.indexer.Search(context.Background(), query)
+	msg := wsMessage{Type: "results", Query: query, Hits: result.Hits}
+	if result.Err != nil {
+		msg.Error = result.Err.Error()
+	}
+	return msg
+}
+
+// notifyIndexed tells every client the index changed and pushes the
+// refreshed results of its current query.
+func (h *hub) notifyIndexed() {
+	h.mu.Lock()
+	clients := make([]*wsClient, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.mu.Lock()
+		query := c.query
+		c.mu.Unlock()
+
+		c.send(wsMessage{Type: "indexed"})
+		c.send(h.results(query))
+	}
+}
@@ -1,8 +1,22 @@
 package search
 
+import (
+	"context"
+	"time"
+)
+
 type DocumentMatch struct {
 	Path    string
 	Content string
+	Matches int     // occurrences of the query terms in the note, 0 if unknown
+	Title   string  // title of the note if it has one besides its file name
+	Score   float64 `json:",omitempty"` // relevance, to merge the hits of several vaults
+	Vault   string  `json:",omitempty"` // vault of the note when searching several
+	Hash    string  `json:",omitempty"` // digest of the content, the same for copies and hard links; empty notes have none
+	Private bool    `json:",omitempty"` // only found by its title, its content isn't shown
+
+	// Notes linking to the hit when it is an attachment such as an image.
+	ReferencedBy []string `json:",omitempty"`
 }
 
 type SearchResult struct {
@@ -10,10 +24,54 @@ type SearchResult struct {
 	Hits []DocumentMatch
 }
 
+// DefaultSize is the number of hits returned by Search.
+const DefaultSize = 100
+
 // The indexer that indexes all the notes and searches them.
+//
+// IndexNotes, Search and SearchPage stop early once ctx is done: a
+// cancelled search returns ctx.Err() as its error, a cancelled reindex
+// leaves the rest of the notes to the next one.
 type NotesIndexer interface {
-	IndexNotes()                      // Index all the notes.
-	Search(query string) SearchResult // Search the index for the given query.
-	OpenIndex()                       // Search the index for the given query.
-	CloseIndex()                      // Search the index for the given query.
+	IndexNotes(ctx context.Context)                        // Index all the notes.
+	IndexFile(path string) error                           // Index the note at path alone, e.g. once it's edited.
+	Search(ctx context.Context, query string) SearchResult // Search the index for the given query.
+	// SearchPage returns size hits of the query starting at from,
+	// so large results can be fetched incrementally.
+	SearchPage(ctx context.Context, query string, from, size int) SearchResult
+	OpenIndex()                       // Open the index.
+	CloseIndex()                      // Close the index, e.g. while the editor runs.
+	Random() SearchResult             // Pick a random note from the index.
+	Similar(path string) SearchResult // Notes related to the note at path.
+	Syntax() []SyntaxEntry            // Query syntax of the backend, for the cheat sheet.
+	DocCount() (uint64, error)        // Number of indexed notes.
+	IndexStatus() []IndexStatus       // State of the reindex of each notes root.
+	// Tags counts the tags of the notes matching the query, most used
+	// first; "" counts the tags of every note.
+	Tags(ctx context.Context, query string) ([]TagCount, error)
+	// Activity counts the notes by the days they were created and last
+	// modified on, keyed 2006-01-02 in local time, from since to today.
+	Activity(ctx context.Context, since time.Time) (map[string]int, error)
+}
+
+// TagCount is a tag and the number of notes having it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// IndexStatus is the state of the reindex of a notes root.
+type IndexStatus struct {
+	Root     string
+	Vault    string        `json:",omitempty"` // vault of the root when searching several
+	Indexing bool          // a reindex is running
+	Scanned  int           `json:",omitempty"` // notes the running reindex found, 0 while it walks the root
+	Indexed  int           `json:",omitempty"` // notes the running reindex has indexed so far
+	ToIndex  int           `json:",omitempty"` // new and modified notes the running reindex indexes
+	Deleted  int           `json:",omitempty"` // notes the running reindex removed from the index
+	Errors   int           `json:",omitempty"` // notes the last reindex failed to read or index
+	Notes    int           // notes found by the last reindex
+	Took     time.Duration // time the last reindex took
+	Skipped  int           `json:",omitempty"` // files and folders the last reindex wasn't allowed to read
+	Err      string        `json:",omitempty"` // why the last reindex failed, the index was left as is
 }
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weeks shown by the heatmap, a year.
+const HeatmapWeeks = 53
+
+// Cells of the heatmap, from no notes touched to the busiest days.
+var shades = []string{"·", "░", "▒", "▓", "█"}
+
+// HeatmapStart returns the Monday the heatmap ending on end starts on.
+func HeatmapStart(end time.Time, weeks int) time.Time {
+	day := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	return monday.AddDate(0, 0, -7*(weeks-1))
+}
+
+// Heatmap renders the notes created or modified each day, counts keyed
+// 2006-01-02, as a calendar of the weeks up to end like the contributions
+// of GitHub: a row per weekday from Monday, a column per week, busier days
+// darker.
+// The months head the weeks they start in.
+func Heatmap(counts map[string]int, end time.Time, weeks int) string {
+	start := HeatmapStart(end, weeks)
+	most, total, active := 0, 0, 0
+	busiest := ""
+	for day, count := range counts {
+		if count == 0 {
+			continue
+		}
+		total += count
+		active++
+		if count > most || (count == most && day > busiest) {
+			most, busiest = count, day
+		}
+	}
+
+	months := []rune(strings.Repeat(" ", weeks+3))
+	for week := 0; week < weeks; week++ {
+		monday := start.AddDate(0, 0, 7*week)
+		if week == 0 || monday.AddDate(0, 0, -7).Month() != monday.Month() {
+			name := monday.Format("Jan")
+			if week > 0 && months[week-1] != ' ' {
+				continue
+			}
+			copy(months[week:], []rune(name))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "     %s\n", strings.TrimRight(string(months), " "))
+	labels := []string{"Mon", "", "Wed", "", "Fri", "", "Sun"}
+	for weekday := 0; weekday < 7; weekday++ {
+		fmt.Fprintf(&b, "%-4s ", labels[weekday])
+		for week := 0; week < weeks; week++ {
+			day := start.AddDate(0, 0, 7*week+weekday)
+			if day.After(end) {
+				break
+			}
+			b.WriteString(shade(counts[day.Format("2006-01-02")], most))
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n     less %s more\n", strings.Join(shades, " "))
+	if total == 0 {
+		fmt.Fprintf(&b, "no notes created or modified in the last %d weeks\n", weeks)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d notes created or modified on %d days, most on %s (%d)\n", total, active, busiest, most)
+	return b.String()
+}
+
+// shade picks the cell of a day with count notes, most being the busiest.
+func shade(count, most int) string {
+	if count <= 0 || most <= 0 {
+		return shades[0]
+	}
+	return shades[1+(count*(len(shades)-1)-1)/most]
+}
@@ -0,0 +1,178 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// Number of recent searches whose latency is kept.
+const maxLatencies = 1000
+
+// How often recorded stats are written at most, the rest is written by
+// Flush, e.g. on quit.
+const flushEvery = time.Minute
+
+// Latencies records how long the recent searches took, in
+// latencies.json under the data path, oldest first. They're kept in
+// memory and written at most every flushEvery, so searches don't wait on
+// the disk.
+type Latencies struct {
+	path    string
+	mu      sync.Mutex
+	pending []time.Duration // recorded since the last write
+	written time.Time       // when they were last written
+}
+
+// NewLatencies returns the latencies stored under the data path.
+func NewLatencies() *Latencies {
+	return &Latencies{path: filepath.Join(utils.DataDir(), "latencies.json")}
+}
+
+// Record adds the latency of a search, written with the others once
+// flushEvery passed since the last write.
+func (l *Latencies) Record(took time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, took)
+	if time.Since(l.written) < flushEvery {
+		return nil
+	}
+	return l.flush()
+}
+
+// Flush writes the latencies recorded since the last write.
+func (l *Latencies) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.flush()
+}
+
+// flush adds the pending latencies to the stored ones, dropping the
+// oldest ones past maxLatencies. The file is read again as another
+// instance may have written it.
+func (l *Latencies) flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	all, err := l.stored()
+	if err != nil {
+		return err
+	}
+	all = append(all, l.pending...)
+	if len(all) > maxLatencies {
+		all = all[len(all)-maxLatencies:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	ms := make([]float64, len(all))
+	for i, d := range all {
+		ms[i] = float64(d.Microseconds()) / 1000
+	}
+	data, err := json.Marshal(ms)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(l.path, data, 0600); err != nil {
+		return err
+	}
+	l.pending, l.written = nil, time.Now()
+	return nil
+}
+
+// All returns the recorded latencies, oldest first.
+func (l *Latencies) All() ([]time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	all, err := l.stored()
+	return append(all, l.pending...), err
+}
+
+// stored returns the latencies written so far, oldest first.
+func (l *Latencies) stored() ([]time.Duration, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ms := []float64{}
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.path, err)
+	}
+	all := make([]time.Duration, len(ms))
+	for i, v := range ms {
+		all[i] = time.Duration(v * float64(time.Millisecond))
+	}
+	return all, nil
+}
+
+// Upper bounds of the histogram buckets, the last one takes the rest.
+var buckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Histogram renders the latencies as a text histogram with their
+// median and 95th percentile, width being the longest bar.
+func Histogram(latencies []time.Duration, width int) string {
+	if len(latencies) == 0 {
+		return "no searches recorded yet\n"
+	}
+
+	counts := make([]int, len(buckets)+1)
+	for _, d := range latencies {
+		i := sort.Search(len(buckets), func(i int) bool { return d < buckets[i] })
+		counts[i]++
+	}
+	most := 0
+	for _, c := range counts {
+		if c > most {
+			most = c
+		}
+	}
+
+	var b strings.Builder
+	for i, c := range counts {
+		label := "≥ " + buckets[len(buckets)-1].String()
+		if i < len(buckets) {
+			label = "< " + buckets[i].String()
+		}
+		bar := strings.Repeat("█", c*width/most)
+		fmt.Fprintf(&b, "%8s  %-*s %d\n", label, width, bar, c)
+	}
+
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	fmt.Fprintf(&b, "\n%d searches, median %s, p95 %s\n", len(sorted),
+		Round(sorted[len(sorted)/2]), Round(sorted[len(sorted)*95/100]))
+	return b.String()
+}
+
+// Round shortens a latency for display, e.g. 23ms or 1.2s.
+func Round(d time.Duration) string {
+	if d < time.Millisecond {
+		return "<1ms"
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,185 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/noelzubin/notes_search/search"
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// UsageReport is what's recorded with usage_metrics enabled. It never
+// leaves the machine, it's meant for tuning the setup and bug reports.
+type UsageReport struct {
+	Since        time.Time     // first recorded use
+	Queries      int           // searches run, not counting further pages
+	QueryTime    time.Duration // total time spent searching
+	Reindexes    int           // times the notes were indexed
+	IndexTime    time.Duration // total time spent indexing
+	LastIndex    time.Duration // time the last reindex took
+	IndexedNotes uint64        // notes in the index after the last reindex
+	SkippedFiles int           // files and folders the last reindex wasn't allowed to read
+}
+
+// Usage records the usage report in usage.json under the data path. The
+// updates are kept in memory and written at most every flushEvery, and by
+// Flush.
+type Usage struct {
+	path    string
+	mu      sync.Mutex
+	pending []func(r *UsageReport) // updates since the last write
+	written time.Time              // when they were last written
+}
+
+// NewUsage returns the usage report stored under the data path.
+func NewUsage() *Usage {
+	return &Usage{path: filepath.Join(utils.DataDir(), "usage.json")}
+}
+
+// Report returns the recorded usage, empty if nothing was recorded,
+// along with the updates not written yet.
+func (u *Usage) Report() (UsageReport, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	report, err := u.stored()
+	for _, fn := range u.pending {
+		fn(&report)
+	}
+	return report, err
+}
+
+// stored returns the usage written so far.
+func (u *Usage) stored() (UsageReport, error) {
+	report := UsageReport{}
+	data, err := os.ReadFile(u.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", u.path, err)
+	}
+	return report, nil
+}
+
+// update queues fn, written with the other updates once flushEvery
+// passed since the last write.
+func (u *Usage) update(fn func(r *UsageReport)) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.pending = append(u.pending, fn)
+	if time.Since(u.written) < flushEvery {
+		return nil
+	}
+	return u.flush()
+}
+
+// Flush writes the updates queued since the last write.
+func (u *Usage) Flush() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.flush()
+}
+
+// flush applies the queued updates to the stored usage and stores it.
+// The file is read again as another instance may have written it.
+func (u *Usage) flush() error {
+	if len(u.pending) == 0 {
+		return nil
+	}
+	report, err := u.stored()
+	if err != nil {
+		return err
+	}
+	if report.Since.IsZero() {
+		report.Since = time.Now()
+	}
+	for _, fn := range u.pending {
+		fn(&report)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(u.path, data, 0600); err != nil {
+		return err
+	}
+	u.pending, u.written = nil, time.Now()
+	return nil
+}
+
+// usageIndexer records the searches and reindexes of the indexer it wraps.
+type usageIndexer struct {
+	search.NotesIndexer
+	usage *Usage
+}
+
+// NewUsageIndexer wraps indexer to record its use in usage.
+func NewUsageIndexer(indexer search.NotesIndexer, usage *Usage) search.NotesIndexer {
+	return &usageIndexer{NotesIndexer: indexer, usage: usage}
+}
+
+func (u *usageIndexer) Search(ctx context.Context, query string) search.SearchResult {
+	return u.SearchPage(ctx, query, 0, search.DefaultSize)
+}
+
+// SearchPage records the first page of the searches that weren't
+// cancelled.
+func (u *usageIndexer) SearchPage(ctx context.Context, query string, from, size int) search.SearchResult {
+	start := time.Now()
+	result := u.NotesIndexer.SearchPage(ctx, query, from, size)
+	if from == 0 && ctx.Err() == nil {
+		took := time.Since(start)
+		u.record(func(r *UsageReport) {
+			r.Queries++
+			r.QueryTime += took
+		})
+	}
+	return result
+}
+
+// CloseIndex writes the usage recorded so far, then closes the index.
+func (u *usageIndexer) CloseIndex() {
+	if err := u.usage.Flush(); err != nil {
+		slog.Warn("recording the usage failed", "err", err)
+	}
+	u.NotesIndexer.CloseIndex()
+}
+
+// record updates the usage, failures are only logged.
+func (u *usageIndexer) record(fn func(r *UsageReport)) {
+	if err := u.usage.update(fn); err != nil {
+		slog.Warn("recording the usage failed", "err", err)
+	}
+}
+
+func (u *usageIndexer) IndexNotes(ctx context.Context) {
+	start := time.Now()
+	u.NotesIndexer.IndexNotes(ctx)
+	took := time.Since(start)
+	count, _ := u.NotesIndexer.DocCount()
+	skipped := 0
+	for _, status := range u.NotesIndexer.IndexStatus() {
+		skipped += status.Skipped
+	}
+	u.record(func(r *UsageReport) {
+		r.Reindexes++
+		r.IndexTime += took
+		r.LastIndex = took
+		r.IndexedNotes = count
+		r.SkippedFiles = skipped
+	})
+}
@@ -0,0 +1,163 @@
+package trash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noelzubin/notes_search/utils"
+)
+
+// ErrEmpty is returned when there is nothing to restore.
+var ErrEmpty = errors.New("trash is empty")
+
+// Entry is a trashed note as recorded in the manifest.
+type Entry struct {
+	Name      string    // File name inside the trash directory
+	Path      string    // Original path of the note
+	DeletedAt time.Time // When the note was trashed
+}
+
+// Trash holds deleted notes so they can be restored.
+// Notes are moved into dir and recorded in dir/manifest.json,
+// most recent last.
+type Trash struct {
+	dir string
+}
+
+// New returns the trash stored under the data path.
+func New() *Trash {
+	return &Trash{dir: filepath.Join(utils.DataDir(), "trash")}
+}
+
+// Get path to the manifest file
+func (t *Trash) manifestPath() string {
+	return filepath.Join(t.dir, "manifest.json")
+}
+
+// Delete moves the note at path into the trash.
+func (t *Trash) Delete(path string) (Entry, error) {
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return Entry{}, err
+	}
+
+	entries, err := t.Entries()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	now := time.Now()
+	entry := Entry{
+		Name:      fmt.Sprintf("%d-%s", now.UnixNano(), filepath.Base(path)),
+		Path:      path,
+		DeletedAt: now,
+	}
+
+	if err := moveFile(path, filepath.Join(t.dir, entry.Name)); err != nil {
+		return Entry{}, err
+	}
+
+	return entry, t.store(append(entries, entry))
+}
+
+// Restore moves the most recently deleted note back to where it was.
+func (t *Trash) Restore() (Entry, error) {
+	entries, err := t.Entries()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, ErrEmpty
+	}
+
+	entry := entries[len(entries)-1]
+	if _, err := os.Stat(entry.Path); err == nil {
+		return Entry{}, fmt.Errorf("%s already exists", entry.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return Entry{}, err
+	}
+	if err := moveFile(filepath.Join(t.dir, entry.Name), entry.Path); err != nil {
+		return Entry{}, err
+	}
+
+	return entry, t.store(entries[:len(entries)-1])
+}
+
+// Empty permanently removes everything in the trash and
+// returns how many notes were removed.
+func (t *Trash) Empty() (int, error) {
+	entries, err := t.Entries()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(t.dir); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// Entries returns the trashed notes, most recent last.
+func (t *Trash) Entries() (entries []Entry, err error) {
+	data, err := os.ReadFile(t.manifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &entries)
+	return entries, err
+}
+
+// store writes the manifest.
+func (t *Trash) store(entries []Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.manifestPath(), data, 0600)
+}
+
+// moveFile renames src to dst, copying when they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// Keep the modification time so the note sorts the same once restored.
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	in.Close()
+	return os.Remove(src)
+}
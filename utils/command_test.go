@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"code --wait", []string{"code", "--wait"}},
+		{"  pandoc\t-o  note.pdf ", []string{"pandoc", "-o", "note.pdf"}},
+		{`notify-send "Sent {path}"`, []string{"notify-send", "Sent {path}"}},
+		{`sh -c 'wc -w "$1"' _ {file}`, []string{"sh", "-c", `wc -w "$1"`, "_", "{file}"}},
+		{`cp {file} My\ Notes/`, []string{"cp", "{file}", "My Notes/"}},
+		{`echo "a \"b\" \d"`, []string{"echo", `a "b" \d`}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{`echo "unclosed quote`, []string{"echo", "unclosed quote"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := SplitCommand(tt.command); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,469 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
-	"path"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // Config is the cofiguration for the application
 type Config struct {
-	RootPath   string   `mapstructure:"root_path"`  // Root path of the notes.
-	Editor     string   `mapstructure:"editor"`     // Editor to open the notes with
-	Extensions []string `mapstructure:"extensions"` // Extensions of notes to be indexed
+	RootPath   string       `mapstructure:"root_path"`  // Root path of the notes.
+	Editor     string       `mapstructure:"editor"`     // Editor to open the notes with
+	Extensions []string     `mapstructure:"extensions"` // Extensions of notes to be indexed
+	Server     ServerConfig `mapstructure:"server"`     // Settings for `serve` and `--connect`
+
+	// Groups of extensions, indexed besides Extensions and searched with
+	// type:name, e.g. {markdown: [.md, .markdown], code: [.go, .py]}.
+	// "" stands for the files without extension that look like text.
+	Types map[string][]string `mapstructure:"types"`
+
+	// Index the files without extension that look like text, e.g. TODO or
+	// NOTES, as if "" was among the Extensions.
+	IndexNoExtension bool `mapstructure:"index_no_extension"`
+
+	// Colours of the UI: default, high-contrast, colorblind or none.
+	// NO_COLOR in the environment forces none.
+	Theme string `mapstructure:"theme"`
+
+	// Chroma style of the note preview, e.g. monokai or github. Defaults
+	// to one suiting the theme and the background.
+	SyntaxTheme string `mapstructure:"syntax_theme"`
+
+	// Background of the terminal: auto asks the terminal, light or dark
+	// override it when that goes wrong.
+	Background string `mapstructure:"background"`
+
+	// Language of the UI, e.g. "de". Defaults to $LANG.
+	Locale string `mapstructure:"locale"`
+
+	// Folder archived notes are moved to, relative to the root path.
+	ArchivePath string `mapstructure:"archive_path"`
+
+	// Folder new notes, e.g. from the clipboard, are created in,
+	// relative to the root path.
+	InboxPath string `mapstructure:"inbox_path"`
+
+	// Path of the daily notes relative to the root path, whose file name
+	// is a Go time layout, e.g. "journal/2006-01-02.md".
+	DailyNote string `mapstructure:"daily_note"`
+
+	// Note used as scratchpad, relative to the root path.
+	// Defaults to scratchpad.md in the data dir.
+	Scratchpad string `mapstructure:"scratchpad"`
+
+	// Exports are written here, next to the note when empty.
+	ExportDir string `mapstructure:"export_dir"`
+	// Command converting an exported HTML file to PDF, e.g. "wkhtmltopdf {in} {out}".
+	PDFConverter string `mapstructure:"pdf_converter"`
+
+	// Commands rendering diagram fences as text in the preview, by fence
+	// language, e.g. {mermaid: "mermaid-ascii -f {in}"}. The diagram is
+	// written to {in}, or piped in without it.
+	Diagrams map[string]string `mapstructure:"diagrams"`
+
+	// Commands the selected note can be sent to with alt+z, by name, e.g.
+	// {pandoc: "pandoc -o note.pdf", copy path: "wl-copy {path}"}. The
+	// note is piped in, unless {path} passes its path. Arguments are
+	// quoted like in a shell, see SplitCommand.
+	SendTo map[string]string `mapstructure:"send_to"`
+
+	// Commands run on the selected note from the alt+z menu, by the key
+	// pressed there, e.g. {p: "pandoc {file} -o {file}.pdf"}. {file},
+	// {dir} and {query} stand for the note, its folder and the query.
+	// Those on a ctrl+ or alt+ key that isn't taken also run from the
+	// results.
+	Actions map[string]string `mapstructure:"actions"`
+
+	// The last word of the query is searched as a prefix once it has this
+	// many characters, and only if fewer than MaxPrefixExpansions indexed
+	// terms start with it. Keeps short prefixes cheap on big indexes.
+	MinPrefixLength     int `mapstructure:"min_prefix_length"`
+	MaxPrefixExpansions int `mapstructure:"max_prefix_expansions"`
+
+	// What searches the notes: bleve, an index kept up to date by
+	// reindexes, or grep, which reads the notes at every search.
+	Backend string `mapstructure:"backend"`
+
+	// Edits a word of a fuzzy query may be away from the words of the
+	// notes, see EditDistance.
+	Fuzziness int `mapstructure:"fuzziness"`
+
+	// What's listed while the query is empty: recent (the recently modified
+	// notes), pinned, stale (the notes unchanged for StalePeriod), search
+	// (the results of StartQuery) or dashboard (all of them).
+	StartView  string   `mapstructure:"start_view"`
+	StartQuery string   `mapstructure:"start_query"` // saved search of the start view
+	Pinned     []string `mapstructure:"pinned"`      // notes listed first, relative to the root path
+
+	// Shorthands for recurring filters, typed as @name in queries, e.g.
+	// {inbox: "path:inbox/ -done"}.
+	Macros map[string]string `mapstructure:"macros"`
+
+	// Score multipliers of the results by folder, relative to the root path,
+	// e.g. {projects/: 2.0, archive/: 0.3}.
+	Boosts map[string]float64 `mapstructure:"boosts"`
+
+	// Words left out of the index, the English stopwords when unset.
+	// [none] keeps every word. Changing it rebuilds the index.
+	Stopwords []string `mapstructure:"stopwords"`
+
+	// Keep the index and its metadata encrypted on disk. The key is derived
+	// from $NOTES_SEARCH_PASSPHRASE or the output of PassphraseCommand,
+	// e.g. "secret-tool lookup service notes_search" to use the keyring.
+	EncryptIndex      bool   `mapstructure:"encrypt_index"`
+	PassphraseCommand string `mapstructure:"passphrase_command"`
+
+	// Other note folders searched along with RootPath, by name,
+	// e.g. {work: /Users/username/work-notes}. Each has its own index.
+	Vaults map[string]string `mapstructure:"vaults"`
+
+	// Least level logged to debug.log: debug, info, warn or error.
+	// debug also logs every query with its latency.
+	LogLevel string `mapstructure:"log_level"`
+
+	// Record the number of searches, the index size and timings in
+	// usage.json in the data dir, for `stats --usage`. Nothing is sent.
+	UsageMetrics bool `mapstructure:"usage_metrics"`
+
+	// Record how long the searches take in latencies.json in the data dir,
+	// for the histogram of `stats`.
+	SearchLatencies bool `mapstructure:"search_latencies"`
+
+	// Go easy on the notes root: auto when it's on a network filesystem
+	// (NFS, SMB, SSHFS, ...), always or never. See LowIO.
+	LowIOMode string `mapstructure:"low_io"`
+
+	// Notes sent to the index at once while reindexing. Bigger batches
+	// index faster but hold more notes in memory.
+	IndexBatchSize int `mapstructure:"index_batch_size"`
+
+	// Minutes between automatic reindexes, 0 disables them.
+	ReindexInterval int `mapstructure:"reindex_interval"`
+
+	// Days without changes after which a note is stale, see StalePeriod.
+	StaleAfter int `mapstructure:"stale_after"`
+
+	// Links followed from the note in the center of the graph view.
+	GraphHops int `mapstructure:"graph_hops"`
+
+	// Watch the notes roots and index the changed notes right away, see
+	// WatchedRoots.
+	Watch bool `mapstructure:"watch"`
+
+	vault string // name of the vault this config is for, "" for RootPath
+}
+
+// ServerConfig secures the HTTP API of the daemon.
+// The same settings are used by the client when connecting.
+type ServerConfig struct {
+	Token    string `mapstructure:"token"`    // Bearer token required on every request
+	Username string `mapstructure:"username"` // Basic auth username
+	Password string `mapstructure:"password"` // Basic auth password
+	TLSCert  string `mapstructure:"tls_cert"` // Certificate file, enables TLS, requires TLSKey
+	TLSKey   string `mapstructure:"tls_key"`  // Private key file for TLSCert
+}
+
+// Validate reports settings the daemon can't serve safely. Half of a TLS
+// setup would otherwise fall back to plain HTTP and send the token in clear.
+func (s ServerConfig) Validate() error {
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return errors.New("server: tls_cert and tls_key must be set together")
+	}
+	return nil
+}
+
+// ReindexEvery returns the automatic reindex interval, 0 if disabled.
+func (c *Config) ReindexEvery() time.Duration {
+	return time.Duration(c.ReindexInterval) * time.Minute
+}
+
+// EditDistance returns the fuzziness of fuzzy queries, 1 or 2 edits, the
+// most bleve allows.
+func (c *Config) EditDistance() int {
+	return lo.Clamp(c.Fuzziness, 1, 2)
+}
+
+// StalePeriod returns how long a note goes unchanged before is:stale
+// lists it.
+func (c *Config) StalePeriod() time.Duration {
+	return time.Duration(c.StaleAfter) * 24 * time.Hour
+}
+
+// LowIO tells whether the notes root should be read with little I/O:
+// walked slowly, read a few notes at a time and not walked at startup.
+func (c *Config) LowIO() bool {
+	switch c.LowIOMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return IsNetworkMount(c.RootPath)
+}
+
+// ArchiveDir returns the absolute path of the archive folder.
+func (c *Config) ArchiveDir() string {
+	if filepath.IsAbs(c.ArchivePath) {
+		return filepath.Clean(c.ArchivePath)
+	}
+	return filepath.Join(c.RootPath, c.ArchivePath)
+}
+
+// InboxDir returns the absolute path of the inbox folder.
+func (c *Config) InboxDir() string {
+	if filepath.IsAbs(c.InboxPath) {
+		return filepath.Clean(c.InboxPath)
+	}
+	return filepath.Join(c.RootPath, c.InboxPath)
+}
+
+// DailyNotePath returns the absolute path of the daily note of day. Only
+// the file name of DailyNote is a time layout, so folder names such as
+// "Journal" or "notes2" are left alone.
+func (c *Config) DailyNotePath(day time.Time) string {
+	dir, name := filepath.Split(filepath.FromSlash(c.DailyNote))
+	path := filepath.Join(dir, day.Format(name))
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(c.RootPath, path)
+}
+
+// NoteExtensions returns the extensions of the notes to index, those of
+// Extensions and of the Types, and "" with IndexNoExtension.
+func (c *Config) NoteExtensions() []string {
+	extensions := append([]string{}, c.Extensions...)
+	names := lo.Keys(c.Types)
+	sort.Strings(names)
+	for _, name := range names {
+		extensions = append(extensions, c.Types[name]...)
+	}
+	if c.IndexNoExtension {
+		extensions = append(extensions, "")
+	}
+	return lo.Uniq(extensions)
+}
+
+// TypeOf returns the lowercased name of the group of types, see
+// Config.Types, the file at path is in, "" if it's in none. Groups
+// sharing an extension are tried by name.
+func TypeOf(types map[string][]string, path string) string {
+	ext := filepath.Ext(path)
+	names := lo.Keys(types)
+	sort.Strings(names)
+	for _, name := range names {
+		if lo.ContainsBy(types[name], func(e string) bool { return strings.EqualFold(e, ext) }) {
+			return strings.ToLower(name)
+		}
+	}
+	return ""
+}
+
+// PinnedPaths returns the absolute paths of the pinned notes.
+func (c *Config) PinnedPaths() []string {
+	return lo.Map(c.Pinned, func(path string, _ int) string {
+		if filepath.IsAbs(path) {
+			return filepath.Clean(path)
+		}
+		return filepath.Join(c.RootPath, path)
+	})
+}
+
+// ScratchpadPath returns the absolute path of the scratchpad note.
+// The scratchpad belongs to RootPath, other vaults have none.
+func (c *Config) ScratchpadPath() string {
+	switch {
+	case c.vault != "":
+		return ""
+	case c.Scratchpad == "":
+		return filepath.Join(DataDir(), "scratchpad.md")
+	case filepath.IsAbs(c.Scratchpad):
+		return filepath.Clean(c.Scratchpad)
+	default:
+		return filepath.Join(c.RootPath, c.Scratchpad)
+	}
+}
+
+// FolderBoosts returns the boosts keyed by lowercased folder, with forward
+// slashes and without a leading "./".
+func (c *Config) FolderBoosts() map[string]float64 {
+	boosts := map[string]float64{}
+	for folder, boost := range c.Boosts {
+		folder = strings.TrimPrefix(filepath.ToSlash(folder), "./")
+		boosts[strings.ToLower(folder)] = boost
+	}
+	return boosts
+}
+
+// IndexPassphrase returns the passphrase the index is encrypted with,
+// from the environment or else from the passphrase command.
+func (c *Config) IndexPassphrase() (string, error) {
+	if passphrase := os.Getenv("NOTES_SEARCH_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fields := strings.Fields(c.PassphraseCommand)
+	if len(fields) == 0 {
+		return "", errors.New("encrypt_index needs $NOTES_SEARCH_PASSPHRASE or a passphrase_command")
+	}
+	out, err := exec.Command(fields[0], fields[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("passphrase_command failed: %w", err)
+	}
+
+	passphrase := strings.TrimRight(string(out), "\r\n")
+	if passphrase == "" {
+		return "", errors.New("passphrase_command returned an empty passphrase")
+	}
+	return passphrase, nil
+}
+
+// WatchedRoots returns the roots watched for changes with Watch: every
+// vault's but the low I/O ones, where changes can't be watched reliably.
+func (c *Config) WatchedRoots() []string {
+	if !c.Watch {
+		return nil
+	}
+	return lo.FilterMap(c.VaultConfigs(), func(vault *Config, _ int) (string, bool) {
+		return vault.RootPath, !vault.LowIO()
+	})
+}
+
+// VaultConfigs returns a config per vault to search, RootPath first.
+// They share every setting but the root path and the index dir.
+func (c *Config) VaultConfigs() []*Config {
+	configs := []*Config{c}
+	names := lo.Keys(c.Vaults)
+	sort.Strings(names)
+	for _, name := range names {
+		vault := *c
+		vault.RootPath = c.Vaults[name]
+		vault.vault = name
+		configs = append(configs, &vault)
+	}
+	return configs
+}
+
+// VaultName labels the results of the vault: its name, or the folder
+// name for RootPath.
+func (c *Config) VaultName() string {
+	if c.vault != "" {
+		return c.vault
+	}
+	return filepath.Base(filepath.Clean(c.RootPath))
+}
+
+// IndexDir returns where the index of the vault is stored.
+func (c *Config) IndexDir() string {
+	if c.vault != "" {
+		return filepath.Join(DataDir(), "vaults", c.vault)
+	}
+	return DataDir()
+}
+
+// ConfigDir returns the directory holding the config file and the debug log.
+// On Windows this is %APPDATA%\notes_search, elsewhere ~/.config/notes_search.
+func ConfigDir() string {
+	if runtime.GOOS == "windows" {
+		dir, _ := os.UserConfigDir()
+		return filepath.Join(dir, "notes_search")
+	}
+	homedir, _ := os.UserHomeDir()
+	return filepath.Join(homedir, ".config", "notes_search")
+}
+
+// DataDir returns where the index, its metadata and other state is stored.
+func DataDir() string {
+	dir, _ := os.UserCacheDir()
+	return filepath.Join(dir, "notes_search")
+}
+
+// ConfigPath returns the path of the config file.
+func ConfigPath() string {
+	return filepath.Join(ConfigDir(), "config.yaml")
+}
+
+// WriteConfig writes a config file with the settings asked for on the
+// first run. An existing config file is left alone.
+func WriteConfig(rootPath, editor string, extensions []string) error {
+	body, err := yaml.Marshal(struct {
+		RootPath   string   `yaml:"root_path"`
+		Editor     string   `yaml:"editor,omitempty"`
+		Extensions []string `yaml:"extensions,flow"`
+	}{rootPath, editor, extensions})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(ConfigPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append([]byte("# See the README for the other settings.\n"), body...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rootPathLine matches the root_path setting of the config file.
+var rootPathLine = regexp.MustCompile(`(?m)^root_path:.*$`)
+
+// SetRootPath changes the root_path of the config file, leaving the other
+// settings and the comments as they are.
+func SetRootPath(rootPath string) error {
+	body, err := os.ReadFile(ConfigPath())
+	if err != nil {
+		return err
+	}
+	value, err := yaml.Marshal(map[string]string{"root_path": rootPath})
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(string(value), "\n")
+	if rootPathLine.Match(body) {
+		body = rootPathLine.ReplaceAllLiteral(body, []byte(line))
+	} else {
+		body = append([]byte(line+"\n"), body...)
+	}
+	return os.WriteFile(ConfigPath(), body, 0644)
 }
 
 // NewConfig returns a new Config object by reading from the config file
 func NewConfig() *Config {
-	homedir, _ := os.UserHomeDir()
-	configPath := path.Join(homedir, "/.config/notes_search/config.yaml")
-	viper.SetConfigFile(configPath)
+	viper.SetConfigFile(ConfigPath())
 
 	viper.SetDefault("extensions", []string{".md"})
+	viper.SetDefault("archive_path", "archive")
+	viper.SetDefault("inbox_path", "inbox")
+	viper.SetDefault("daily_note", "daily/2006-01-02.md")
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("low_io", "auto")
+	viper.SetDefault("background", "auto")
+	viper.SetDefault("start_view", "recent")
+	viper.SetDefault("min_prefix_length", 2)
+	viper.SetDefault("max_prefix_expansions", 1000)
+	viper.SetDefault("fuzziness", 1)
+	viper.SetDefault("backend", "bleve")
+	viper.SetDefault("index_batch_size", 500)
+	viper.SetDefault("stale_after", 180)
+	viper.SetDefault("graph_hops", 2)
+	viper.SetDefault("search_latencies", true)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatal("failed to read config file", err)
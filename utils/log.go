@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// SetupLog sends the log, including the standard logger, as JSON lines to
+// the file at path. level is one of debug, info, warn or error.
+func SetupLog(path, level string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		lvl = slog.LevelInfo
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{Level: lvl})))
+	return f, nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Macros used by macros are expanded this deep, so cycles end.
+const maxMacroDepth = 8
+
+// macroRef matches the @name references to macros in a query.
+var macroRef = regexp.MustCompile(`(^|\s)@([\w-]+)`)
+
+// ExpandMacros replaces the @name references in query with the queries the
+// macros of that name stand for, e.g. "@inbox budget" becomes
+// "path:inbox/ -done budget". Names are case-insensitive, unknown ones
+// are left as is.
+func ExpandMacros(query string, macros map[string]string) string {
+	if len(macros) == 0 {
+		return query
+	}
+	for depth := 0; depth < maxMacroDepth; depth++ {
+		expanded := macroRef.ReplaceAllStringFunc(query, func(ref string) string {
+			match := macroRef.FindStringSubmatch(ref)
+			if macro, ok := macros[strings.ToLower(match[2])]; ok {
+				return match[1] + strings.TrimSpace(macro)
+			}
+			return ref
+		})
+		if expanded == query {
+			break
+		}
+		query = expanded
+	}
+	return query
+}
@@ -0,0 +1,32 @@
+package utils
+
+import "testing"
+
+func TestExpandMacros(t *testing.T) {
+	macros := map[string]string{
+		"inbox": " path:inbox/ -done ",
+		"work":  "vault:work @inbox",
+		"loop":  "@loop x",
+	}
+	tests := []struct {
+		name   string
+		query  string
+		macros map[string]string
+		want   string
+	}{
+		{"no macros", "@inbox budget", nil, "@inbox budget"},
+		{"expanded", "@inbox budget", macros, "path:inbox/ -done budget"},
+		{"case-insensitive", "budget @INBOX", macros, "budget path:inbox/ -done"},
+		{"nested", "@work", macros, "vault:work path:inbox/ -done"},
+		{"unknown left as is", "@later budget", macros, "@later budget"},
+		{"only at word starts", "me@inbox.com", macros, "me@inbox.com"},
+		{"cycles end", "@loop", macros, "@loop x x x x x x x x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandMacros(tt.query, tt.macros); got != tt.want {
+				t.Errorf("ExpandMacros(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
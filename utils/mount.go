@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// networkFSTypes are the filesystems served over the network.
+var networkFSTypes = []string{
+	"nfs", "nfs4", "cifs", "smb", "smb2", "smb3", "smbfs", "afpfs", "webdav", "davfs",
+	"fuse.sshfs", "sshfs", "osxfuse", "macfuse", "fuse.rclone", "9p", "afs", "ceph", "glusterfs",
+}
+
+// mount is a mounted filesystem.
+type mount struct {
+	dir    string // mount point
+	fsType string
+}
+
+// IsNetworkMount tells whether path is on a network filesystem such as
+// NFS, SMB or SSHFS. It's false when that can't be told.
+func IsNetworkMount(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// UNC paths, mapped network drives can't be told apart.
+		return strings.HasPrefix(abs, `\\`)
+	}
+
+	var mounts []mount
+	if runtime.GOOS == "linux" {
+		mounts = linuxMounts()
+	} else {
+		mounts = mountCommandMounts()
+	}
+
+	// The mount point closest to the path is the one it's on.
+	best := mount{}
+	for _, m := range mounts {
+		if (abs == m.dir || strings.HasPrefix(abs, strings.TrimSuffix(m.dir, "/")+"/")) && len(m.dir) > len(best.dir) {
+			best = m
+		}
+	}
+	fsType := strings.ToLower(best.fsType)
+	for _, t := range networkFSTypes {
+		if fsType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// linuxMounts reads the mounts from /proc/self/mountinfo, whose lines are
+// "id parent dev root mountpoint options [tags] - fstype source options".
+func linuxMounts() []mount {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	mounts := []mount{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		before, after, ok := strings.Cut(scanner.Text(), " - ")
+		fields, fsFields := strings.Fields(before), strings.Fields(after)
+		if !ok || len(fields) < 5 || len(fsFields) < 1 {
+			continue
+		}
+		// Spaces in mount points are escaped as \040.
+		dir := strings.ReplaceAll(fields[4], `\040`, " ")
+		mounts = append(mounts, mount{dir: dir, fsType: fsFields[0]})
+	}
+	return mounts
+}
+
+// mountLine matches the lines of mount on macOS and the BSDs, e.g.
+// "//user@server/share on /Volumes/share (smbfs, nodev, nosuid)".
+var mountLine = regexp.MustCompile(`^.* on (.*) \(([^,)]+)`)
+
+// mountCommandMounts reads the mounts from the output of mount.
+func mountCommandMounts() []mount {
+	out, err := exec.Command("mount").Output()
+	if err != nil {
+		return nil
+	}
+	mounts := []mount{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if match := mountLine.FindStringSubmatch(line); match != nil {
+			mounts = append(mounts, mount{dir: match[1], fsType: match[2]})
+		}
+	}
+	return mounts
+}
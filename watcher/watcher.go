@@ -0,0 +1,175 @@
+// Package watcher reports the changes of the files under the notes roots
+// as they happen, so they can be indexed without waiting for a reindex.
+package watcher
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/samber/lo"
+)
+
+// Events are gathered this long before the changes are reported, so a
+// save touching a file several times is indexed once.
+const delay = 300 * time.Millisecond
+
+// Changes are the files changed since the last report. Rescan is set when
+// folders came or went, whose files aren't reported one by one.
+type Changes struct {
+	Paths  []string
+	Rescan bool
+}
+
+// Watcher watches the folders of the notes roots, except the hidden ones
+// and those holding a .noindex file like the indexer.
+type Watcher struct {
+	fs      *fsnotify.Watcher
+	changes chan Changes
+	done    chan struct{}
+
+	mu   sync.Mutex
+	dirs map[string]bool // watched folders
+}
+
+// New starts watching the roots and their subfolders.
+func New(roots []string) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{fs: fsWatcher, changes: make(chan Changes), done: make(chan struct{}), dirs: map[string]bool{}}
+	for _, root := range roots {
+		if err := w.addTree(root, root); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+// Changes delivers the changes as they're gathered, and is closed with the
+// watcher.
+func (w *Watcher) Changes() <-chan Changes {
+	return w.changes
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fs.Close()
+}
+
+// addTree watches dir and its subfolders. The folders it isn't allowed
+// to read are left out.
+func (w *Watcher) addTree(root, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrPermission) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ".noindex")); err == nil {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(path); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		w.dirs[path] = true
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// forget stops tracking dir and its subfolders once they're gone,
+// fsnotify drops their watches itself. It tells whether dir was watched.
+func (w *Watcher) forget(dir string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.dirs[dir] {
+		return false
+	}
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			delete(w.dirs, path)
+		}
+	}
+	return true
+}
+
+// run gathers the events and reports them once none came for a while.
+// Changes is closed once the watcher is.
+func (w *Watcher) run() {
+	defer close(w.changes)
+	paths := map[string]bool{}
+	rescan := false
+	timer := time.NewTimer(delay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("watching the notes failed", "err", err)
+			// Overflows lose events, only a rescan catches up.
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				rescan = true
+				timer.Reset(delay)
+			}
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			switch {
+			case event.Has(fsnotify.Create):
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Files moved in with the folder make no events.
+					if err := w.addTree(filepath.Dir(event.Name), event.Name); err != nil {
+						slog.Warn("can't watch the new folder", "path", event.Name, "err", err)
+					}
+					rescan = true
+					break
+				}
+				paths[event.Name] = true
+			case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+				if w.forget(event.Name) {
+					rescan = true
+					break
+				}
+				paths[event.Name] = true
+			case event.Has(fsnotify.Write):
+				paths[event.Name] = true
+			default:
+				continue
+			}
+			timer.Reset(delay)
+		case <-timer.C:
+			changes := Changes{Paths: lo.Keys(paths), Rescan: rescan}
+			select {
+			case w.changes <- changes:
+			case <-w.done:
+				return
+			}
+			paths, rescan = map[string]bool{}, false
+		}
+	}
+}